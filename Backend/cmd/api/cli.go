@@ -0,0 +1,499 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	netmail "net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/config"
+	"github.com/drshn/excalidraw/Backend/internal/database"
+	"github.com/drshn/excalidraw/Backend/internal/handlers"
+	"github.com/drshn/excalidraw/Backend/internal/mail"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/oidc"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/term"
+)
+
+const usage = `Usage: main [command]
+
+Commands:
+  serve                                       Start the API server (default)
+  user create --email EMAIL [--admin]         Create a user with a verified email
+  user reset-password --email EMAIL [--link]  Set a new password, or print a reset link
+  user disable --email EMAIL                  Disable an account and sign it out
+  user enable --email EMAIL                   Re-enable an account
+  user set-role --email EMAIL --role ROLE     Make a user an "admin" or a "user"
+  drawings export --user EMAIL [--out FILE]   Write a user's drawings to a zip file
+  migrate                                     Create the database indexes
+  config check                                Validate the configuration
+
+Passwords are read from standard input unless given with --password.
+Configuration is read from the same environment variables as the server.
+`
+
+// cliCommand runs a subcommand with its remaining arguments.
+type cliCommand func(env *cliEnv, args []string) error
+
+var cliCommands = map[string]cliCommand{
+	"user create":         createUserCommand,
+	"user reset-password": resetPasswordCommand,
+	"user disable":        disableUserCommand,
+	"user enable":         enableUserCommand,
+	"user set-role":       setRoleCommand,
+	"drawings export":     exportDrawingsCommand,
+	"migrate":             migrateCommand,
+}
+
+// runCommand runs a subcommand other than serve.
+func runCommand(cfg *config.Config, args []string, stdin io.Reader, stdout io.Writer) error {
+	switch strings.Join(args, " ") {
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	case "config check":
+		return checkConfig(cfg, stdout)
+	}
+
+	name, rest, ok := findCommand(args)
+	if !ok {
+		return fmt.Errorf("unknown command %q\n\n%s", strings.Join(args, " "), usage)
+	}
+
+	client, err := database.GetMongoClient(cfg)
+	if err != nil {
+		return fmt.Errorf("could not connect to MongoDB: %w", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	env, err := newCLIEnv(cfg, client.Database(cfg.DBName), stdin, stdout)
+	if err != nil {
+		return err
+	}
+	return cliCommands[name](env, rest)
+}
+
+// findCommand matches the longest command name at the start of args.
+func findCommand(args []string) (string, []string, bool) {
+	for n := min(2, len(args)); n > 0; n-- {
+		name := strings.Join(args[:n], " ")
+		if _, ok := cliCommands[name]; ok {
+			return name, args[n:], true
+		}
+	}
+	return "", nil, false
+}
+
+// cliEnv holds what the subcommands share. They go through the same
+// handlers as the API so accounts end up in the same state either way.
+type cliEnv struct {
+	ctx           context.Context
+	db            *mongo.Database
+	userRepo      repository.UserRepository
+	auth          *handlers.AuthHandler
+	passwordReset *handlers.PasswordResetHandler
+	admin         *handlers.AdminHandler
+	account       *handlers.AccountHandler
+	stdin         *bufio.Reader
+	// terminal is stdin when it is a terminal, so passwords can be read
+	// without echoing them.
+	terminal *os.File
+	stdout   io.Writer
+}
+
+func newCLIEnv(cfg *config.Config, db *mongo.Database, stdin io.Reader, stdout io.Writer) (*cliEnv, error) {
+	userRepo := repository.NewMongoUserRepository(db)
+	drawingRepo := repository.NewMongoDrawingRepository(db)
+	libraryRepo := repository.NewMongoLibraryRepository(db)
+	commentRepo := repository.NewMongoCommentRepository(db)
+	userTokenRepo := repository.NewMongoUserTokenRepository(db)
+	personalAccessTokenRepo := repository.NewMongoPersonalAccessTokenRepository(db)
+	invitationRepo := repository.NewMongoInvitationRepository(db)
+
+	keys, err := auth.LoadKeySet(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not load JWT keys: %w", err)
+	}
+	tokenService := auth.NewTokenService(repository.NewMongoRefreshTokenRepository(db), repository.NewMongoRevocationRepository(db), repository.NewMongoSessionRepository(db), keys, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+
+	passwords, err := auth.LoadPasswordHasher(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid password hashing configuration: %w", err)
+	}
+	passwordPolicy, err := auth.LoadPasswordPolicy(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid password policy: %w", err)
+	}
+
+	mailer, err := mail.NewMailer(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not configure mail delivery: %w", err)
+	}
+
+	var terminal *os.File
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		terminal = f
+	}
+
+	passwordReset := handlers.NewPasswordResetHandler(userRepo, userTokenRepo, tokenService, passwords, passwordPolicy, mailer, cfg.AppBaseURL, cfg.PasswordResetTTL)
+	return &cliEnv{
+		ctx:           context.Background(),
+		db:            db,
+		userRepo:      userRepo,
+		auth:          handlers.NewAuthHandler(userRepo, tokenService, passwords, passwordPolicy, nil, nil, nil),
+		passwordReset: passwordReset,
+		admin:         handlers.NewAdminHandler(userRepo, drawingRepo, libraryRepo, tokenService, passwordReset),
+		account:       handlers.NewAccountHandler(userRepo, drawingRepo, commentRepo, libraryRepo, invitationRepo, personalAccessTokenRepo, userTokenRepo, tokenService, passwords, mailer, cfg.AppBaseURL),
+		stdin:         bufio.NewReader(stdin),
+		terminal:      terminal,
+		stdout:        stdout,
+	}, nil
+}
+
+// findUser looks a user up by email or ID.
+func (e *cliEnv) findUser(emailOrID string) (*models.User, error) {
+	if emailOrID == "" {
+		return nil, errors.New("a user is required")
+	}
+
+	var user *models.User
+	var err error
+	if id, idErr := primitive.ObjectIDFromHex(emailOrID); idErr == nil {
+		user, err = e.userRepo.FindByID(e.ctx, id)
+	} else {
+		user, err = e.userRepo.FindByEmail(e.ctx, emailOrID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("no user %q", emailOrID)
+	}
+	return user, nil
+}
+
+// readPassword returns the flag value, or else the first line of stdin,
+// which is not echoed when stdin is a terminal. The password policy is
+// applied by the handlers it is passed to.
+func (e *cliEnv) readPassword(flagValue string) (string, error) {
+	password := flagValue
+	if password == "" && e.terminal != nil {
+		fmt.Fprint(e.stdout, "Password: ")
+		line, err := term.ReadPassword(int(e.terminal.Fd()))
+		fmt.Fprintln(e.stdout)
+		if err != nil {
+			return "", err
+		}
+		password = string(line)
+	} else if password == "" {
+		fmt.Fprint(e.stdout, "Password: ")
+		line, err := e.stdin.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		fmt.Fprintln(e.stdout)
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return "", errors.New("a password is required")
+	}
+	return password, nil
+}
+
+func newFlagSet(name string, stdout io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	return fs
+}
+
+func createUserCommand(env *cliEnv, args []string) error {
+	fs := newFlagSet("user create", env.stdout)
+	email := fs.String("email", "", "email address")
+	password := fs.String("password", "", "password (read from stdin if omitted)")
+	admin := fs.Bool("admin", false, "give the user the admin role")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if addr, err := netmail.ParseAddress(*email); err != nil || addr.Address != *email {
+		return fmt.Errorf("invalid email %q", *email)
+	}
+	pw, err := env.readPassword(*password)
+	if err != nil {
+		return err
+	}
+
+	// The operator vouches for the address, so it starts out verified.
+	user := &models.User{ID: primitive.NewObjectID(), Email: *email, EmailVerified: true}
+	if *admin {
+		user.Role = models.RoleAdmin
+	}
+	if err := env.auth.CreateUser(env.ctx, user, pw); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.stdout, "Created user %s (%s)\n", user.Email, user.ID.Hex())
+	return nil
+}
+
+func resetPasswordCommand(env *cliEnv, args []string) error {
+	fs := newFlagSet("user reset-password", env.stdout)
+	email := fs.String("email", "", "email address or user ID")
+	password := fs.String("password", "", "new password (read from stdin if omitted)")
+	link := fs.Bool("link", false, "print a reset link for the user instead of setting a password")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	user, err := env.findUser(*email)
+	if err != nil {
+		return err
+	}
+
+	if *link {
+		resetLink, err := env.passwordReset.ResetLink(env.ctx, user)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(env.stdout, "Reset link for %s, valid for %s:\n%s\n", user.Email, env.passwordReset.TokenTTL, resetLink)
+		return nil
+	}
+
+	pw, err := env.readPassword(*password)
+	if err != nil {
+		return err
+	}
+	if err := env.passwordReset.SetPassword(env.ctx, user, pw); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stdout, "Password changed for %s; all sessions signed out\n", user.Email)
+	return nil
+}
+
+func disableUserCommand(env *cliEnv, args []string) error {
+	return setDisabled(env, "user disable", args, true)
+}
+
+func enableUserCommand(env *cliEnv, args []string) error {
+	return setDisabled(env, "user enable", args, false)
+}
+
+func setDisabled(env *cliEnv, name string, args []string, disabled bool) error {
+	fs := newFlagSet(name, env.stdout)
+	email := fs.String("email", "", "email address or user ID")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	user, err := env.findUser(*email)
+	if err != nil {
+		return err
+	}
+	if err := env.admin.SetUserDisabled(env.ctx, user, disabled); err != nil {
+		return err
+	}
+
+	if disabled {
+		fmt.Fprintf(env.stdout, "Disabled %s; all sessions signed out\n", user.Email)
+	} else {
+		fmt.Fprintf(env.stdout, "Enabled %s\n", user.Email)
+	}
+	return nil
+}
+
+func setRoleCommand(env *cliEnv, args []string) error {
+	fs := newFlagSet("user set-role", env.stdout)
+	email := fs.String("email", "", "email address or user ID")
+	role := fs.String("role", "", `"admin" or "user"`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *role != models.RoleAdmin && *role != models.RoleUser {
+		return fmt.Errorf("role must be %q or %q", models.RoleAdmin, models.RoleUser)
+	}
+
+	user, err := env.findUser(*email)
+	if err != nil {
+		return err
+	}
+	if err := env.userRepo.SetRole(env.ctx, user.ID, *role); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.stdout, "%s is now %s\n", user.Email, *role)
+	return nil
+}
+
+func exportDrawingsCommand(env *cliEnv, args []string) error {
+	fs := newFlagSet("drawings export", env.stdout)
+	email := fs.String("user", "", "email address or user ID")
+	out := fs.String("out", "", `output file, or "-" for stdout (default drawings-<user ID>.zip)`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	user, err := env.findUser(*email)
+	if err != nil {
+		return err
+	}
+
+	w := env.stdout
+	if *out != "-" {
+		if *out == "" {
+			*out = "drawings-" + user.ID.Hex() + ".zip"
+		}
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	archive := zip.NewWriter(w)
+	if err := env.account.WriteDrawings(env.ctx, archive, user.ID); err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	if *out != "-" {
+		fmt.Fprintf(env.stdout, "Wrote drawings of %s to %s\n", user.Email, *out)
+	}
+	return nil
+}
+
+func migrateCommand(env *cliEnv, args []string) error {
+	if err := newFlagSet("migrate", env.stdout).Parse(args); err != nil {
+		return err
+	}
+
+	err := repository.Migrate(env.ctx, env.db, func(step string) {
+		fmt.Fprintln(env.stdout, step)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(env.stdout, "Database is up to date")
+	return nil
+}
+
+// checkConfig validates the configuration the way the server would load it,
+// and checks that MongoDB is reachable.
+func checkConfig(cfg *config.Config, stdout io.Writer) error {
+	var failures int
+	report := func(name string, err error, detail string) {
+		if err != nil {
+			failures++
+			fmt.Fprintf(stdout, "FAIL  %s: %v\n", name, err)
+			return
+		}
+		fmt.Fprintf(stdout, "ok    %s: %s\n", name, detail)
+	}
+	warn := func(message string) {
+		fmt.Fprintf(stdout, "warn  %s\n", message)
+	}
+
+	keys, err := auth.LoadKeySet(cfg)
+	var keyDetail string
+	if err == nil {
+		keyDetail = fmt.Sprintf("signing with %s, %d key(s) accepted", keys.Signing.Method.Alg(), len(keys.Verification))
+		if cfg.JWTSigningKeyFile == "" && cfg.JWTSecret == config.DefaultJWTSecret {
+			warn("JWT_SECRET is the default; anyone can forge access tokens")
+		}
+	}
+	report("JWT keys", err, keyDetail)
+
+	passwords, err := auth.LoadPasswordHasher(cfg)
+	var passwordDetail string
+	if err == nil && passwords.Algorithm == auth.PasswordAlgorithmBcrypt {
+		passwordDetail = fmt.Sprintf("bcrypt, cost %d", passwords.BcryptCost)
+	} else if err == nil {
+		passwordDetail = fmt.Sprintf("argon2id, %d KiB, %d iterations, %d threads", passwords.Argon2.Memory, passwords.Argon2.Iterations, passwords.Argon2.Parallelism)
+	}
+	report("Password hashing", err, passwordDetail)
+
+	policy, err := auth.LoadPasswordPolicy(cfg)
+	var policyDetail string
+	if err == nil {
+		policyDetail = fmt.Sprintf("%d to %d characters", policy.MinLength, policy.MaxLength)
+		if policy.Breached != nil {
+			policyDetail += ", breached passwords rejected"
+		}
+	}
+	report("Password policy", err, policyDetail)
+
+	registration, err := handlers.NewRegistration(cfg.RegistrationMode, cfg.RegistrationAllowedDomains, cfg.RequireEmailVerification, nil)
+	var registrationDetail string
+	if err == nil {
+		registrationDetail = registration.Mode
+		if registration.Mode == handlers.RegistrationDomains {
+			registrationDetail += " (" + strings.Join(registration.AllowedDomains, ", ") + ")"
+		}
+	}
+	report("Registration", err, registrationDetail)
+
+	providers, err := oidc.ParseProviders(cfg.OIDCProviders)
+	report("Single sign-on", err, fmt.Sprintf("%d provider(s)", len(providers)))
+
+	_, err = mail.NewMailer(cfg)
+	report("Mail", err, "driver "+cfg.MailDriver)
+	if err == nil && cfg.MailDriver != "smtp" {
+		warn("MAIL_DRIVER is not smtp; emails are logged instead of sent")
+	}
+
+	for _, u := range []struct{ name, value string }{
+		{"APP_BASE_URL", cfg.AppBaseURL},
+		{"API_BASE_URL", cfg.APIBaseURL},
+	} {
+		parsed, err := url.Parse(u.value)
+		if err == nil && (parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "") {
+			err = errors.New("must be an absolute http(s) URL")
+		}
+		report(u.name, err, u.value)
+	}
+
+	for _, d := range cfg.Durations() {
+		var err error
+		if d.Value <= 0 {
+			err = errors.New("must be a positive duration")
+		}
+		report(d.Name, err, d.Value.String())
+	}
+
+	report("MongoDB", pingMongo(cfg), cfg.DBName)
+
+	if failures > 0 {
+		return fmt.Errorf("%d configuration problem(s)", failures)
+	}
+	fmt.Fprintln(stdout, "Configuration is valid")
+	return nil
+}
+
+// pingMongo connects without database.GetMongoClient, which exits the
+// process when the server is unreachable.
+func pingMongo(cfg *config.Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoDBURI))
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(context.Background())
+	return client.Ping(ctx, nil)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/config"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// runTestCommand runs a subcommand against the test database.
+func runTestCommand(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout bytes.Buffer
+	env, err := newCLIEnv(config.LoadConfig(), testDB, strings.NewReader(stdin), &stdout)
+	if err != nil {
+		t.Fatalf("newCLIEnv: %v", err)
+	}
+
+	name, rest, ok := findCommand(args)
+	if !ok {
+		t.Fatalf("unknown command %q", args)
+	}
+	err = cliCommands[name](env, rest)
+	return stdout.String(), err
+}
+
+func TestCLIUserCommands(t *testing.T) {
+	ctx := context.Background()
+	userRepo := repository.NewMongoUserRepository(testDB)
+	email := "cli-admin@example.com"
+	passwords, err := auth.LoadPasswordHasher(config.LoadConfig())
+	assert.NoError(t, err)
+
+	_, err = runTestCommand(t, "correct-horse\n", "user", "create", "--email", email, "--admin")
+	assert.NoError(t, err)
+
+	user, err := userRepo.FindByEmail(ctx, email)
+	assert.NoError(t, err)
+	if user == nil {
+		t.Fatal("user was not created")
+	}
+	assert.Equal(t, models.RoleAdmin, user.Role)
+	assert.True(t, user.EmailVerified)
+	match, _, _ := passwords.Verify("correct-horse", user.Password)
+	assert.True(t, match)
+
+	_, err = runTestCommand(t, "", "user", "create", "--email", email, "--password", "another-password")
+	assert.Error(t, err, "email already taken")
+
+	_, err = runTestCommand(t, "short\n", "user", "create", "--email", "cli-short@example.com")
+	assert.Error(t, err, "password too short")
+
+	_, err = runTestCommand(t, "", "user", "reset-password", "--email", email, "--password", "battery-staple")
+	assert.NoError(t, err)
+	user, _ = userRepo.FindByEmail(ctx, email)
+	match, _, _ = passwords.Verify("battery-staple", user.Password)
+	assert.True(t, match)
+
+	out, err := runTestCommand(t, "", "user", "reset-password", "--email", email, "--link")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "/reset-password?token=")
+
+	_, err = runTestCommand(t, "", "user", "disable", "--email", email)
+	assert.NoError(t, err)
+	user, _ = userRepo.FindByEmail(ctx, email)
+	assert.NotNil(t, user.DisabledAt)
+
+	_, err = runTestCommand(t, "", "user", "enable", "--email", user.ID.Hex())
+	assert.NoError(t, err)
+	user, _ = userRepo.FindByEmail(ctx, email)
+	assert.Nil(t, user.DisabledAt)
+
+	_, err = runTestCommand(t, "", "user", "set-role", "--email", email, "--role", "owner")
+	assert.Error(t, err, "unknown role")
+
+	_, err = runTestCommand(t, "", "user", "set-role", "--email", email, "--role", models.RoleUser)
+	assert.NoError(t, err)
+	user, _ = userRepo.FindByEmail(ctx, email)
+	assert.Equal(t, models.RoleUser, user.Role)
+
+	_, err = runTestCommand(t, "", "user", "disable", "--email", "nobody@example.com")
+	assert.Error(t, err, "unknown user")
+}
+
+func TestCLIExportDrawings(t *testing.T) {
+	email := "cli-export@example.com"
+	_, err := runTestCommand(t, "", "user", "create", "--email", email, "--password", "correct-horse")
+	assert.NoError(t, err)
+
+	out, err := runTestCommand(t, "", "drawings", "export", "--user", email, "--out", "-")
+	assert.NoError(t, err)
+
+	archive, err := zip.NewReader(strings.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("export is not a zip archive: %v", err)
+	}
+	var names []string
+	for _, f := range archive.File {
+		names = append(names, f.Name)
+	}
+	assert.Contains(t, names, "drawings.json")
+	assert.Contains(t, names, "comments.json")
+}
+
+func TestCLIMigrate(t *testing.T) {
+	ctx := context.Background()
+	users := testDB.Collection("users")
+	oldUser, _ := users.InsertOne(ctx, bson.M{"email": "before-verification@example.com"})
+	unverified, _ := users.InsertOne(ctx, bson.M{"email": "unverified@example.com", "emailVerified": false})
+
+	out, err := runTestCommand(t, "", "migrate")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Creating users indexes")
+	assert.Contains(t, out, "Backfilling users.emailVerified")
+
+	userRepo := repository.NewMongoUserRepository(testDB)
+	user, _ := userRepo.FindByID(ctx, oldUser.InsertedID.(primitive.ObjectID))
+	assert.True(t, user.EmailVerified, "accounts from before verification count as verified")
+	user, _ = userRepo.FindByID(ctx, unverified.InsertedID.(primitive.ObjectID))
+	assert.False(t, user.EmailVerified)
+
+	// Running it again changes nothing.
+	_, err = runTestCommand(t, "", "migrate")
+	assert.NoError(t, err)
+}
+
+func TestCLIUnknownCommand(t *testing.T) {
+	var stdout bytes.Buffer
+	err := runCommand(config.LoadConfig(), []string{"user", "frobnicate"}, strings.NewReader(""), &stdout)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown command")
+
+	err = runCommand(config.LoadConfig(), []string{"help"}, strings.NewReader(""), &stdout)
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "user create")
+}
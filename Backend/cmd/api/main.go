@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -11,61 +12,60 @@ import (
 
 	"github.com/drshn/excalidraw/Backend/internal/config"
 	"github.com/drshn/excalidraw/Backend/internal/database"
-	"github.com/drshn/excalidraw/Backend/internal/handlers"
-	"github.com/drshn/excalidraw/Backend/internal/middleware"
+	"github.com/drshn/excalidraw/Backend/internal/mail"
 	"github.com/drshn/excalidraw/Backend/internal/repository"
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
+	"github.com/drshn/excalidraw/Backend/internal/server"
 )
 
 func main() {
 	cfg := config.LoadConfig()
 
+	args := os.Args[1:]
+	if len(args) == 0 || args[0] == "serve" {
+		serve(cfg)
+		return
+	}
+	if err := runCommand(cfg, args, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// serve runs the API server until it receives SIGINT or SIGTERM.
+func serve(cfg *config.Config) {
 	db, err := database.GetMongoClient(cfg)
 	if err != nil {
 		log.Fatalf("Could not connect to MongoDB: %v", err)
 	}
 
-	userRepo := repository.NewMongoUserRepository(db.Database(cfg.DBName))
-	drawingRepo := repository.NewMongoDrawingRepository(db.Database(cfg.DBName))
-
-	authHandler := handlers.NewAuthHandler(userRepo, cfg.JWTSecret)
-	drawingHandler := handlers.NewDrawingHandler(drawingRepo)
-
-	r := gin.Default()
-
-	// Configure CORS to allow all origins
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"*"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: false,
-		MaxAge:           12 * time.Hour,
-	}))
-
-	api := r.Group("/api/v1")
-	{
-		auth := api.Group("/auth")
-		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
-		}
+	// The unique email index and the backfills are not optional, so refuse
+	// to serve a database they cannot be applied to.
+	if err := repository.Migrate(context.Background(), db.Database(cfg.DBName), func(step string) { log.Print(step) }); err != nil {
+		log.Fatalf("Could not migrate the database: %v; fix the data and rerun, or run ./main migrate", err)
+	}
 
-		drawings := api.Group("/drawings")
-		drawings.Use(middleware.AuthMiddleware(cfg.JWTSecret))
-		{
-			drawings.POST("", drawingHandler.CreateDrawing)
-			drawings.GET("", drawingHandler.GetDrawings)
-			drawings.GET("/:id", drawingHandler.GetDrawingByID)
-			drawings.PUT("/:id", drawingHandler.UpdateDrawing)
-			drawings.DELETE("/:id", drawingHandler.DeleteDrawing)
-		}
+	mailer, err := mail.NewMailer(cfg)
+	if err != nil {
+		log.Fatalf("Could not configure mail delivery: %v", err)
 	}
 
+	app, err := server.New(cfg, server.NewMongoRepositories(db.Database(cfg.DBName)), mailer)
+	if err != nil {
+		log.Fatalf("Could not start the server: %v", err)
+	}
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go app.TrashPurger.Run(jobsCtx)
+	trackerDone := make(chan struct{})
+	go func() {
+		app.SessionTracker.Run(jobsCtx)
+		close(trackerDone)
+	}()
+
 	srv := &http.Server{
 		Addr:    ":" + cfg.Port,
-		Handler: r,
+		Handler: app.Router,
 	}
 
 	go func() {
@@ -92,6 +92,11 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Stop background jobs once no more requests are in flight so the final
+	// session activity flush sees every request.
+	stopJobs()
+	<-trackerDone
+
 	log.Println("Server exiting")
 
 	// Disconnect from MongoDB
@@ -8,9 +8,8 @@ import (
 
 	"github.com/drshn/excalidraw/Backend/internal/config"
 	"github.com/drshn/excalidraw/Backend/internal/database"
-	"github.com/drshn/excalidraw/Backend/internal/handlers"
-	"github.com/drshn/excalidraw/Backend/internal/middleware"
-	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/drshn/excalidraw/Backend/internal/mail"
+	"github.com/drshn/excalidraw/Backend/internal/server"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -42,35 +41,9 @@ func TestMain(m *testing.M) {
 }
 
 func setupRouter(cfg *config.Config, db *mongo.Database) *gin.Engine {
-	r := gin.Default()
-
-	// Repositories
-	userRepo := repository.NewMongoUserRepository(db)
-	drawingRepo := repository.NewMongoDrawingRepository(db)
-
-	// Handlers
-	authHandler := handlers.NewAuthHandler(userRepo, cfg.JWTSecret)
-	drawingHandler := handlers.NewDrawingHandler(drawingRepo)
-
-	// Routes
-	api := r.Group("/api/v1")
-	{
-		auth := api.Group("/auth")
-		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
-		}
-
-		drawings := api.Group("/drawings")
-		drawings.Use(middleware.AuthMiddleware(cfg.JWTSecret))
-		{
-			drawings.POST("", drawingHandler.CreateDrawing)
-			drawings.GET("", drawingHandler.GetDrawings)
-			drawings.GET("/:id", drawingHandler.GetDrawingByID)
-			drawings.PUT("/:id", drawingHandler.UpdateDrawing)
-			drawings.DELETE("/:id", drawingHandler.DeleteDrawing)
-		}
+	app, err := server.New(cfg, server.NewMongoRepositories(db), mail.NewLogMailer("", cfg.MailFrom))
+	if err != nil {
+		log.Fatalf("Could not build the server: %v", err)
 	}
-
-	return r
+	return app.Router
 }
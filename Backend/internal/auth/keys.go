@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/drshn/excalidraw/Backend/internal/config"
+	"github.com/drshn/excalidraw/Backend/internal/oidc"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// hmacKeyID is the kid of the shared-secret key.
+const hmacKeyID = "hmac"
+
+// Key is a JWT signing or verification key. Private is nil for keys that are
+// only trusted for verification.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private interface{}
+	Public  interface{}
+}
+
+// KeySet holds the key access tokens are signed with and every key tokens
+// are accepted from. Rotating keys means signing with a new key while the
+// previous one stays in Verification until its tokens have expired.
+type KeySet struct {
+	Signing      *Key
+	Verification map[string]*Key
+}
+
+// NewHMACKeySet returns a key set that signs and verifies with a shared
+// secret (HS256).
+func NewHMACKeySet(secret string) *KeySet {
+	key := &Key{ID: hmacKeyID, Method: jwt.SigningMethodHS256, Private: []byte(secret), Public: []byte(secret)}
+	return &KeySet{Signing: key, Verification: map[string]*Key{key.ID: key}}
+}
+
+// LoadKeySet loads the signing key from JWT_SIGNING_KEY_FILE and extra
+// verification keys from JWT_VERIFICATION_KEY_FILES. Without a signing key
+// file it falls back to HS256 with JWT_SECRET.
+func LoadKeySet(cfg *config.Config) (*KeySet, error) {
+	if cfg.JWTSigningKeyFile == "" {
+		if cfg.JWTVerificationKeyFiles != "" {
+			return nil, errors.New("JWT_VERIFICATION_KEY_FILES requires JWT_SIGNING_KEY_FILE")
+		}
+		if cfg.JWTSecret == config.DefaultJWTSecret {
+			log.Printf("WARNING: signing tokens with the default JWT_SECRET; set JWT_SIGNING_KEY_FILE or JWT_SECRET")
+		}
+		return NewHMACKeySet(cfg.JWTSecret), nil
+	}
+
+	signing, err := loadKeyFile(cfg.JWTSigningKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if signing.Private == nil {
+		return nil, fmt.Errorf("%s: signing key must be a private key", cfg.JWTSigningKeyFile)
+	}
+
+	set := &KeySet{Signing: signing, Verification: map[string]*Key{signing.ID: signing}}
+	for _, path := range strings.Split(cfg.JWTVerificationKeyFiles, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// Only the public half of old keys is needed.
+		key.Private = nil
+		if _, exists := set.Verification[key.ID]; !exists {
+			set.Verification[key.ID] = key
+		}
+	}
+	return set, nil
+}
+
+// NewKey wraps an *rsa.PrivateKey or ed25519.PrivateKey (or their public
+// keys) as a Key, with the RFC 7638 thumbprint as its ID.
+func NewKey(k interface{}) (*Key, error) {
+	key := &Key{}
+	switch k := k.(type) {
+	case *rsa.PrivateKey:
+		key.Private, key.Public, key.Method = k, &k.PublicKey, jwt.SigningMethodRS256
+	case *rsa.PublicKey:
+		key.Public, key.Method = k, jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		key.Private, key.Public, key.Method = k, k.Public(), jwt.SigningMethodEdDSA
+	case ed25519.PublicKey:
+		key.Public, key.Method = k, jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T; use RSA or Ed25519", k)
+	}
+
+	jwk := key.JWK()
+	var canonical string
+	if jwk.Kty == "RSA" {
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	} else {
+		canonical = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":%q}`, jwk.X)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	key.ID = base64.RawURLEncoding.EncodeToString(sum[:])
+	return key, nil
+}
+
+// JWK returns the public half of an asymmetric key in JWK format.
+func (k *Key) JWK() oidc.JSONWebKey {
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		return oidc.RSAPublicJWK(k.ID, pub)
+	case ed25519.PublicKey:
+		return oidc.JSONWebKey{
+			Kty: "OKP",
+			Kid: k.ID,
+			Use: "sig",
+			Alg: "EdDSA",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}
+	default:
+		return oidc.JSONWebKey{}
+	}
+}
+
+// JWKS returns the public verification keys. Shared-secret keys are never
+// published.
+func (s *KeySet) JWKS() oidc.JSONWebKeySet {
+	set := oidc.JSONWebKeySet{Keys: []oidc.JSONWebKey{}}
+	if s.Signing.Method != jwt.SigningMethodHS256 {
+		set.Keys = append(set.Keys, s.Signing.JWK())
+	}
+	var previous []oidc.JSONWebKey
+	for id, key := range s.Verification {
+		if id != s.Signing.ID && key.Method != jwt.SigningMethodHS256 {
+			previous = append(previous, key.JWK())
+		}
+	}
+	sort.Slice(previous, func(i, j int) bool { return previous[i].Kid < previous[j].Kid })
+	set.Keys = append(set.Keys, previous...)
+	return set
+}
+
+// sign signs claims with the signing key, naming it in the kid header.
+func (s *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.Signing.Method, claims)
+	token.Header["kid"] = s.Signing.ID
+	return token.SignedString(s.Signing.Private)
+}
+
+// keyFunc finds the verification key for a token. Tokens without a kid,
+// issued before keys were named, are checked against the signing key.
+func (s *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	key := s.Signing
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, ok = s.Verification[kid]; !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+func loadKeyFile(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	key, err := NewKey(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if rsaKey, ok := key.Public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("%s: RSA keys must be at least 2048 bits", path)
+	}
+	return key, nil
+}
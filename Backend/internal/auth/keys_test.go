@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/config"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func writePrivateKey(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return path
+}
+
+func newTestService(keys *KeySet) *TokenService {
+	return NewTokenService(repository.NewMockRefreshTokenRepository(), repository.NewMockRevocationRepository(), repository.NewMockSessionRepository(), keys, 15*time.Minute, time.Hour)
+}
+
+func TestLoadKeySet(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaPath := writePrivateKey(t, rsaKey)
+	edPath := writePrivateKey(t, edKey)
+	ctx := context.Background()
+
+	t.Run("HMAC Fallback", func(t *testing.T) {
+		keys, err := LoadKeySet(&config.Config{JWTSecret: "secret"})
+		assert.NoError(t, err)
+		assert.Equal(t, "HS256", keys.Signing.Method.Alg())
+		assert.Empty(t, keys.JWKS().Keys, "shared secrets are never published")
+	})
+
+	t.Run("Sign And Verify", func(t *testing.T) {
+		for _, path := range []string{rsaPath, edPath} {
+			keys, err := LoadKeySet(&config.Config{JWTSigningKeyFile: path})
+			assert.NoError(t, err)
+
+			service := newTestService(keys)
+			pair, err := service.IssueTokens(ctx, primitive.NewObjectID(), DeviceInfo{})
+			assert.NoError(t, err)
+			_, err = service.ValidateAccessToken(ctx, pair.AccessToken)
+			assert.NoError(t, err)
+
+			jwks := keys.JWKS()
+			assert.Len(t, jwks.Keys, 1)
+			assert.Equal(t, keys.Signing.ID, jwks.Keys[0].Kid)
+			pub, err := jwks.Keys[0].PublicKey()
+			assert.NoError(t, err)
+			assert.Equal(t, keys.Signing.Public, pub)
+		}
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		oldKeys, _ := LoadKeySet(&config.Config{JWTSigningKeyFile: rsaPath})
+		pair, _ := newTestService(oldKeys).IssueTokens(ctx, primitive.NewObjectID(), DeviceInfo{})
+
+		rotated, err := LoadKeySet(&config.Config{JWTSigningKeyFile: edPath, JWTVerificationKeyFiles: rsaPath})
+		assert.NoError(t, err)
+		assert.Len(t, rotated.JWKS().Keys, 2)
+		assert.Nil(t, rotated.Verification[oldKeys.Signing.ID].Private)
+		_, err = newTestService(rotated).ValidateAccessToken(ctx, pair.AccessToken)
+		assert.NoError(t, err, "tokens signed with the previous key stay valid")
+
+		retired, _ := LoadKeySet(&config.Config{JWTSigningKeyFile: edPath})
+		_, err = newTestService(retired).ValidateAccessToken(ctx, pair.AccessToken)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Algorithm Confusion", func(t *testing.T) {
+		// An HS256 token keyed with the public RSA key must not verify.
+		keys, _ := LoadKeySet(&config.Config{JWTSigningKeyFile: rsaPath})
+		jwk := keys.Signing.JWK()
+		forged := NewHMACKeySet(jwk.N)
+		forged.Signing.ID = keys.Signing.ID
+		pair, _ := newTestService(forged).IssueTokens(ctx, primitive.NewObjectID(), DeviceInfo{})
+
+		_, err := newTestService(keys).ValidateAccessToken(ctx, pair.AccessToken)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Invalid Files", func(t *testing.T) {
+		_, err := LoadKeySet(&config.Config{JWTSigningKeyFile: filepath.Join(t.TempDir(), "missing.pem")})
+		assert.Error(t, err)
+
+		pubDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+		pubPath := filepath.Join(t.TempDir(), "pub.pem")
+		os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o600)
+		_, err = LoadKeySet(&config.Config{JWTSigningKeyFile: pubPath})
+		assert.Error(t, err, "a public key cannot sign")
+
+		_, err = LoadKeySet(&config.Config{JWTVerificationKeyFiles: rsaPath})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/drshn/excalidraw/Backend/internal/config"
+)
+
+// PasswordPolicyError lists every rule a password breaks, so users can fix
+// them all at once.
+type PasswordPolicyError struct {
+	Problems []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password " + strings.Join(e.Problems, "; ")
+}
+
+// PasswordPolicy decides which new passwords are acceptable. Existing
+// passwords are never checked against it, so tightening the policy does not
+// lock anyone out.
+type PasswordPolicy struct {
+	// MinLength and MaxLength count characters, not bytes.
+	MinLength int
+	MaxLength int
+	// RequiredClasses is how many of lowercase letters, uppercase letters,
+	// digits and other characters a password must mix.
+	RequiredClasses int
+	// Breached is checked when set.
+	Breached *BreachedPasswords
+}
+
+// LoadPasswordPolicy returns the policy configured by the PASSWORD_* and
+// BREACHED_PASSWORDS_PATH settings.
+func LoadPasswordPolicy(cfg *config.Config) (*PasswordPolicy, error) {
+	if cfg.PasswordMinLength < 1 || cfg.PasswordMaxLength < cfg.PasswordMinLength {
+		return nil, errors.New("PASSWORD_MIN_LENGTH must be positive and at most PASSWORD_MAX_LENGTH")
+	}
+	if cfg.PasswordRequiredClasses < 0 || cfg.PasswordRequiredClasses > 4 {
+		return nil, errors.New("PASSWORD_REQUIRED_CLASSES must be between 0 and 4")
+	}
+
+	policy := &PasswordPolicy{
+		MinLength:       cfg.PasswordMinLength,
+		MaxLength:       cfg.PasswordMaxLength,
+		RequiredClasses: cfg.PasswordRequiredClasses,
+	}
+	if cfg.BreachedPasswordsPath != "" {
+		breached, err := LoadBreachedPasswords(cfg.BreachedPasswordsPath)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = breached
+	}
+	return policy, nil
+}
+
+// Check returns a *PasswordPolicyError if password may not be used by the
+// account with the given email, or another error if the breached password
+// list cannot be read.
+func (p *PasswordPolicy) Check(password, email string) error {
+	var problems []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d characters", p.MaxLength))
+	}
+	if characterClasses(password) < p.RequiredClasses {
+		problems = append(problems, fmt.Sprintf("must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.RequiredClasses))
+	}
+
+	// Very short local parts, like "jo", turn up in too many good passwords.
+	if local, _, _ := strings.Cut(email, "@"); utf8.RuneCountInString(local) >= 3 &&
+		strings.Contains(strings.ToLower(password), strings.ToLower(local)) {
+		problems = append(problems, "must not contain your email address")
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			problems = append(problems, "appears in a list of passwords exposed in data breaches")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &PasswordPolicyError{Problems: problems}
+	}
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, other int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	return lower + upper + digit + other
+}
+
+// BreachedPasswords is a local copy of a breached password corpus, keyed by
+// uppercase hex SHA-1 as in Have I Been Pwned's Pwned Passwords.
+//
+// A directory holds k-anonymity range files named after the first five
+// characters of the hash (such as 21BD1.txt), each listing the remaining 35
+// characters as SUFFIX:COUNT lines. This is the layout the official
+// downloader produces, and files are read on demand, so the full corpus
+// never has to fit in memory.
+//
+// A single file is loaded into memory instead. Each line is a full hash,
+// optionally followed by :COUNT, or a password in plain text, which makes it
+// easy to ban a handful of site-specific passwords.
+type BreachedPasswords struct {
+	dir    string
+	hashes map[string]struct{}
+}
+
+// LoadBreachedPasswords opens a directory of range files or loads a file.
+func LoadBreachedPasswords(path string) (*BreachedPasswords, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("breached passwords: %w", err)
+	}
+	if info.IsDir() {
+		return &BreachedPasswords{dir: path}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("breached passwords: %w", err)
+	}
+	defer f.Close()
+
+	hashes := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if hash, _, _ := strings.Cut(line, ":"); isSHA1Hex(hash) {
+			hashes[strings.ToUpper(hash)] = struct{}{}
+		} else {
+			hashes[sha1Hex(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("breached passwords: %w", err)
+	}
+	return &BreachedPasswords{hashes: hashes}, nil
+}
+
+// Contains reports whether the password is in the corpus.
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	hash := sha1Hex(password)
+	if b.hashes != nil {
+		_, ok := b.hashes[hash]
+		return ok, nil
+	}
+
+	f, err := os.Open(filepath.Join(b.dir, hash[:5]+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if suffix, _, _ := strings.Cut(scanner.Text(), ":"); strings.EqualFold(strings.TrimSpace(suffix), hash[5:]) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != 2*sha1.Size {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := &PasswordPolicy{MinLength: 10, MaxLength: 20, RequiredClasses: 3}
+
+	assert.NoError(t, policy.Check("Correct-horse", "alice@example.com"))
+	assert.NoError(t, policy.Check("Ünïcödé-pässwörd", "alice@example.com"), "length counts characters")
+
+	err := policy.Check("short", "alice@example.com")
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected a policy error, got %v", err)
+	}
+	assert.Equal(t, []string{
+		"must be at least 10 characters",
+		"must mix at least 3 of lowercase letters, uppercase letters, digits and symbols",
+	}, policyErr.Problems)
+
+	err = policy.Check("Correct-horse-battery-staple", "alice@example.com")
+	assert.EqualError(t, err, "password must be at most 20 characters")
+
+	err = policy.Check("ALICE-2024-spring", "alice@example.com")
+	assert.EqualError(t, err, "password must not contain your email address")
+	assert.NoError(t, policy.Check("Joyful-2024-spring", "jo@example.com"), "short local parts are allowed")
+}
+
+func TestBreachedPasswords(t *testing.T) {
+	dir := t.TempDir()
+
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8.
+	rangeDir := filepath.Join(dir, "ranges")
+	assert.NoError(t, os.Mkdir(rangeDir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(rangeDir, "5BAA6.txt"), []byte("003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004\r\n"), 0o644))
+
+	breached, err := LoadBreachedPasswords(rangeDir)
+	assert.NoError(t, err)
+	found, err := breached.Contains("password")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	found, err = breached.Contains("correct horse battery staple")
+	assert.NoError(t, err)
+	assert.False(t, found, "missing range files mean no match")
+
+	list := filepath.Join(dir, "list.txt")
+	assert.NoError(t, os.WriteFile(list, []byte("5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8:12\nexcalidraw2024\n\n"), 0o644))
+	breached, err = LoadBreachedPasswords(list)
+	assert.NoError(t, err)
+	found, _ = breached.Contains("password")
+	assert.True(t, found)
+	found, _ = breached.Contains("excalidraw2024")
+	assert.True(t, found, "plain text entries are hashed")
+	found, _ = breached.Contains("something else")
+	assert.False(t, found)
+
+	policy := &PasswordPolicy{MinLength: 8, Breached: breached}
+	assert.EqualError(t, policy.Check("excalidraw2024", "alice@example.com"), "password appears in a list of passwords exposed in data breaches")
+
+	_, err = LoadBreachedPasswords(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms.
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+// ErrPasswordTooLong is returned when hashing a password bcrypt would
+// silently truncate.
+var ErrPasswordTooLong = errors.New("password is longer than 72 bytes, which bcrypt cannot hash")
+
+// ErrPasswordHasherBusy is returned when every hashing slot stayed taken for
+// longer than the hasher's MaxWait.
+var ErrPasswordHasherBusy = errors.New("too many passwords are being hashed at once")
+
+var errUnknownPasswordHash = errors.New("unrecognized password hash")
+
+// passwordHashMaxWait is how long hashing waits for a free slot by default.
+const passwordHashMaxWait = 2 * time.Second
+
+// Argon2Params are the Argon2id cost parameters. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// PasswordHasher hashes new passwords with one algorithm and verifies hashes
+// made with any supported algorithm or parameters, so the configuration can
+// change without locking anyone out.
+//
+// Every Argon2id hash holds its full memory cost while it runs, so at most
+// one hash per CPU runs at a time and a burst of logins queues instead of
+// exhausting the server's memory.
+type PasswordHasher struct {
+	Algorithm  string
+	Argon2     Argon2Params
+	BcryptCost int
+	// MaxWait is how long Hash and Verify wait for a free slot before
+	// giving up with ErrPasswordHasherBusy.
+	MaxWait time.Duration
+
+	slots chan struct{}
+	// dummyHash is verified in place of a missing hash so that Verify takes
+	// as long either way.
+	dummyHash string
+}
+
+// NewPasswordHasher returns a hasher for the given algorithm.
+func NewPasswordHasher(algorithm string, argon2Params Argon2Params, bcryptCost int) (*PasswordHasher, error) {
+	switch algorithm {
+	case PasswordAlgorithmArgon2id:
+		if argon2Params.Memory < 8*uint32(argon2Params.Parallelism) || argon2Params.Iterations < 1 || argon2Params.Parallelism < 1 {
+			return nil, errors.New("argon2id needs at least 1 iteration, 1 thread and 8 KiB of memory per thread")
+		}
+		if argon2Params.SaltLength < 8 || argon2Params.KeyLength < 16 {
+			return nil, errors.New("argon2id needs a salt of at least 8 bytes and a key of at least 16")
+		}
+	case PasswordAlgorithmBcrypt:
+		if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return nil, fmt.Errorf("unknown password hashing algorithm %q; use %q or %q", algorithm, PasswordAlgorithmArgon2id, PasswordAlgorithmBcrypt)
+	}
+
+	h := &PasswordHasher{
+		Algorithm:  algorithm,
+		Argon2:     argon2Params,
+		BcryptCost: bcryptCost,
+		MaxWait:    passwordHashMaxWait,
+		slots:      make(chan struct{}, runtime.GOMAXPROCS(0)),
+	}
+	dummyHash, err := h.hash("not-a-real-password")
+	if err != nil {
+		return nil, fmt.Errorf("could not hash a test password: %w", err)
+	}
+	h.dummyHash = dummyHash
+	return h, nil
+}
+
+// LoadPasswordHasher returns the hasher configured by PASSWORD_HASH_ALGORITHM
+// and its ARGON2_* or BCRYPT_COST parameters.
+func LoadPasswordHasher(cfg *config.Config) (*PasswordHasher, error) {
+	if cfg.Argon2Parallelism > 255 {
+		return nil, errors.New("ARGON2_PARALLELISM must be at most 255")
+	}
+	return NewPasswordHasher(cfg.PasswordHashAlgorithm, Argon2Params{
+		Memory:      cfg.Argon2Memory,
+		Iterations:  cfg.Argon2Iterations,
+		Parallelism: uint8(cfg.Argon2Parallelism),
+		SaltLength:  16,
+		KeyLength:   32,
+	}, cfg.BcryptCost)
+}
+
+// acquire takes a hashing slot, waiting up to MaxWait for one, and returns
+// the function that gives it back.
+func (h *PasswordHasher) acquire() (release func(), err error) {
+	timer := time.NewTimer(h.MaxWait)
+	defer timer.Stop()
+	select {
+	case h.slots <- struct{}{}:
+		return func() { <-h.slots }, nil
+	case <-timer.C:
+		return nil, ErrPasswordHasherBusy
+	}
+}
+
+// Hash returns the encoded hash of a password: a PHC string for Argon2id,
+// or the usual $2a$ format for bcrypt.
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	release, err := h.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return h.hash(password)
+}
+
+func (h *PasswordHasher) hash(password string) (string, error) {
+	if h.Algorithm == PasswordAlgorithmBcrypt {
+		if len(password) > 72 {
+			return "", ErrPasswordTooLong
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+		return string(hash), err
+	}
+
+	p := h.Argon2
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether password matches the encoded hash, and whether the
+// hash should be replaced because it uses another algorithm or weaker
+// parameters than the hasher's. An empty hash never matches but takes as
+// long to check as a real one, so callers do not reveal which accounts
+// exist or have a password. The only error is ErrPasswordHasherBusy.
+func (h *PasswordHasher) Verify(password, encoded string) (match, needsRehash bool, err error) {
+	if encoded == "" {
+		_, _, err := h.Verify(password, h.dummyHash)
+		return false, false, err
+	}
+
+	release, err := h.acquire()
+	if err != nil {
+		return false, false, err
+	}
+	defer release()
+	match, needsRehash = h.verify(password, encoded)
+	return match, needsRehash, nil
+}
+
+func (h *PasswordHasher) verify(password, encoded string) (match, needsRehash bool) {
+	if strings.HasPrefix(encoded, "$argon2id$") {
+		p, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, false
+		}
+		candidate := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+		if subtle.ConstantTimeCompare(candidate, key) != 1 {
+			return false, false
+		}
+		want := h.Argon2
+		weaker := p.Memory < want.Memory || p.Iterations < want.Iterations || p.Parallelism < want.Parallelism ||
+			p.SaltLength < want.SaltLength || p.KeyLength < want.KeyLength
+		return true, h.Algorithm != PasswordAlgorithmArgon2id || weaker
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) != nil {
+		return false, false
+	}
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return true, err != nil || h.Algorithm != PasswordAlgorithmBcrypt || cost < h.BcryptCost
+}
+
+// decodeArgon2id parses $argon2id$v=19$m=...,t=...,p=...$salt$key.
+func decodeArgon2id(encoded string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, errUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errUnknownPasswordHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, errUnknownPasswordHash
+	}
+	if p.Iterations < 1 || p.Parallelism < 1 {
+		return p, nil, nil, errUnknownPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, errUnknownPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errUnknownPasswordHash
+	}
+	p.SaltLength, p.KeyLength = uint32(len(salt)), uint32(len(key))
+	return p, salt, key, nil
+}
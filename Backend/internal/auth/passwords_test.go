@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2Params = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestPasswordHasherArgon2id(t *testing.T) {
+	hasher, err := NewPasswordHasher(PasswordAlgorithmArgon2id, testArgon2Params, 0)
+	assert.NoError(t, err)
+
+	hash, err := hasher.Hash("correct horse")
+	assert.NoError(t, err)
+	assert.Regexp(t, `^\$argon2id\$v=19\$m=64,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`, hash)
+
+	other, _ := hasher.Hash("correct horse")
+	assert.NotEqual(t, hash, other, "hashes are salted")
+
+	match, needsRehash, _ := hasher.Verify("correct horse", hash)
+	assert.True(t, match)
+	assert.False(t, needsRehash)
+	match, _, _ = hasher.Verify("battery staple", hash)
+	assert.False(t, match)
+
+	// Passwords bcrypt would truncate are hashed in full.
+	long := strings.Repeat("a", 100)
+	hash, err = hasher.Hash(long)
+	assert.NoError(t, err)
+	match, _, _ = hasher.Verify(long[:72], hash)
+	assert.False(t, match)
+
+	match, _, _ = hasher.Verify("correct horse", "$argon2id$v=19$m=64,t=1,p=0$c2FsdA$a2V5")
+	assert.False(t, match, "malformed hashes never match")
+	match, _, _ = hasher.Verify("", "")
+	assert.False(t, match, "an empty hash never matches")
+}
+
+func TestPasswordHasherRehash(t *testing.T) {
+	argon2id, _ := NewPasswordHasher(PasswordAlgorithmArgon2id, testArgon2Params, 0)
+	bcryptHasher, _ := NewPasswordHasher(PasswordAlgorithmBcrypt, Argon2Params{}, bcrypt.MinCost+1)
+
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	match, needsRehash, _ := argon2id.Verify("correct horse", string(legacy))
+	assert.True(t, match, "bcrypt hashes still verify after switching to Argon2id")
+	assert.True(t, needsRehash)
+
+	match, needsRehash, _ = bcryptHasher.Verify("correct horse", string(legacy))
+	assert.True(t, match)
+	assert.True(t, needsRehash, "a lower bcrypt cost is upgraded")
+
+	current, _ := bcryptHasher.Hash("correct horse")
+	_, needsRehash, _ = bcryptHasher.Verify("correct horse", current)
+	assert.False(t, needsRehash)
+
+	weak, _ := argon2id.Hash("correct horse")
+	stronger := testArgon2Params
+	stronger.Iterations = 2
+	strongerHasher, _ := NewPasswordHasher(PasswordAlgorithmArgon2id, stronger, 0)
+	match, needsRehash, _ = strongerHasher.Verify("correct horse", weak)
+	assert.True(t, match)
+	assert.True(t, needsRehash, "weaker Argon2id parameters are upgraded")
+
+	_, needsRehash, _ = argon2id.Verify("correct horse", current)
+	assert.True(t, needsRehash, "bcrypt hashes are replaced by the configured algorithm")
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	_, err := NewPasswordHasher("md5", testArgon2Params, 0)
+	assert.Error(t, err)
+	_, err = NewPasswordHasher(PasswordAlgorithmArgon2id, Argon2Params{Memory: 64, Iterations: 0, Parallelism: 1, SaltLength: 16, KeyLength: 32}, 0)
+	assert.Error(t, err)
+	_, err = NewPasswordHasher(PasswordAlgorithmBcrypt, Argon2Params{}, 40)
+	assert.Error(t, err)
+
+	hasher, err := NewPasswordHasher(PasswordAlgorithmBcrypt, Argon2Params{}, bcrypt.MinCost)
+	assert.NoError(t, err)
+	_, err = hasher.Hash(strings.Repeat("a", 73))
+	assert.ErrorIs(t, err, ErrPasswordTooLong)
+}
+
+func TestPasswordHasherBusy(t *testing.T) {
+	hasher, _ := NewPasswordHasher(PasswordAlgorithmArgon2id, testArgon2Params, 0)
+	hash, _ := hasher.Hash("correct horse")
+	hasher.MaxWait = 10 * time.Millisecond
+
+	for i := 0; i < cap(hasher.slots); i++ {
+		hasher.slots <- struct{}{}
+	}
+	_, err := hasher.Hash("correct horse")
+	assert.ErrorIs(t, err, ErrPasswordHasherBusy)
+	_, _, err = hasher.Verify("correct horse", hash)
+	assert.ErrorIs(t, err, ErrPasswordHasherBusy)
+	_, _, err = hasher.Verify("correct horse", "")
+	assert.ErrorIs(t, err, ErrPasswordHasherBusy, "unknown accounts wait like real ones")
+
+	<-hasher.slots
+	match, _, err := hasher.Verify("correct horse", hash)
+	assert.NoError(t, err)
+	assert.True(t, match, "a freed slot is used")
+}
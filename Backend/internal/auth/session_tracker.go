@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SessionTracker buffers session activity in memory and periodically writes
+// last-seen times in a single batch, so authenticated requests do not each
+// cost a database write.
+type SessionTracker struct {
+	SessionRepo repository.SessionRepository
+	Interval    time.Duration
+
+	mu      sync.Mutex
+	pending map[primitive.ObjectID]time.Time
+}
+
+func NewSessionTracker(sessionRepo repository.SessionRepository, interval time.Duration) *SessionTracker {
+	return &SessionTracker{
+		SessionRepo: sessionRepo,
+		Interval:    interval,
+		pending:     make(map[primitive.ObjectID]time.Time),
+	}
+}
+
+// Touch records that the session was just used.
+func (t *SessionTracker) Touch(sessionID primitive.ObjectID) {
+	t.mu.Lock()
+	t.pending[sessionID] = time.Now().UTC()
+	t.mu.Unlock()
+}
+
+// Run flushes buffered activity on every interval until ctx is cancelled,
+// then flushes once more.
+func (t *SessionTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.Flush(context.Background())
+			return
+		case <-ticker.C:
+			t.Flush(ctx)
+		}
+	}
+}
+
+func (t *SessionTracker) Flush(ctx context.Context) {
+	t.mu.Lock()
+	batch := t.pending
+	t.pending = make(map[primitive.ObjectID]time.Time)
+	t.mu.Unlock()
+
+	if err := t.SessionRepo.TouchMany(ctx, batch); err != nil {
+		log.Printf("Failed to update session activity: %v", err)
+	}
+}
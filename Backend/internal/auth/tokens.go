@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidToken      = errors.New("invalid or expired token")
+	ErrRevokedToken      = errors.New("token has been revoked")
+	ErrRefreshTokenReuse = errors.New("refresh token reuse detected")
+)
+
+// Claims are the claims of an access token. SessionID identifies the refresh
+// token family the access token was issued from, so revoking the family
+// revokes its access tokens too.
+type Claims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid"`
+}
+
+// TokenPair is what a client receives after logging in or refreshing.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// DeviceInfo describes the client a session was started from.
+type DeviceInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// TokenService issues short-lived access tokens and rotating refresh tokens,
+// and tracks the session each refresh token family belongs to.
+type TokenService struct {
+	RefreshTokenRepo repository.RefreshTokenRepository
+	RevocationRepo   repository.RevocationRepository
+	SessionRepo      repository.SessionRepository
+	Keys             *KeySet
+	AccessTokenTTL   time.Duration
+	RefreshTokenTTL  time.Duration
+}
+
+func NewTokenService(refreshTokenRepo repository.RefreshTokenRepository, revocationRepo repository.RevocationRepository, sessionRepo repository.SessionRepository, keys *KeySet, accessTokenTTL, refreshTokenTTL time.Duration) *TokenService {
+	return &TokenService{
+		RefreshTokenRepo: refreshTokenRepo,
+		RevocationRepo:   revocationRepo,
+		SessionRepo:      sessionRepo,
+		Keys:             keys,
+		AccessTokenTTL:   accessTokenTTL,
+		RefreshTokenTTL:  refreshTokenTTL,
+	}
+}
+
+// IssueTokens starts a new session (refresh token family) for the user.
+func (s *TokenService) IssueTokens(ctx context.Context, userID primitive.ObjectID, device DeviceInfo) (*TokenPair, error) {
+	now := time.Now().UTC()
+	session := &models.Session{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		UserAgent:  device.UserAgent,
+		IP:         device.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.RefreshTokenTTL),
+	}
+	if err := s.SessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, userID, session.ID)
+}
+
+// Refresh exchanges a refresh token for a new token pair in the same family.
+// Presenting a token that was already exchanged revokes the whole family,
+// since either the client or an attacker holds a stolen copy.
+func (s *TokenService) Refresh(ctx context.Context, rawRefreshToken string) (*TokenPair, error) {
+	token, err := s.RefreshTokenRepo.FindByHash(ctx, HashToken(rawRefreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	if token.RevokedAt != nil {
+		return nil, ErrRevokedToken
+	}
+
+	ok, err := s.RefreshTokenRepo.MarkUsed(ctx, token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.RevokeSession(ctx, token.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReuse
+	}
+
+	pair, err := s.issue(ctx, token.UserID, token.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.SessionRepo.Extend(ctx, token.FamilyID, time.Now().Add(s.RefreshTokenTTL).UTC()); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// ListSessions returns the user's active sessions.
+func (s *TokenService) ListSessions(ctx context.Context, userID primitive.ObjectID) ([]*models.Session, error) {
+	return s.SessionRepo.FindActiveByUserID(ctx, userID)
+}
+
+// RevokeSession revokes a refresh token family and the access tokens issued
+// from it.
+func (s *TokenService) RevokeSession(ctx context.Context, sessionID primitive.ObjectID) error {
+	if err := s.RefreshTokenRepo.RevokeFamily(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := s.SessionRepo.Revoke(ctx, sessionID); err != nil {
+		return err
+	}
+	return s.RevocationRepo.Revoke(ctx, sessionID.Hex(), time.Now().Add(s.AccessTokenTTL))
+}
+
+// RevokeAllSessions signs the user out everywhere.
+func (s *TokenService) RevokeAllSessions(ctx context.Context, userID primitive.ObjectID) error {
+	families, err := s.RefreshTokenRepo.RevokeAllByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.SessionRepo.RevokeAllByUserID(ctx, userID); err != nil {
+		return err
+	}
+	for _, family := range families {
+		if err := s.RevocationRepo.Revoke(ctx, family.Hex(), time.Now().Add(s.AccessTokenTTL)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokeOtherSessions signs the user out everywhere except the session
+// keep, such as after a password change.
+func (s *TokenService) RevokeOtherSessions(ctx context.Context, userID, keep primitive.ObjectID) error {
+	sessions, err := s.SessionRepo.FindActiveByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID == keep {
+			continue
+		}
+		if err := s.RevokeSession(ctx, session.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateAccessToken verifies an access token's signature and expiry and
+// checks that its session has not been revoked.
+func (s *TokenService) ValidateAccessToken(ctx context.Context, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.Keys.keyFunc)
+	if err != nil || !token.Valid || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.SessionID != "" {
+		revoked, err := s.RevocationRepo.IsRevoked(ctx, claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrRevokedToken
+		}
+	}
+	return claims, nil
+}
+
+func (s *TokenService) issue(ctx context.Context, userID, familyID primitive.ObjectID) (*TokenPair, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.AccessTokenTTL)
+
+	accessToken, err := s.Keys.sign(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID.Hex(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			ID:        primitive.NewObjectID().Hex(),
+		},
+		SessionID: familyID.Hex(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rawRefreshToken, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken := &models.RefreshToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: HashToken(rawRefreshToken),
+		CreatedAt: now.UTC(),
+		ExpiresAt: now.Add(s.RefreshTokenTTL).UTC(),
+	}
+	if err := s.RefreshTokenRepo.Create(ctx, refreshToken); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: rawRefreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// GenerateToken returns a random URL-safe token with 256 bits of entropy.
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 hash under which an opaque token is stored.
+func HashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 appendix B test vector for SHA-1, truncated to 6 digits.
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	code, err := TOTPCode(secret, time.Unix(59, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, "287082", code)
+	code, _ = TOTPCode(secret, time.Unix(1111111109, 0))
+	assert.Equal(t, "081804", code)
+
+	now := time.Unix(1700000000, 0)
+	previous, _ := TOTPCode(secret, now.Add(-30*time.Second))
+	step, ok := ValidateTOTP(secret, previous, now)
+	assert.True(t, ok, "codes from the previous period are accepted")
+	assert.Equal(t, totpStep(now)-1, step)
+
+	stale, _ := TOTPCode(secret, now.Add(-2*time.Minute))
+	_, ok = ValidateTOTP(secret, stale, now)
+	assert.False(t, ok)
+	_, ok = ValidateTOTP(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	assert.NoError(t, err)
+	assert.Len(t, codes, 10)
+	assert.Regexp(t, `^[a-z2-9]{5}-[a-z2-9]{5}$`, codes[0])
+	assert.Equal(t, HashRecoveryCode(codes[0]), HashRecoveryCode(" "+codes[0][:5]+codes[0][6:]+" "))
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("Excalidraw", "user@example.com", "ABC")
+	assert.Equal(t, "otpauth://totp/Excalidraw:user@example.com?algorithm=SHA1&digits=6&issuer=Excalidraw&period=30&secret=ABC", uri)
+}
@@ -1,16 +1,99 @@
 package config
 
 import (
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
+// DefaultJWTSecret is the JWT_SECRET default, which must not be used in
+// production.
+const DefaultJWTSecret = "a-very-secret-key"
+
 type Config struct {
 	Port       string `mapstructure:"PORT"`
 	MongoDBURI string `mapstructure:"MONGODB_URI"`
 	DBName     string `mapstructure:"DB_NAME"`
 	JWTSecret  string `mapstructure:"JWT_SECRET"`
+
+	// JWTSigningKeyFile is a PEM RSA or Ed25519 private key to sign access
+	// tokens with instead of JWTSecret. JWTVerificationKeyFiles is a
+	// comma-separated list of previous keys still accepted during rotation.
+	JWTSigningKeyFile       string `mapstructure:"JWT_SIGNING_KEY_FILE"`
+	JWTVerificationKeyFiles string `mapstructure:"JWT_VERIFICATION_KEY_FILES"`
+
+	AccessTokenTTL  time.Duration `mapstructure:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL time.Duration `mapstructure:"REFRESH_TOKEN_TTL"`
+	// SessionFlushInterval is how often buffered session last-seen times are
+	// written to the database.
+	SessionFlushInterval time.Duration `mapstructure:"SESSION_FLUSH_INTERVAL"`
+
+	// AppBaseURL is the public URL of the frontend, used to build links in
+	// emails.
+	AppBaseURL string `mapstructure:"APP_BASE_URL"`
+	// APIBaseURL is the public URL of this server, used to build OIDC
+	// redirect URIs.
+	APIBaseURL string `mapstructure:"API_BASE_URL"`
+
+	// OIDCProviders is a JSON array of single sign-on providers, each with
+	// name, displayName, issuer, clientId, clientSecret and scopes.
+	OIDCProviders string `mapstructure:"OIDC_PROVIDERS"`
+
+	// MailDriver selects how email is sent: "smtp", or "log" to write
+	// messages to MailDir (or the server log) for local development.
+	MailDriver   string `mapstructure:"MAIL_DRIVER"`
+	MailDir      string `mapstructure:"MAIL_DIR"`
+	MailFrom     string `mapstructure:"MAIL_FROM"`
+	SMTPHost     string `mapstructure:"SMTP_HOST"`
+	SMTPPort     int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
+
+	PasswordResetTTL time.Duration `mapstructure:"PASSWORD_RESET_TTL"`
+
+	// PasswordHashAlgorithm is "argon2id" or "bcrypt". Hashes made with the
+	// other algorithm, or weaker parameters, still verify and are replaced
+	// on the user's next login. Argon2Memory is in KiB.
+	PasswordHashAlgorithm string `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	Argon2Memory          uint32 `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations      uint32 `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism     uint32 `mapstructure:"ARGON2_PARALLELISM"`
+	BcryptCost            int    `mapstructure:"BCRYPT_COST"`
+
+	// New passwords must be PasswordMinLength to PasswordMaxLength
+	// characters and mix PasswordRequiredClasses of lowercase, uppercase,
+	// digits and symbols. BreachedPasswordsPath is a file or directory of
+	// breached password hashes to reject (see auth.BreachedPasswords).
+	PasswordMinLength       int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength       int    `mapstructure:"PASSWORD_MAX_LENGTH"`
+	PasswordRequiredClasses int    `mapstructure:"PASSWORD_REQUIRED_CLASSES"`
+	BreachedPasswordsPath   string `mapstructure:"BREACHED_PASSWORDS_PATH"`
+
+	// RegistrationMode is "open", "disabled", "domains" (only addresses in
+	// the comma-separated RegistrationAllowedDomains, which also needs
+	// RequireEmailVerification) or "invite" (only with an invitation code).
+	// Admins can always issue invitations, which last InvitationTTL; with
+	// InvitationsByMembers set, every user can.
+	RegistrationMode           string        `mapstructure:"REGISTRATION_MODE"`
+	RegistrationAllowedDomains string        `mapstructure:"REGISTRATION_ALLOWED_DOMAINS"`
+	InvitationTTL              time.Duration `mapstructure:"INVITATION_TTL"`
+	InvitationsByMembers       bool          `mapstructure:"INVITATIONS_BY_MEMBERS"`
+
+	// EmailVerificationTTL is how long a verification link stays valid.
+	// With RequireEmailVerification set, users cannot create drawings until
+	// they have verified their address.
+	EmailVerificationTTL     time.Duration `mapstructure:"EMAIL_VERIFICATION_TTL"`
+	RequireEmailVerification bool          `mapstructure:"REQUIRE_EMAIL_VERIFICATION"`
+
+	// TOTPIssuer is the account name shown in authenticator apps.
+	TOTPIssuer string `mapstructure:"TOTP_ISSUER"`
+
+	// TrashRetention is how long a deleted drawing stays in the trash before
+	// it is purged; TrashPurgeInterval is how often the purge job runs.
+	TrashRetention     time.Duration `mapstructure:"TRASH_RETENTION"`
+	TrashPurgeInterval time.Duration `mapstructure:"TRASH_PURGE_INTERVAL"`
 }
 
 func LoadConfig() *Config {
@@ -20,7 +103,41 @@ func LoadConfig() *Config {
 	v.SetDefault("PORT", "8080")
 	v.SetDefault("MONGODB_URI", "mongodb://localhost:27017")
 	v.SetDefault("DB_NAME", "excalidraw")
-	v.SetDefault("JWT_SECRET", "a-very-secret-key")
+	v.SetDefault("JWT_SECRET", DefaultJWTSecret)
+	v.SetDefault("JWT_SIGNING_KEY_FILE", "")
+	v.SetDefault("JWT_VERIFICATION_KEY_FILES", "")
+	v.SetDefault("ACCESS_TOKEN_TTL", "15m")
+	v.SetDefault("REFRESH_TOKEN_TTL", "720h")
+	v.SetDefault("SESSION_FLUSH_INTERVAL", "30s")
+	v.SetDefault("APP_BASE_URL", "http://localhost:5173")
+	v.SetDefault("API_BASE_URL", "http://localhost:8080")
+	v.SetDefault("OIDC_PROVIDERS", "")
+	v.SetDefault("MAIL_DRIVER", "log")
+	v.SetDefault("MAIL_DIR", "")
+	v.SetDefault("MAIL_FROM", "Excalidraw <no-reply@localhost>")
+	v.SetDefault("SMTP_HOST", "localhost")
+	v.SetDefault("SMTP_PORT", 587)
+	v.SetDefault("SMTP_USERNAME", "")
+	v.SetDefault("SMTP_PASSWORD", "")
+	v.SetDefault("PASSWORD_RESET_TTL", "1h")
+	v.SetDefault("PASSWORD_HASH_ALGORITHM", "argon2id")
+	v.SetDefault("ARGON2_MEMORY", 65536)
+	v.SetDefault("ARGON2_ITERATIONS", 3)
+	v.SetDefault("ARGON2_PARALLELISM", 2)
+	v.SetDefault("BCRYPT_COST", 12)
+	v.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	v.SetDefault("PASSWORD_MAX_LENGTH", 256)
+	v.SetDefault("PASSWORD_REQUIRED_CLASSES", 0)
+	v.SetDefault("BREACHED_PASSWORDS_PATH", "")
+	v.SetDefault("REGISTRATION_MODE", "open")
+	v.SetDefault("REGISTRATION_ALLOWED_DOMAINS", "")
+	v.SetDefault("INVITATION_TTL", "168h")
+	v.SetDefault("INVITATIONS_BY_MEMBERS", false)
+	v.SetDefault("EMAIL_VERIFICATION_TTL", "48h")
+	v.SetDefault("REQUIRE_EMAIL_VERIFICATION", false)
+	v.SetDefault("TOTP_ISSUER", "Excalidraw")
+	v.SetDefault("TRASH_RETENTION", "720h")
+	v.SetDefault("TRASH_PURGE_INTERVAL", "1h")
 
 	// Read from environment variables
 	v.AutomaticEnv()
@@ -41,4 +158,35 @@ func LoadConfig() *Config {
 	}
 
 	return &cfg
+}
+
+// Duration is a named duration setting.
+type Duration struct {
+	Name  string
+	Value time.Duration
+}
+
+// Durations lists the duration settings, all of which must be positive.
+func (c *Config) Durations() []Duration {
+	return []Duration{
+		{"ACCESS_TOKEN_TTL", c.AccessTokenTTL},
+		{"REFRESH_TOKEN_TTL", c.RefreshTokenTTL},
+		{"SESSION_FLUSH_INTERVAL", c.SessionFlushInterval},
+		{"PASSWORD_RESET_TTL", c.PasswordResetTTL},
+		{"EMAIL_VERIFICATION_TTL", c.EmailVerificationTTL},
+		{"TRASH_RETENTION", c.TrashRetention},
+		{"TRASH_PURGE_INTERVAL", c.TrashPurgeInterval},
+		{"INVITATION_TTL", c.InvitationTTL},
+	}
+}
+
+// Validate rejects settings the server cannot run with, such as a zero
+// TRASH_RETENTION, which would purge the whole trash at once.
+func (c *Config) Validate() error {
+	for _, d := range c.Durations() {
+		if d.Value <= 0 {
+			return fmt.Errorf("%s must be a positive duration, got %s", d.Name, d.Value)
+		}
+	}
+	return nil
 }
\ No newline at end of file
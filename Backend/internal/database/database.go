@@ -20,7 +20,10 @@ func GetMongoClient(cfg *config.Config) (*mongo.Client, error) {
 	var err error
 	once.Do(func() {
 		serverAPI := options.ServerAPI(options.ServerAPIVersion1)
-		opts := options.Client().ApplyURI(cfg.MongoDBURI).SetServerAPIOptions(serverAPI)
+		// Decode nested documents in untyped fields (e.g. library item elements)
+		// as maps so they serialize back to plain JSON objects.
+		bsonOpts := &options.BSONOptions{DefaultDocumentM: true}
+		opts := options.Client().ApplyURI(cfg.MongoDBURI).SetServerAPIOptions(serverAPI).SetBSONOptions(bsonOpts)
 
 		client, innerErr := mongo.Connect(context.TODO(), opts)
 		if innerErr != nil {
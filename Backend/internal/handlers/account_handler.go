@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/mail"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/drshn/excalidraw/Backend/internal/ratelimit"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AccountHandler serves data-subject requests: exporting everything stored
+// about the user, and deleting the account.
+//
+// Drawings have a single owner and are never shared, so deleting an account
+// removes all of its drawings together with their comments, which can only
+// be written by the owner. Sessions are revoked rather than deleted and
+// expire on their own.
+type AccountHandler struct {
+	UserRepo                repository.UserRepository
+	DrawingRepo             repository.DrawingRepository
+	CommentRepo             repository.CommentRepository
+	LibraryRepo             repository.LibraryRepository
+	InvitationRepo          repository.InvitationRepository
+	PersonalAccessTokenRepo repository.PersonalAccessTokenRepository
+	UserTokenRepo           repository.UserTokenRepository
+	Tokens                  *auth.TokenService
+	Passwords               *auth.PasswordHasher
+	Mailer                  mail.Mailer
+	// Limiter limits how often a deletion link can be emailed to a user.
+	Limiter    *ratelimit.Limiter
+	AppBaseURL string
+}
+
+func NewAccountHandler(userRepo repository.UserRepository, drawingRepo repository.DrawingRepository, commentRepo repository.CommentRepository, libraryRepo repository.LibraryRepository, invitationRepo repository.InvitationRepository, personalAccessTokenRepo repository.PersonalAccessTokenRepository, userTokenRepo repository.UserTokenRepository, tokens *auth.TokenService, passwords *auth.PasswordHasher, mailer mail.Mailer, appBaseURL string) *AccountHandler {
+	return &AccountHandler{
+		UserRepo:                userRepo,
+		DrawingRepo:             drawingRepo,
+		CommentRepo:             commentRepo,
+		LibraryRepo:             libraryRepo,
+		InvitationRepo:          invitationRepo,
+		PersonalAccessTokenRepo: personalAccessTokenRepo,
+		UserTokenRepo:           userTokenRepo,
+		Tokens:                  tokens,
+		Passwords:               passwords,
+		Mailer:                  mailer,
+		Limiter:                 ratelimit.NewLimiter(3, 15*time.Minute),
+		AppBaseURL:              appBaseURL,
+	}
+}
+
+// ExportAccount downloads a zip archive of the user's profile, drawings
+// (including the trash), comments, libraries, sessions and access tokens.
+//
+// Drawings keep no version history, and images are embedded in the scene
+// data rather than stored as separate files, so the scenes hold all of it.
+func (h *AccountHandler) ExportAccount(c *gin.Context) {
+	user, ok := findCurrentUser(c, h.UserRepo)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	summaries, err := h.LibraryRepo.FindAllByUserID(ctx, user.ID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	var libraries []*models.Library
+	for _, summary := range summaries {
+		library, err := h.LibraryRepo.FindByIDAndUserID(ctx, summary.ID, user.ID)
+		if err != nil {
+			RepositoryError(c, err)
+			return
+		}
+		if library != nil {
+			libraries = append(libraries, library)
+		}
+	}
+
+	sessions, err := h.Tokens.ListSessions(ctx, user.ID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	accessTokens, err := h.PersonalAccessTokenRepo.FindAllByUserID(ctx, user.ID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	if err := h.WriteDrawings(ctx, archive, user.ID); err != nil {
+		InternalServerError(c, err)
+		return
+	}
+	files := []exportFile{
+		{"profile.json", newUserProfile(user)},
+		{"sessions.json", sessions},
+		{"tokens.json", accessTokens},
+	}
+	for _, library := range libraries {
+		files = append(files, exportFile{
+			"libraries/" + library.ID.Hex() + ".excalidrawlib",
+			ExcalidrawLibFile{Type: "excalidrawlib", Version: 2, Source: "drawcali", LibraryItems: library.Items},
+		})
+	}
+	for _, file := range files {
+		if err := writeJSONToZip(archive, file.name, file.data); err != nil {
+			InternalServerError(c, err)
+			return
+		}
+	}
+	if user.Avatar != nil {
+		name := "avatar." + strings.TrimPrefix(user.Avatar.ContentType, "image/")
+		if err := writeToZip(archive, name, user.Avatar.Data); err != nil {
+			InternalServerError(c, err)
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("excalidraw-export-%s.zip", time.Now().UTC().Format("2006-01-02"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// WriteDrawings adds the user's drawings, including the trash, and their
+// comments to an export archive.
+func (h *AccountHandler) WriteDrawings(ctx context.Context, archive *zip.Writer, userID primitive.ObjectID) error {
+	drawings, err := h.DrawingRepo.FindAllWithScenesByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	comments := []*models.Comment{}
+	for _, drawing := range drawings {
+		drawingComments, err := h.CommentRepo.FindByDrawingID(ctx, drawing.ID)
+		if err != nil {
+			return err
+		}
+		comments = append(comments, drawingComments...)
+	}
+
+	if err := writeJSONToZip(archive, "drawings.json", drawings); err != nil {
+		return err
+	}
+	if err := writeJSONToZip(archive, "comments.json", comments); err != nil {
+		return err
+	}
+	// Scenes are stored as serialized .excalidraw files, so they are written
+	// as-is and can be opened directly.
+	for _, drawing := range drawings {
+		if err := writeToZip(archive, "drawings/"+drawing.ID.Hex()+".excalidraw", []byte(drawing.SceneData)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accountDeletionTTL is how long an emailed account deletion link is valid.
+const accountDeletionTTL = time.Hour
+
+// DeleteAccountRequest confirms an account deletion. Users who sign in only
+// through single sign-on have no password; they confirm with the token from
+// an emailed link instead.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
+// DeleteAccount permanently deletes the user and everything they own, and
+// signs them out everywhere. For users without a password, a request
+// without a token emails them a deletion link and deletes nothing.
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	user, ok := findCurrentUser(c, h.UserRepo)
+	if !ok {
+		return
+	}
+	if user.Password != "" {
+		if !confirmPassword(c, h.Passwords, req.Password, user.Password) {
+			return
+		}
+	} else if req.Token == "" {
+		h.sendDeletionLink(c, user)
+		return
+	} else {
+		token, err := consumeUserToken(c.Request.Context(), h.UserTokenRepo, models.TokenPurposeAccountDeletion, req.Token)
+		if err != nil {
+			if errors.Is(err, errInvalidUserToken) {
+				HandleError(c, http.StatusBadRequest, problem.InvalidEmailToken, "Invalid or expired token")
+				return
+			}
+			RepositoryError(c, err)
+			return
+		}
+		if token.UserID != user.ID {
+			HandleError(c, http.StatusBadRequest, problem.InvalidEmailToken, "Invalid or expired token")
+			return
+		}
+	}
+
+	// Sign out first so nothing is created while data is being removed. The
+	// user record goes last, so a failed deletion can simply be retried.
+	ctx := c.Request.Context()
+	if err := h.Tokens.RevokeAllSessions(ctx, user.ID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if err := h.PersonalAccessTokenRepo.DeleteAllByUserID(ctx, user.ID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	drawings, err := h.DrawingRepo.FindAllWithScenesByUserID(ctx, user.ID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	for _, drawing := range drawings {
+		if err := h.CommentRepo.DeleteByDrawingID(ctx, drawing.ID); err != nil {
+			RepositoryError(c, err)
+			return
+		}
+	}
+	if _, err := h.DrawingRepo.PurgeAllByUserID(ctx, user.ID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if err := h.LibraryRepo.DeleteAllByUserID(ctx, user.ID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	// Unused invitations would still let people sign up on the deleted
+	// user's behalf.
+	if err := h.InvitationRepo.DeleteAllByCreator(ctx, user.ID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if err := h.UserTokenRepo.DeleteAllByUserID(ctx, user.ID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if err := h.UserRepo.Delete(ctx, user.ID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	log.Printf("Deleted account %s with %d drawings", user.ID.Hex(), len(drawings))
+
+	sendMailAsync(h.Mailer, mail.Message{
+		To:      user.Email,
+		Subject: "Your account has been deleted",
+		Body:    "Your account and all of its drawings have been permanently deleted.\n",
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+// sendDeletionLink emails a single sign-on user a link to confirm deleting
+// their account, which proves they still control its mailbox.
+func (h *AccountHandler) sendDeletionLink(c *gin.Context, user *models.User) {
+	if ok, retryAfter := h.Limiter.Allow(user.ID.Hex()); !ok {
+		TooManyRequests(c, retryAfter)
+		return
+	}
+
+	rawToken, err := issueUserToken(c.Request.Context(), h.UserTokenRepo, user.ID, models.TokenPurposeAccountDeletion, "", accountDeletionTTL)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	link := strings.TrimRight(h.AppBaseURL, "/") + "/delete-account?token=" + url.QueryEscape(rawToken)
+	sendMailAsync(h.Mailer, mail.Message{
+		To:      user.Email,
+		Subject: "Confirm deleting your account",
+		Body: fmt.Sprintf("To permanently delete your account and all of its drawings, open this link:\n%s\n\n"+
+			"The link expires in %s. If this was not you, ignore this email.\n",
+			link, accountDeletionTTL),
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "A confirmation link has been sent to your email"})
+}
+
+// exportFile is a file in the export archive, written as indented JSON.
+type exportFile struct {
+	name string
+	data interface{}
+}
+
+func writeJSONToZip(archive *zip.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeToZip(archive, name, data)
+}
+
+func writeToZip(archive *zip.Writer, name string, data []byte) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAccountHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	mockUserRepo := repository.NewMockUserRepository()
+	mockDrawingRepo := repository.NewMockDrawingRepository()
+	mockCommentRepo := repository.NewMockCommentRepository()
+	mockLibraryRepo := repository.NewMockLibraryRepository()
+	mockInvitationRepo := repository.NewMockInvitationRepository()
+	mockPersonalAccessTokenRepo := repository.NewMockPersonalAccessTokenRepository()
+	mockUserTokenRepo := repository.NewMockUserTokenRepository()
+	tokens := newTestTokenService()
+	mailer := newRecordingMailer()
+	authHandler := NewAuthHandler(mockUserRepo, tokens, newTestPasswordHasher(), newTestPasswordPolicy(), nil, nil, nil)
+	accountHandler := NewAccountHandler(mockUserRepo, mockDrawingRepo, mockCommentRepo, mockLibraryRepo, mockInvitationRepo, mockPersonalAccessTokenRepo, mockUserTokenRepo, tokens, newTestPasswordHasher(), mailer, "https://draw.example.com")
+
+	router := gin.Default()
+	router.POST("/register", authHandler.Register)
+	router.POST("/login", authHandler.Login)
+	router.GET("/users/me/export", newTestAuthMiddleware(tokens), accountHandler.ExportAccount)
+	router.DELETE("/users/me", newTestAuthMiddleware(tokens), accountHandler.DeleteAccount)
+
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	const credentials = `{"email": "leaving@example.com", "password": "password123"}`
+
+	do(http.MethodPost, "/register", credentials, "")
+	w := do(http.MethodPost, "/login", credentials, "")
+	var login map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &login)
+	token := login["token"].(string)
+	userID := mockUserRepo.Users["leaving@example.com"].ID
+
+	deletedAt := time.Now().UTC()
+	drawing := &models.Drawing{ID: primitive.NewObjectID(), UserID: userID, Title: "Mine", SceneData: `{"type":"excalidraw"}`}
+	trashed := &models.Drawing{ID: primitive.NewObjectID(), UserID: userID, Title: "Trashed", SceneData: "{}", DeletedAt: &deletedAt}
+	otherDrawing := &models.Drawing{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Title: "Someone else's", SceneData: "{}"}
+	for _, d := range []*models.Drawing{drawing, trashed, otherDrawing} {
+		mockDrawingRepo.Create(ctx, d)
+	}
+	mockCommentRepo.Create(ctx, &models.Comment{ID: primitive.NewObjectID(), DrawingID: drawing.ID, UserID: userID, Body: "Note to self"})
+	mockCommentRepo.Create(ctx, &models.Comment{ID: primitive.NewObjectID(), DrawingID: otherDrawing.ID, UserID: otherDrawing.UserID, Body: "Not mine"})
+	mockLibraryRepo.Create(ctx, &models.Library{ID: primitive.NewObjectID(), UserID: userID, Name: "Shapes", Items: []models.LibraryItem{{ID: "item", Status: "published"}}})
+	mockPersonalAccessTokenRepo.Create(ctx, &models.PersonalAccessToken{ID: primitive.NewObjectID(), UserID: userID, Name: "CI", TokenHash: "hash"})
+	mockInvitationRepo.Create(ctx, &models.Invitation{ID: primitive.NewObjectID(), CreatedBy: userID, CodeHash: "unused"})
+	otherInvitation := &models.Invitation{ID: primitive.NewObjectID(), CreatedBy: otherDrawing.UserID, CodeHash: "someone else's"}
+	mockInvitationRepo.Create(ctx, otherInvitation)
+
+	t.Run("Export", func(t *testing.T) {
+		w := do(http.MethodGet, "/users/me/export", "", token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+		archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		if err != nil {
+			t.Fatalf("export is not a zip archive: %v", err)
+		}
+		files := map[string]string{}
+		for _, f := range archive.File {
+			r, _ := f.Open()
+			data, _ := io.ReadAll(r)
+			files[f.Name] = string(data)
+		}
+
+		assert.Contains(t, files["profile.json"], "leaving@example.com")
+		assert.NotContains(t, files["profile.json"], "password\"")
+		assert.Contains(t, files["drawings.json"], "Trashed", "the trash is included")
+		assert.NotContains(t, files["drawings.json"], "Someone else's")
+		assert.Equal(t, drawing.SceneData, files["drawings/"+drawing.ID.Hex()+".excalidraw"])
+		assert.Contains(t, files["comments.json"], "Note to self")
+		assert.NotContains(t, files["comments.json"], "Not mine")
+		assert.Contains(t, files["sessions.json"], "userAgent")
+		assert.Contains(t, files["tokens.json"], "CI")
+		assert.NotContains(t, files["tokens.json"], "hash")
+		var libraries int
+		for name := range files {
+			if strings.HasPrefix(name, "libraries/") {
+				libraries++
+			}
+		}
+		assert.Equal(t, 1, libraries)
+	})
+
+	t.Run("Delete Requires Password", func(t *testing.T) {
+		w := do(http.MethodDelete, "/users/me", `{"password": "wrong-password"}`, token)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, mockUserRepo.Users, "leaving@example.com")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		w := do(http.MethodDelete, "/users/me", `{"password": "password123"}`, token)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		assert.NotContains(t, mockUserRepo.Users, "leaving@example.com")
+		assert.Len(t, mockDrawingRepo.Drawings, 1)
+		assert.Contains(t, mockDrawingRepo.Drawings, otherDrawing.ID)
+		assert.Len(t, mockCommentRepo.Comments, 1, "only comments on other users' drawings remain")
+		assert.Empty(t, mockLibraryRepo.Libraries)
+		assert.Empty(t, mockPersonalAccessTokenRepo.Tokens)
+		assert.Len(t, mockInvitationRepo.Invitations, 1, "invitations issued by the user are deleted")
+		assert.Contains(t, mockInvitationRepo.Invitations, otherInvitation.ID)
+		assert.Equal(t, "leaving@example.com", mailer.next(t).To)
+
+		w = do(http.MethodGet, "/users/me/export", "", token)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "sessions are revoked")
+		w = do(http.MethodPost, "/login", credentials, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestAccountHandler_SingleSignOnDelete(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	mockUserRepo := repository.NewMockUserRepository()
+	mockUserTokenRepo := repository.NewMockUserTokenRepository()
+	tokens := newTestTokenService()
+	mailer := newRecordingMailer()
+	accountHandler := NewAccountHandler(mockUserRepo, repository.NewMockDrawingRepository(), repository.NewMockCommentRepository(), repository.NewMockLibraryRepository(), repository.NewMockInvitationRepository(), repository.NewMockPersonalAccessTokenRepository(), mockUserTokenRepo, tokens, newTestPasswordHasher(), mailer, "https://draw.example.com")
+
+	router := gin.Default()
+	router.DELETE("/users/me", newTestAuthMiddleware(tokens), accountHandler.DeleteAccount)
+
+	newUser := func(email string) string {
+		user := &models.User{ID: primitive.NewObjectID(), Email: email, EmailVerified: true}
+		mockUserRepo.Create(ctx, user)
+		pair, err := tokens.IssueTokens(ctx, user.ID, auth.DeviceInfo{})
+		if err != nil {
+			t.Fatalf("could not issue tokens: %v", err)
+		}
+		return pair.AccessToken
+	}
+	do := func(body, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodDelete, "/users/me", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	token := newUser("sso@example.com")
+	otherToken := newUser("other-sso@example.com")
+
+	t.Run("The Email Is Not Enough", func(t *testing.T) {
+		w := do(`{"email": "sso@example.com", "token": "guessed"}`, token)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, mockUserRepo.Users, "sso@example.com")
+	})
+
+	t.Run("Emails A Confirmation Link", func(t *testing.T) {
+		w := do(`{}`, token)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Contains(t, mockUserRepo.Users, "sso@example.com", "nothing is deleted yet")
+
+		msg := mailer.next(t)
+		assert.Equal(t, "sso@example.com", msg.To)
+		assert.Contains(t, msg.Body, "https://draw.example.com/delete-account?token=")
+		rawToken := tokenFromEmail(t, msg)
+
+		w = do(`{"token": "`+rawToken+`"}`, otherToken)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "the link only works for its own account")
+		assert.Contains(t, mockUserRepo.Users, "other-sso@example.com")
+	})
+
+	t.Run("Delete With The Emailed Token", func(t *testing.T) {
+		do(`{}`, token)
+		rawToken := tokenFromEmail(t, mailer.next(t))
+
+		w := do(`{"token": "`+rawToken+`"}`, token)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.NotContains(t, mockUserRepo.Users, "sso@example.com")
+	})
+}
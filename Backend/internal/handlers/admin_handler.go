@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AdminHandler serves the administrative API. Every action is logged with
+// the acting administrator.
+type AdminHandler struct {
+	UserRepo      repository.UserRepository
+	DrawingRepo   repository.DrawingRepository
+	LibraryRepo   repository.LibraryRepository
+	Tokens        *auth.TokenService
+	PasswordReset *PasswordResetHandler
+}
+
+func NewAdminHandler(userRepo repository.UserRepository, drawingRepo repository.DrawingRepository, libraryRepo repository.LibraryRepository, tokens *auth.TokenService, passwordReset *PasswordResetHandler) *AdminHandler {
+	return &AdminHandler{
+		UserRepo:      userRepo,
+		DrawingRepo:   drawingRepo,
+		LibraryRepo:   libraryRepo,
+		Tokens:        tokens,
+		PasswordReset: passwordReset,
+	}
+}
+
+// AdminUser is a user as shown to administrators.
+type AdminUser struct {
+	ID               primitive.ObjectID    `json:"_id"`
+	Email            string                `json:"email"`
+	EmailVerified    bool                  `json:"emailVerified"`
+	DisplayName      string                `json:"displayName,omitempty"`
+	Role             string                `json:"role"`
+	DisabledAt       *time.Time            `json:"disabledAt,omitempty"`
+	HasPassword      bool                  `json:"hasPassword"`
+	TwoFactorEnabled bool                  `json:"twoFactorEnabled"`
+	Identities       []models.UserIdentity `json:"identities,omitempty"`
+}
+
+func newAdminUser(user *models.User) AdminUser {
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+	return AdminUser{
+		ID:               user.ID,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerified,
+		DisplayName:      user.DisplayName,
+		Role:             role,
+		DisabledAt:       user.DisabledAt,
+		HasPassword:      user.Password != "",
+		TwoFactorEnabled: user.TwoFactor != nil && user.TwoFactor.Enabled,
+		Identities:       user.Identities,
+	}
+}
+
+type ListUsersQuery struct {
+	Query  string `form:"q"`
+	Offset int64  `form:"offset" binding:"min=0"`
+	Limit  int64  `form:"limit" binding:"omitempty,min=1,max=200"`
+}
+
+type ListUsersResponse struct {
+	Users []AdminUser `json:"users"`
+	// Total is the number of users matching the query across all pages.
+	Total int64 `json:"total"`
+}
+
+// ListUsers returns a page of users, optionally filtered by a search on
+// email and display name.
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	var query ListUsersQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		BadRequest(c, err)
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = 50
+	}
+
+	users, total, err := h.UserRepo.Search(c.Request.Context(), query.Query, query.Offset, query.Limit)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	response := make([]AdminUser, 0, len(users))
+	for _, user := range users {
+		response = append(response, newAdminUser(user))
+	}
+	c.JSON(http.StatusOK, ListUsersResponse{Users: response, Total: total})
+}
+
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, newAdminUser(user))
+}
+
+// StorageUsage is how much a user stores, by kind.
+type StorageUsage struct {
+	Drawings        models.StorageUsage `json:"drawings"`
+	TrashedDrawings models.StorageUsage `json:"trashedDrawings"`
+	Libraries       models.StorageUsage `json:"libraries"`
+	AvatarBytes     int64               `json:"avatarBytes"`
+	TotalBytes      int64               `json:"totalBytes"`
+}
+
+func (h *AdminHandler) GetUserStorage(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	var usage StorageUsage
+	var err error
+	usage.Drawings, usage.TrashedDrawings, err = h.DrawingRepo.StorageUsageByUserID(c.Request.Context(), user.ID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	usage.Libraries, err = h.LibraryRepo.StorageUsageByUserID(c.Request.Context(), user.ID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if user.Avatar != nil {
+		usage.AvatarBytes = int64(len(user.Avatar.Data))
+	}
+	usage.TotalBytes = usage.Drawings.Bytes + usage.TrashedDrawings.Bytes + usage.Libraries.Bytes + usage.AvatarBytes
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// DisableUser blocks the user from signing in and signs them out everywhere.
+func (h *AdminHandler) DisableUser(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+	if user.ID.Hex() == c.GetString("userID") {
+		HandleError(c, http.StatusBadRequest, problem.InvalidRequest, "You cannot disable your own account")
+		return
+	}
+
+	if err := h.SetUserDisabled(c.Request.Context(), user, true); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	h.audit(c, "disabled user", user)
+	c.JSON(http.StatusOK, newAdminUser(user))
+}
+
+func (h *AdminHandler) EnableUser(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.SetUserDisabled(c.Request.Context(), user, false); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	h.audit(c, "enabled user", user)
+	c.JSON(http.StatusOK, newAdminUser(user))
+}
+
+// SetUserDisabled disables or re-enables a user. Disabling also signs them
+// out everywhere.
+func (h *AdminHandler) SetUserDisabled(ctx context.Context, user *models.User, disabled bool) error {
+	if !disabled {
+		if err := h.UserRepo.SetDisabled(ctx, user.ID, nil); err != nil {
+			return err
+		}
+		user.DisabledAt = nil
+		return nil
+	}
+
+	if user.DisabledAt == nil {
+		now := time.Now().UTC()
+		if err := h.UserRepo.SetDisabled(ctx, user.ID, &now); err != nil {
+			return err
+		}
+		user.DisabledAt = &now
+	}
+	return h.Tokens.RevokeAllSessions(ctx, user.ID)
+}
+
+// ForcePasswordReset removes the user's password, signs them out everywhere
+// and emails them a reset link. Sign-in through single sign-on keeps working.
+func (h *AdminHandler) ForcePasswordReset(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.UserRepo.UpdatePassword(c.Request.Context(), user.ID, ""); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if err := h.Tokens.RevokeAllSessions(c.Request.Context(), user.ID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if err := h.PasswordReset.SendReset(c.Request.Context(), user); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	h.audit(c, "forced a password reset for", user)
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset email sent"})
+}
+
+type TransferDrawingRequest struct {
+	UserID string `json:"userId" binding:"required"`
+}
+
+// TransferDrawing makes another user the owner of a drawing, including one
+// in the trash. Its comments move with it.
+func (h *AdminHandler) TransferDrawing(c *gin.Context) {
+	drawingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	var req TransferDrawingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+	toUserID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	toUser, err := h.UserRepo.FindByID(c.Request.Context(), toUserID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if toUser == nil {
+		NotFound(c, "User not found")
+		return
+	}
+
+	if err := h.DrawingRepo.TransferOwnership(c.Request.Context(), drawingID, toUserID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	log.Printf("Admin %s transferred drawing %s to user %s", c.GetString("userID"), drawingID.Hex(), toUserID.Hex())
+	c.JSON(http.StatusOK, gin.H{"message": "Drawing transferred"})
+}
+
+// findUser loads the user named by the :id parameter, writing an error
+// response and returning false if it cannot.
+func (h *AdminHandler) findUser(c *gin.Context) (*models.User, bool) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		BadRequest(c, err)
+		return nil, false
+	}
+
+	user, err := h.UserRepo.FindByID(c.Request.Context(), userID)
+	if err != nil {
+		RepositoryError(c, err)
+		return nil, false
+	}
+	if user == nil {
+		NotFound(c, "User not found")
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *AdminHandler) audit(c *gin.Context, action string, user *models.User) {
+	log.Printf("Admin %s %s %s (%s)", c.GetString("userID"), action, user.ID.Hex(), user.Email)
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/middleware"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAdminHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	mockUserRepo := repository.NewMockUserRepository()
+	mockDrawingRepo := repository.NewMockDrawingRepository()
+	mockLibraryRepo := repository.NewMockLibraryRepository()
+	mockUserTokenRepo := repository.NewMockUserTokenRepository()
+	tokens := newTestTokenService()
+	mailer := newRecordingMailer()
+	authHandler := NewAuthHandler(mockUserRepo, tokens, newTestPasswordHasher(), newTestPasswordPolicy(), nil, nil, nil)
+	passwordReset := NewPasswordResetHandler(mockUserRepo, mockUserTokenRepo, tokens, newTestPasswordHasher(), newTestPasswordPolicy(), mailer, "https://draw.example.com", time.Hour)
+	adminHandler := NewAdminHandler(mockUserRepo, mockDrawingRepo, mockLibraryRepo, tokens, passwordReset)
+
+	router := gin.Default()
+	router.POST("/register", authHandler.Register)
+	router.POST("/login", authHandler.Login)
+	admin := router.Group("/admin", newTestAuthMiddleware(tokens), middleware.RequireRole(mockUserRepo, models.RoleAdmin))
+	admin.GET("/users", adminHandler.ListUsers)
+	admin.GET("/users/:id", adminHandler.GetUser)
+	admin.GET("/users/:id/storage", adminHandler.GetUserStorage)
+	admin.POST("/users/:id/disable", adminHandler.DisableUser)
+	admin.POST("/users/:id/enable", adminHandler.EnableUser)
+	admin.POST("/users/:id/password-reset", adminHandler.ForcePasswordReset)
+	admin.POST("/drawings/:id/transfer", adminHandler.TransferDrawing)
+
+	do := func(method, path, body, token string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+	login := func(email string) (*httptest.ResponseRecorder, string) {
+		w, resp := do(http.MethodPost, "/login", `{"email": "`+email+`", "password": "password123"}`, "")
+		token, _ := resp["token"].(string)
+		return w, token
+	}
+
+	for _, email := range []string{"admin@example.com", "alice@example.com", "bob@example.com"} {
+		do(http.MethodPost, "/register", `{"email": "`+email+`", "password": "password123"}`, "")
+	}
+	adminUser := mockUserRepo.Users["admin@example.com"]
+	adminUser.Role = models.RoleAdmin
+	alice := mockUserRepo.Users["alice@example.com"]
+	bob := mockUserRepo.Users["bob@example.com"]
+	_, adminToken := login("admin@example.com")
+
+	t.Run("Requires Admin Role", func(t *testing.T) {
+		_, aliceToken := login("alice@example.com")
+		w, _ := do(http.MethodGet, "/admin/users", "", aliceToken)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = do(http.MethodGet, "/admin/users", "", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("List And Search Users", func(t *testing.T) {
+		w, resp := do(http.MethodGet, "/admin/users?limit=2", "", adminToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, float64(3), resp["total"])
+		assert.Len(t, resp["users"], 2)
+
+		w, resp = do(http.MethodGet, "/admin/users?q=ALI", "", adminToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, float64(1), resp["total"])
+		found := resp["users"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "alice@example.com", found["email"])
+		assert.Equal(t, "user", found["role"])
+		assert.NotContains(t, found, "password")
+
+		w, _ = do(http.MethodGet, "/admin/users?limit=1000", "", adminToken)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w, _ = do(http.MethodGet, "/admin/users/"+primitive.NewObjectID().Hex(), "", adminToken)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Disable And Enable", func(t *testing.T) {
+		_, aliceToken := login("alice@example.com")
+
+		w, _ := do(http.MethodPost, "/admin/users/"+adminUser.ID.Hex()+"/disable", "", adminToken)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "admins cannot lock themselves out")
+
+		w, resp := do(http.MethodPost, "/admin/users/"+alice.ID.Hex()+"/disable", "", adminToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotNil(t, resp["disabledAt"])
+
+		w, _ = do(http.MethodGet, "/admin/users", "", aliceToken)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "sessions are revoked")
+		w, _ = login("alice@example.com")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = do(http.MethodPost, "/login", `{"email": "alice@example.com", "password": "wrong-password"}`, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "a wrong password does not reveal the account state")
+
+		w, _ = do(http.MethodPost, "/admin/users/"+alice.ID.Hex()+"/enable", "", adminToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w, _ = login("alice@example.com")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Force Password Reset", func(t *testing.T) {
+		w, _ := do(http.MethodPost, "/admin/users/"+bob.ID.Hex()+"/password-reset", "", adminToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		msg := mailer.next(t)
+		assert.Equal(t, "bob@example.com", msg.To)
+		assert.NotEmpty(t, tokenFromEmail(t, msg))
+		w, _ = login("bob@example.com")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "the old password no longer works")
+	})
+
+	t.Run("Storage And Transfer", func(t *testing.T) {
+		deletedAt := time.Now()
+		drawing := &models.Drawing{ID: primitive.NewObjectID(), UserID: alice.ID, Title: "Plan", SceneData: "0123456789"}
+		mockDrawingRepo.Create(ctx, drawing)
+		mockDrawingRepo.Create(ctx, &models.Drawing{ID: primitive.NewObjectID(), UserID: alice.ID, SceneData: "01234", DeletedAt: &deletedAt})
+
+		w, resp := do(http.MethodGet, "/admin/users/"+alice.ID.Hex()+"/storage", "", adminToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, map[string]interface{}{"count": float64(1), "bytes": float64(10)}, resp["drawings"])
+		assert.Equal(t, map[string]interface{}{"count": float64(1), "bytes": float64(5)}, resp["trashedDrawings"])
+		assert.Equal(t, float64(15), resp["totalBytes"])
+
+		w, _ = do(http.MethodPost, "/admin/drawings/"+drawing.ID.Hex()+"/transfer", `{"userId": "`+primitive.NewObjectID().Hex()+`"}`, adminToken)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w, _ = do(http.MethodPost, "/admin/drawings/"+primitive.NewObjectID().Hex()+"/transfer", `{"userId": "`+bob.ID.Hex()+`"}`, adminToken)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w, _ = do(http.MethodPost, "/admin/drawings/"+drawing.ID.Hex()+"/transfer", `{"userId": "`+bob.ID.Hex()+`"}`, adminToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, bob.ID, mockDrawingRepo.Drawings[drawing.ID].UserID)
+	})
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/oidc"
+	"github.com/drshn/excalidraw/Backend/internal/openapi"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/drshn/excalidraw/Backend/internal/templates"
+)
+
+// MessageResponse is the body of responses that only confirm an action.
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// UploadAvatarForm is the multipart form UploadAvatar reads.
+type UploadAvatarForm struct {
+	Avatar openapi.Binary `json:"avatar" binding:"required"`
+}
+
+// APIRoutes documents every route of the API. The OpenAPI document is built
+// from it, and a test fails when a registered route is missing here.
+var APIRoutes = concat(
+	group("Meta", openapi.Public,
+		openapi.Route{Method: http.MethodGet, Path: "/.well-known/jwks.json", Summary: "Public keys that verify access tokens", Response: oidc.JSONWebKeySet{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/openapi.json", Summary: "This OpenAPI document", Response: map[string]any{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/docs", Summary: "API documentation page", Response: "", ResponseContentType: "text/html"},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/problems", Summary: "List error codes", Response: []problem.Entry{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/problems/:code", Summary: "Describe an error code", Response: problem.Entry{}},
+	),
+
+	group("Authentication", openapi.Public,
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/auth/registration", Summary: "Registration mode", Response: RegistrationResponse{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/auth/register", Summary: "Register",
+			Description: "Depending on the registration mode an invitation code is required, or registration is closed.",
+			Request:     RegisterRequest{}, Status: http.StatusCreated, Response: RegisterResponse{}, Errors: []int{http.StatusForbidden, http.StatusConflict}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/auth/login", Summary: "Log in",
+			Description: "Users with two-factor authentication get a challenge token to complete with POST /api/v1/auth/2fa/verify.",
+			Request:     LoginRequest{}, Response: openapi.OneOf{TokenResponse{}, TwoFactorChallengeResponse{}},
+			Errors: []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/auth/refresh", Summary: "Refresh an access token",
+			Description: "Rotates the refresh token. Presenting a refresh token twice revokes the session.",
+			Request:     RefreshRequest{}, Response: TokenResponse{}, Errors: []int{http.StatusUnauthorized}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/auth/password/forgot", Summary: "Request a password reset email",
+			Request: ForgotPasswordRequest{}, Response: MessageResponse{}, Errors: []int{http.StatusTooManyRequests}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/auth/password/reset", Summary: "Reset the password with an emailed token",
+			Request: ResetPasswordRequest{}, Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/auth/verify-email", Summary: "Verify an email address with an emailed token",
+			Request: VerifyEmailRequest{}, Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/auth/2fa/verify", Summary: "Complete a two-factor login",
+			Request: VerifyTwoFactorLoginRequest{}, Response: TokenResponse{}, Errors: []int{http.StatusUnauthorized, http.StatusTooManyRequests}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/auth/oidc/providers", Summary: "List single sign-on providers", Response: []OIDCProviderResponse{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/auth/oidc/:provider/login", Summary: "Start single sign-on",
+			Description: "Redirects to the identity provider.", Status: http.StatusFound, Errors: []int{http.StatusBadGateway},
+			Query: []openapi.Parameter{
+				openapi.QueryParam("invitation", "string", "Invitation code, redeemed if the login creates an account"),
+			}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/auth/oidc/:provider/callback", Summary: "Finish single sign-on",
+			Description: "Redirects to the app with the session tokens, or an error, in the URL fragment.",
+			Query: []openapi.Parameter{
+				openapi.QueryParam("state", "string", "State issued by the login redirect"),
+				openapi.QueryParam("code", "string", "Authorization code from the identity provider"),
+				openapi.QueryParam("error", "string", "Error reported by the identity provider"),
+			},
+			Status: http.StatusFound},
+	),
+
+	group("Authentication", openapi.Session,
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/auth/logout", Summary: "Log out of this session", Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/auth/logout-all", Summary: "Log out of every session", Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/auth/sessions", Summary: "List sessions", Response: []SessionResponse{}},
+		openapi.Route{Method: http.MethodDelete, Path: "/api/v1/auth/sessions/:id", Summary: "Revoke a session", Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/auth/verify-email/resend", Summary: "Resend the verification email",
+			Response: MessageResponse{}, Errors: []int{http.StatusConflict, http.StatusTooManyRequests}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/auth/2fa/setup", Summary: "Start two-factor setup",
+			Response: TwoFactorSetupResponse{}, Errors: []int{http.StatusConflict}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/auth/2fa/confirm", Summary: "Enable two-factor authentication",
+			Description: "Returns the recovery codes, which are shown only once.",
+			Request:     TwoFactorCodeRequest{}, Response: RecoveryCodesResponse{}, Errors: []int{http.StatusConflict}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/auth/2fa/disable", Summary: "Disable two-factor authentication",
+			Description: "Accounts without a password send the code alone.",
+			Request:     DisableTwoFactorRequest{}, Response: MessageResponse{}, Errors: []int{http.StatusTooManyRequests}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/auth/2fa/recovery-codes", Summary: "Regenerate recovery codes",
+			Request: TwoFactorCodeRequest{}, Response: RecoveryCodesResponse{}, Errors: []int{http.StatusTooManyRequests}},
+	),
+
+	scoped(group("Drawings", openapi.Scoped,
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/drawings", Summary: "Create a drawing",
+			Description: "With ?template= the drawing is created from a template and the body, which may only set the title, is optional.",
+			Query:       []openapi.Parameter{openapi.QueryParam("template", "string", "ID of a built-in or user template")},
+			Request:     CreateDrawingRequest{}, Status: http.StatusCreated, Response: models.Drawing{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/drawings", Summary: "List drawings",
+			Description: "Scene data is left out.", Response: []models.Drawing{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/drawings/trash", Summary: "List drawings in the trash", Response: []models.Drawing{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/drawings/:id", Summary: "Get a drawing", Response: models.Drawing{}},
+		openapi.Route{Method: http.MethodPut, Path: "/api/v1/drawings/:id", Summary: "Replace a drawing",
+			Description: "A folder or tags left out are removed.", Request: UpdateDrawingRequest{}, Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodPatch, Path: "/api/v1/drawings/:id", Summary: "Change some fields of a drawing",
+			Description: "A JSON Merge Patch: members left out are unchanged and null removes folder or tags. The response leaves out scene data.",
+			Request:     PatchDrawingRequest{}, RequestContentType: MergePatchContentType, Response: models.Drawing{},
+			Errors: []int{http.StatusUnsupportedMediaType}},
+		openapi.Route{Method: http.MethodDelete, Path: "/api/v1/drawings/:id", Summary: "Move a drawing to the trash",
+			Query:    []openapi.Parameter{openapi.QueryParam("permanent", "boolean", "Delete the drawing permanently instead")},
+			Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/drawings/:id/restore", Summary: "Restore a drawing from the trash", Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/drawings/:id/duplicate", Summary: "Duplicate a drawing",
+			Description: "The copy keeps the source's folder unless the request names another one.",
+			Request:     DuplicateDrawingRequest{}, OptionalRequest: true, Status: http.StatusCreated, Response: models.Drawing{}},
+		openapi.Route{Method: http.MethodPut, Path: "/api/v1/drawings/:id/template", Summary: "Mark a drawing as a template",
+			Request: SetTemplateRequest{}, Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodDelete, Path: "/api/v1/drawings/:id/template", Summary: "Stop using a drawing as a template", Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/drawings/:id/comments", Summary: "List comment threads",
+			Query:    []openapi.Parameter{openapi.QueryParam("resolved", "boolean", "Only resolved or only unresolved threads")},
+			Response: CommentListResponse{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/drawings/:id/comments", Summary: "Start a thread or reply",
+			Request: CreateCommentRequest{}, Status: http.StatusCreated, Response: models.Comment{}},
+		openapi.Route{Method: http.MethodPut, Path: "/api/v1/drawings/:id/comments/:commentId", Summary: "Edit a comment",
+			Request: UpdateCommentRequest{}, Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodDelete, Path: "/api/v1/drawings/:id/comments/:commentId", Summary: "Delete a comment",
+			Description: "Deleting a thread's root comment deletes its replies.", Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/drawings/:id/comments/:commentId/resolve", Summary: "Resolve a thread", Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/drawings/:id/comments/:commentId/reopen", Summary: "Reopen a thread", Response: MessageResponse{}},
+	)...),
+
+	scoped(group("Templates", openapi.Scoped,
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/templates", Summary: "List templates",
+			Description: "Built-in templates followed by the user's own.",
+			Query:       []openapi.Parameter{openapi.QueryParam("category", "string", "Only templates of this category")},
+			Response:    []templates.Template{}},
+	)...),
+
+	group("Libraries", openapi.Session,
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/libraries", Summary: "Create a library",
+			Request: CreateLibraryRequest{}, Status: http.StatusCreated, Response: models.Library{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/libraries", Summary: "List libraries", Response: []models.Library{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/libraries/:id", Summary: "Get a library", Response: models.Library{}},
+		openapi.Route{Method: http.MethodPut, Path: "/api/v1/libraries/:id", Summary: "Rename a library",
+			Request: UpdateLibraryRequest{}, Response: models.Library{}},
+		openapi.Route{Method: http.MethodDelete, Path: "/api/v1/libraries/:id", Summary: "Delete a library", Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/libraries/:id/items", Summary: "Add an item to a library",
+			Request: AddLibraryItemRequest{}, Status: http.StatusCreated, Response: models.LibraryItem{}, Errors: []int{http.StatusConflict}},
+		openapi.Route{Method: http.MethodDelete, Path: "/api/v1/libraries/:id/items/:itemId", Summary: "Remove an item from a library", Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/libraries/:id/import", Summary: "Import an .excalidrawlib file",
+			Description: "Items identical to ones already in the library are skipped.",
+			Request:     ExcalidrawLibFile{}, Response: ImportLibraryResponse{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/libraries/:id/export", Summary: "Export a library as an .excalidrawlib file", Response: ExcalidrawLibFile{}},
+	),
+
+	group("Personal access tokens", openapi.Session,
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/tokens", Summary: "Create a personal access token",
+			Description: "The response holds the token, which cannot be retrieved again.",
+			Request:     CreatePersonalAccessTokenRequest{}, Status: http.StatusCreated, Response: CreatePersonalAccessTokenResponse{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/tokens", Summary: "List personal access tokens", Response: []models.PersonalAccessToken{}},
+		openapi.Route{Method: http.MethodDelete, Path: "/api/v1/tokens/:id", Summary: "Revoke a personal access token", Response: MessageResponse{}},
+	),
+
+	group("Invitations", openapi.Session,
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/invitations", Summary: "Create an invitation",
+			Description: "The response holds the code, which cannot be retrieved again, and a registration link.",
+			Request:     CreateInvitationRequest{}, Status: http.StatusCreated, Response: CreateInvitationResponse{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/invitations", Summary: "List invitations", Response: []models.Invitation{}},
+		openapi.Route{Method: http.MethodDelete, Path: "/api/v1/invitations/:id", Summary: "Revoke an invitation", Response: MessageResponse{}},
+	),
+
+	group("Account", openapi.Session,
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/users/me", Summary: "Get the profile", Response: UserProfile{}},
+		openapi.Route{Method: http.MethodPatch, Path: "/api/v1/users/me", Summary: "Update the profile",
+			Description: "Only the fields present are changed.", Request: UpdateProfileRequest{}, Response: UserProfile{}},
+		openapi.Route{Method: http.MethodDelete, Path: "/api/v1/users/me", Summary: "Delete the account and everything it owns",
+			Description: "Confirm with the password. Accounts without one get a confirmation link by email (202) and confirm with its token.",
+			Request:     DeleteAccountRequest{}, Status: http.StatusNoContent},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/users/me/export", Summary: "Download everything stored about the user",
+			Response: openapi.Binary{}, ResponseContentType: "application/zip"},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/users/me/avatar", Summary: "Get the avatar",
+			Response: openapi.Binary{}, ResponseContentType: "image/*", Errors: []int{http.StatusNotFound}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/users/me/avatar", Summary: "Upload an avatar",
+			Request: UploadAvatarForm{}, RequestContentType: "multipart/form-data", Response: UserProfile{},
+			Errors: []int{http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType}},
+		openapi.Route{Method: http.MethodDelete, Path: "/api/v1/users/me/avatar", Summary: "Remove the avatar", Response: UserProfile{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/users/me/password", Summary: "Change the password",
+			Request: ChangePasswordRequest{}, Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/users/me/email", Summary: "Request an email address change",
+			Description: "Sends a confirmation link to the new address. Accounts without a password must have signed in within the last 10 minutes.",
+			Request:     ChangeEmailRequest{}, Status: http.StatusAccepted, Response: MessageResponse{}, Errors: []int{http.StatusForbidden, http.StatusConflict}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/users/me/email/confirm", Summary: "Confirm an email address change",
+			Request: ConfirmEmailChangeRequest{}, Response: UserProfile{}, Errors: []int{http.StatusConflict}},
+	),
+
+	group("Administration", openapi.Admin,
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/admin/users", Summary: "Search users",
+			Query: openapi.QueryParams(ListUsersQuery{}), Response: ListUsersResponse{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/admin/users/:id", Summary: "Get a user", Response: AdminUser{}},
+		openapi.Route{Method: http.MethodGet, Path: "/api/v1/admin/users/:id/storage", Summary: "Get a user's storage usage", Response: StorageUsage{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/admin/users/:id/disable", Summary: "Disable a user", Response: AdminUser{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/admin/users/:id/enable", Summary: "Enable a user", Response: AdminUser{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/admin/users/:id/password-reset", Summary: "Force a password reset", Response: MessageResponse{}},
+		openapi.Route{Method: http.MethodPost, Path: "/api/v1/admin/drawings/:id/transfer", Summary: "Give a drawing to another user",
+			Request: TransferDrawingRequest{}, Response: MessageResponse{}},
+	),
+)
+
+// group sets the tag and auth of routes that share them.
+func group(tag string, auth openapi.Auth, routes ...openapi.Route) []openapi.Route {
+	for i := range routes {
+		routes[i].Tag = tag
+		routes[i].Auth = auth
+	}
+	return routes
+}
+
+// scoped sets the personal access token scopes of drawing routes.
+func scoped(routes ...openapi.Route) []openapi.Route {
+	for i := range routes {
+		routes[i].ReadScope = models.ScopeDrawingsRead
+		routes[i].WriteScope = models.ScopeDrawingsWrite
+	}
+	return routes
+}
+
+func concat(groups ...[]openapi.Route) []openapi.Route {
+	var routes []openapi.Route
+	for _, g := range groups {
+		routes = append(routes, g...)
+	}
+	return routes
+}
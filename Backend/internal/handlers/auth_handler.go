@@ -1,32 +1,82 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/drshn/excalidraw/Backend/internal/auth"
 	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/drshn/excalidraw/Backend/internal/ratelimit"
 	"github.com/drshn/excalidraw/Backend/internal/repository"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"golang.org/x/crypto/bcrypt"
 )
 
+const accountDisabledMessage = "This account has been disabled"
+
 type AuthHandler struct {
 	UserRepo  repository.UserRepository
-	JWTSecret string
+	Tokens    *auth.TokenService
+	Passwords *auth.PasswordHasher
+	Policy    *auth.PasswordPolicy
+	Verifier  *EmailVerificationHandler
+	TwoFactor *TwoFactorHandler
+	// Registration limits who may register; nil means anyone.
+	Registration *Registration
+	// Failed logins are throttled per account (whether or not it exists)
+	// and per client IP.
+	AccountBackoff *ratelimit.Backoff
+	IPBackoff      *ratelimit.Backoff
 }
 
-func NewAuthHandler(userRepo repository.UserRepository, jwtSecret string) *AuthHandler {
+func NewAuthHandler(userRepo repository.UserRepository, tokens *auth.TokenService, passwords *auth.PasswordHasher, policy *auth.PasswordPolicy, verifier *EmailVerificationHandler, twoFactor *TwoFactorHandler, registration *Registration) *AuthHandler {
 	return &AuthHandler{
-		UserRepo:  userRepo,
-		JWTSecret: jwtSecret,
+		UserRepo:     userRepo,
+		Tokens:       tokens,
+		Passwords:    passwords,
+		Policy:       policy,
+		Verifier:     verifier,
+		TwoFactor:    twoFactor,
+		Registration: registration,
+		// 5 free attempts per account, then lockouts from 30 seconds
+		// doubling up to 15 minutes.
+		AccountBackoff: ratelimit.NewBackoff(5, 30*time.Second, 15*time.Minute, time.Hour),
+		// IPs get more room for shared NATs but also catch one client
+		// trying many accounts.
+		IPBackoff: ratelimit.NewBackoff(20, 30*time.Second, time.Hour, time.Hour),
 	}
 }
 
+// RegistrationResponse tells the registration form which mode is in effect.
+type RegistrationResponse struct {
+	Mode string `json:"mode"`
+}
+
 type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8"`
+	Password string `json:"password" binding:"required"`
+	// InvitationCode is required when registration is invite-only.
+	InvitationCode string `json:"invitationCode"`
+}
+
+type RegisterResponse struct {
+	Message string `json:"message"`
+	UserID  string `json:"userId"`
+}
+
+// GetRegistration tells the registration form whether to ask for an
+// invitation code.
+func (h *AuthHandler) GetRegistration(c *gin.Context) {
+	mode := RegistrationOpen
+	if h.Registration != nil {
+		mode = h.Registration.Mode
+	}
+	c.JSON(http.StatusOK, RegistrationResponse{Mode: mode})
 }
 
 func (h *AuthHandler) Register(c *gin.Context) {
@@ -36,34 +86,110 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	existingUser, err := h.UserRepo.FindByEmail(c.Request.Context(), req.Email)
+	user := &models.User{
+		ID:    primitive.NewObjectID(),
+		Email: req.Email,
+	}
+	invitation, err := h.Registration.admit(c.Request.Context(), req.Email, req.InvitationCode, user.ID)
 	if err != nil {
-		InternalServerError(c, err)
+		var refused registrationRefused
+		if errors.As(err, &refused) {
+			HandleError(c, http.StatusForbidden, refused.code(), refused.Error())
+			return
+		}
+		RepositoryError(c, err)
 		return
 	}
-	if existingUser != nil {
-		Conflict(c, "User with this email already exists")
+
+	if err := h.CreateUser(c.Request.Context(), user, req.Password); err != nil {
+		h.Registration.release(c.Request.Context(), invitation)
+		if errors.Is(err, ErrEmailTaken) {
+			HandleError(c, http.StatusConflict, problem.EmailTaken, "User with this email already exists")
+			return
+		}
+		if passwordRejected(c, "password", err) {
+			return
+		}
+		RepositoryError(c, err)
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	// The account exists at this point; if the email cannot be queued the
+	// user can ask for another one through the resend endpoint.
+	if h.Verifier != nil {
+		if err := h.Verifier.SendVerification(c.Request.Context(), user); err != nil {
+			log.Printf("Failed to send verification email to user %s: %v", user.ID.Hex(), err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, RegisterResponse{Message: "User created successfully", UserID: user.ID.Hex()})
+}
+
+// ErrEmailTaken is returned by CreateUser when the email is already in use.
+var ErrEmailTaken = errors.New("user with this email already exists")
+
+// CreateUser stores a new user with the given password. It returns
+// ErrEmailTaken, or a *auth.PasswordPolicyError if the password is not
+// allowed.
+func (h *AuthHandler) CreateUser(ctx context.Context, user *models.User, password string) error {
+	if err := h.Policy.Check(password, user.Email); err != nil {
+		return err
+	}
+
+	existingUser, err := h.UserRepo.FindByEmail(ctx, user.Email)
 	if err != nil {
-		InternalServerError(c, err)
-		return
+		return err
+	}
+	if existingUser != nil {
+		return ErrEmailTaken
 	}
 
-	user := &models.User{
-		ID:       primitive.NewObjectID(),
-		Email:    req.Email,
-		Password: string(hashedPassword),
+	hashedPassword, err := h.Passwords.Hash(password)
+	if err != nil {
+		return err
 	}
+	user.Password = hashedPassword
 
-	if err := h.UserRepo.Create(c.Request.Context(), user); err != nil {
-		InternalServerError(c, err)
-		return
+	return h.UserRepo.Create(ctx, user)
+}
+
+// confirmPassword checks the password given to confirm a sensitive action.
+// It responds with 403, or 503 if the server is too busy to check, and
+// returns false unless the password matches.
+func confirmPassword(c *gin.Context, passwords *auth.PasswordHasher, password, hash string) bool {
+	match, _, err := passwords.Verify(password, hash)
+	if err != nil {
+		RepositoryError(c, err)
+		return false
+	}
+	if !match {
+		HandleError(c, http.StatusForbidden, problem.IncorrectPassword, "Incorrect password")
+		return false
+	}
+	return true
+}
+
+// passwordRejected responds with 400 and returns true if err is about the
+// password in field rather than a server failure. Each broken rule is listed
+// as a field error.
+func passwordRejected(c *gin.Context, field string, err error) bool {
+	var problems []string
+	var policyErr *auth.PasswordPolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		problems = policyErr.Problems
+	case errors.Is(err, auth.ErrPasswordTooLong):
+		problems = []string{"must be at most 72 bytes long"}
+	default:
+		return false
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully", "userId": user.ID.Hex()})
+	fieldErrors := make([]problem.FieldError, len(problems))
+	for i, message := range problems {
+		fieldErrors[i] = problem.FieldError{Field: field, Rule: "policy", Message: message}
+	}
+	problem.Write(c, http.StatusBadRequest, problem.PasswordRejected, "Password does not meet the requirements", fieldErrors...)
+	return true
 }
 
 type LoginRequest struct {
@@ -71,6 +197,13 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// TwoFactorChallengeResponse answers a login with a correct password when
+// the user has 2FA enabled; see TwoFactorHandler.VerifyLogin.
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
+}
+
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -78,31 +211,212 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	accountKey := strings.ToLower(req.Email)
+	ipKey := c.ClientIP()
+	if retryAfter := max(h.AccountBackoff.Locked(accountKey), h.IPBackoff.Locked(ipKey)); retryAfter > 0 {
+		TooManyRequests(c, retryAfter)
+		return
+	}
+
 	user, err := h.UserRepo.FindByEmail(c.Request.Context(), req.Email)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	// Unknown accounts are checked against an empty hash, which takes as
+	// long as a wrong password.
+	var passwordHash string
+	if user != nil {
+		passwordHash = user.Password
+	}
+	match, needsRehash, err := h.Passwords.Verify(req.Password, passwordHash)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if !match {
+		h.AccountBackoff.Fail(accountKey)
+		h.IPBackoff.Fail(ipKey)
+		HandleError(c, http.StatusUnauthorized, problem.InvalidCredentials, "Invalid email or password")
+		return
+	}
+	h.AccountBackoff.Succeed(accountKey)
+	if needsRehash {
+		h.rehashPassword(c.Request.Context(), user, req.Password)
+	}
+	// Only reveal that an account is disabled to someone who knows its
+	// password.
+	if user.DisabledAt != nil {
+		HandleError(c, http.StatusForbidden, problem.AccountDisabled, accountDisabledMessage)
+		return
+	}
+
+	// With 2FA enabled the password only earns a challenge, which
+	// POST /auth/2fa/verify exchanges for tokens given a valid code.
+	if user.TwoFactor != nil && user.TwoFactor.Enabled && h.TwoFactor != nil {
+		challenge, err := h.TwoFactor.StartChallenge(c.Request.Context(), user)
+		if err != nil {
+			RepositoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, TwoFactorChallengeResponse{TwoFactorRequired: true, ChallengeToken: challenge})
+		return
+	}
+
+	device := auth.DeviceInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+	tokens, err := h.Tokens.IssueTokens(c.Request.Context(), user.ID, device)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	respondWithTokens(c, tokens)
+}
+
+// rehashPassword replaces a hash made with an older algorithm or weaker
+// parameters, now that the plaintext is at hand. Failing to is not worth
+// failing the login over.
+func (h *AuthHandler) rehashPassword(ctx context.Context, user *models.User, password string) {
+	hashedPassword, err := h.Passwords.Hash(password)
+	if err == nil {
+		err = h.UserRepo.UpdatePassword(ctx, user.ID, hashedPassword)
+	}
+	if err != nil {
+		log.Printf("Failed to rehash password of user %s: %v", user.ID.Hex(), err)
+	}
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	tokens, err := h.Tokens.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrRefreshTokenReuse):
+			HandleError(c, http.StatusUnauthorized, problem.RefreshTokenReused, "Refresh token was already used; all sessions from this login have been revoked")
+		case errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrRevokedToken):
+			HandleError(c, http.StatusUnauthorized, problem.InvalidRefreshToken, "Invalid or expired refresh token")
+		default:
+			RepositoryError(c, err)
+		}
+		return
+	}
+
+	respondWithTokens(c, tokens)
+}
+
+// Logout revokes the session the request's access token belongs to.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	sessionID, err := primitive.ObjectIDFromHex(c.GetString("sessionID"))
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, problem.InvalidRequest, "Token is not bound to a session")
+		return
+	}
+
+	if err := h.Tokens.RevokeSession(c.Request.Context(), sessionID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll revokes every session of the current user.
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
 	if err != nil {
 		InternalServerError(c, err)
 		return
 	}
-	if user == nil {
-		Unauthorized(c, "Invalid email or password")
+
+	if err := h.Tokens.RevokeAllSessions(c.Request.Context(), userID); err != nil {
+		RepositoryError(c, err)
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		Unauthorized(c, "Invalid email or password")
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions"})
+}
+
+type SessionResponse struct {
+	*models.Session
+	Current bool `json:"current"`
+}
+
+// GetSessions lists the devices the current user is signed in on.
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": user.ID.Hex(),
-		"exp": time.Now().Add(time.Hour * 24 * 7).Unix(), // 1 week
-	})
+	sessions, err := h.Tokens.ListSessions(c.Request.Context(), userID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
 
-	tokenString, err := token.SignedString([]byte(h.JWTSecret))
+	current := c.GetString("sessionID")
+	resp := make([]SessionResponse, len(sessions))
+	for i, session := range sessions {
+		resp[i] = SessionResponse{Session: session, Current: session.ID.Hex() == current}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteSession signs out one of the current user's devices.
+func (h *AuthHandler) DeleteSession(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
 	if err != nil {
 		InternalServerError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": tokenString})
+	sessionID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	session, err := h.Tokens.SessionRepo.FindByID(c.Request.Context(), sessionID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if session == nil || session.UserID != userID || session.RevokedAt != nil {
+		NotFound(c, "Session not found")
+		return
+	}
+
+	if err := h.Tokens.RevokeSession(c.Request.Context(), sessionID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// TokenResponse is a new session's access and refresh tokens.
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresAt    string `json:"expiresAt"`
+}
+
+func respondWithTokens(c *gin.Context, tokens *auth.TokenPair) {
+	c.JSON(http.StatusOK, TokenResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt.UTC().Format(time.RFC3339),
+	})
 }
@@ -2,23 +2,55 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/middleware"
 
 	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
 	"github.com/drshn/excalidraw/Backend/internal/repository"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
 )
 
+func newTestTokenService() *auth.TokenService {
+	return auth.NewTokenService(repository.NewMockRefreshTokenRepository(), repository.NewMockRevocationRepository(), repository.NewMockSessionRepository(), auth.NewHMACKeySet("test-secret"), 15*time.Minute, time.Hour)
+}
+
+// newTestPasswordHasher returns an Argon2id hasher with parameters cheap
+// enough for tests.
+func newTestPasswordHasher() *auth.PasswordHasher {
+	hasher, err := auth.NewPasswordHasher(auth.PasswordAlgorithmArgon2id, auth.Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}, 0)
+	if err != nil {
+		panic(err)
+	}
+	return hasher
+}
+
+func newTestPasswordPolicy() *auth.PasswordPolicy {
+	return &auth.PasswordPolicy{MinLength: 8, MaxLength: 256}
+}
+
+func newTestAuthMiddleware(tokens *auth.TokenService) gin.HandlerFunc {
+	return middleware.AuthMiddleware(tokens, auth.NewSessionTracker(tokens.SessionRepo, time.Minute), auth.NewPersonalAccessTokenService(repository.NewMockPersonalAccessTokenRepository(), repository.NewMockUserRepository()))
+}
+
 func TestAuthHandler_Register(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	t.Run("Successful Registration", func(t *testing.T) {
 		mockUserRepo := repository.NewMockUserRepository()
-		authHandler := NewAuthHandler(mockUserRepo, "test-secret")
+		authHandler := NewAuthHandler(mockUserRepo, newTestTokenService(), newTestPasswordHasher(), newTestPasswordPolicy(), nil, nil, nil)
 
 		r := gin.Default()
 		r.POST("/register", authHandler.Register)
@@ -37,11 +69,34 @@ func TestAuthHandler_Register(t *testing.T) {
 		assert.Contains(t, response, "userId")
 	})
 
+	t.Run("Password Rejected By Policy", func(t *testing.T) {
+		authHandler := NewAuthHandler(repository.NewMockUserRepository(), newTestTokenService(), newTestPasswordHasher(), newTestPasswordPolicy(), nil, nil, nil)
+
+		r := gin.Default()
+		r.POST("/register", authHandler.Register)
+
+		req, _ := http.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(`{"email": "short@example.com", "password": "short"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+		var response problem.Problem
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, problem.PasswordRejected, response.Code)
+		assert.Equal(t, "Password does not meet the requirements", response.Detail)
+		assert.Equal(t, []problem.FieldError{
+			{Field: "password", Rule: "policy", Message: "must be at least 8 characters"},
+			{Field: "password", Rule: "policy", Message: "must not contain your email address"},
+		}, response.Errors)
+	})
+
 	t.Run("Email Already Exists", func(t *testing.T) {
 		mockUserRepo := repository.NewMockUserRepository()
 		// Pre-populate the mock repo
 		mockUserRepo.Create(nil, &models.User{Email: "test@example.com"})
-		authHandler := NewAuthHandler(mockUserRepo, "test-secret")
+		authHandler := NewAuthHandler(mockUserRepo, newTestTokenService(), newTestPasswordHasher(), newTestPasswordPolicy(), nil, nil, nil)
 
 		r := gin.Default()
 		r.POST("/register", authHandler.Register)
@@ -64,7 +119,7 @@ func TestAuthHandler_Login(t *testing.T) {
 	mockUserRepo := repository.NewMockUserRepository()
 	// Note: In a real scenario, you'd hash the password properly before storing.
 	// For this test, we'll handle the logic inside the handler.
-	authHandler := NewAuthHandler(mockUserRepo, "test-secret")
+	authHandler := NewAuthHandler(mockUserRepo, newTestTokenService(), newTestPasswordHasher(), newTestPasswordPolicy(), nil, nil, nil)
 	// Manually register a user to test login
 	regPayload := `{"email": "login@example.com", "password": "password123"}`
 	regReq, _ := http.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(regPayload))
@@ -72,12 +127,11 @@ func TestAuthHandler_Login(t *testing.T) {
 	regW := httptest.NewRecorder()
 	router := gin.Default()
 	router.POST("/register", authHandler.Register)
+	router.POST("/login", authHandler.Login)
 	router.ServeHTTP(regW, regReq)
 	assert.Equal(t, http.StatusCreated, regW.Code)
 
 	t.Run("Successful Login", func(t *testing.T) {
-		router.POST("/login", authHandler.Login)
-
 		loginPayload := `{"email": "login@example.com", "password": "password123"}`
 		loginReq, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(loginPayload))
 		loginReq.Header.Set("Content-Type", "application/json")
@@ -89,11 +143,10 @@ func TestAuthHandler_Login(t *testing.T) {
 		var response map[string]interface{}
 		json.Unmarshal(w.Body.Bytes(), &response)
 		assert.Contains(t, response, "token")
+		assert.Contains(t, response, "refreshToken")
 	})
 
 	t.Run("Invalid Credentials", func(t *testing.T) {
-		router.POST("/login", authHandler.Login)
-
 		loginPayload := `{"email": "login@example.com", "password": "wrongpassword"}`
 		loginReq, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(loginPayload))
 		loginReq.Header.Set("Content-Type", "application/json")
@@ -103,4 +156,259 @@ func TestAuthHandler_Login(t *testing.T) {
 
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
+
+	t.Run("Legacy Hash Is Upgraded", func(t *testing.T) {
+		legacy, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+		mockUserRepo.Create(context.Background(), &models.User{ID: primitive.NewObjectID(), Email: "legacy@example.com", Password: string(legacy)})
+
+		loginReq, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(`{"email": "legacy@example.com", "password": "password123"}`))
+		loginReq.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, loginReq)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		user := mockUserRepo.Users["legacy@example.com"]
+		assert.True(t, strings.HasPrefix(user.Password, "$argon2id$"), "bcrypt hash is replaced on login")
+		match, needsRehash, _ := authHandler.Passwords.Verify("password123", user.Password)
+		assert.True(t, match)
+		assert.False(t, needsRehash)
+	})
+}
+
+func TestAuthHandler_LoginBackoff(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authHandler := NewAuthHandler(repository.NewMockUserRepository(), newTestTokenService(), newTestPasswordHasher(), newTestPasswordPolicy(), nil, nil, nil)
+	router := gin.Default()
+	router.POST("/register", authHandler.Register)
+	router.POST("/login", authHandler.Login)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	post("/register", `{"email": "locked@example.com", "password": "password123"}`)
+
+	t.Run("Account Lockout", func(t *testing.T) {
+		for i := 0; i < 6; i++ {
+			w := post("/login", `{"email": "locked@example.com", "password": "wrongpassword"}`)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+
+		w := post("/login", `{"email": "LOCKED@example.com", "password": "password123"}`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, "even the right password is refused while locked")
+		assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("Unknown Emails Behave The Same", func(t *testing.T) {
+		for i := 0; i < 6; i++ {
+			w := post("/login", `{"email": "ghost@example.com", "password": "wrongpassword"}`)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+		w := post("/login", `{"email": "ghost@example.com", "password": "wrongpassword"}`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+
+	t.Run("Per-IP Lockout", func(t *testing.T) {
+		authHandler.AccountBackoff.Succeed("locked@example.com")
+		for i := 0; i < 20; i++ {
+			post("/login", fmt.Sprintf(`{"email": "spray%d@example.com", "password": "wrongpassword"}`, i))
+		}
+		w := post("/login", `{"email": "locked@example.com", "password": "password123"}`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+}
+
+func TestAuthHandler_RefreshAndLogout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tokens := newTestTokenService()
+	authHandler := NewAuthHandler(repository.NewMockUserRepository(), tokens, newTestPasswordHasher(), newTestPasswordPolicy(), nil, nil, nil)
+
+	router := gin.Default()
+	router.POST("/register", authHandler.Register)
+	router.POST("/login", authHandler.Login)
+	router.POST("/refresh", authHandler.Refresh)
+	router.POST("/logout", newTestAuthMiddleware(tokens), authHandler.Logout)
+	router.POST("/logout-all", newTestAuthMiddleware(tokens), authHandler.LogoutAll)
+	router.GET("/protected", newTestAuthMiddleware(tokens), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	post := func(path, body, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	protected := func(token string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	decode := func(w *httptest.ResponseRecorder) map[string]string {
+		var response map[string]string
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+	login := func() map[string]string {
+		w := post("/login", `{"email": "refresh@example.com", "password": "password123"}`, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		return decode(w)
+	}
+
+	post("/register", `{"email": "refresh@example.com", "password": "password123"}`, "")
+
+	t.Run("Refresh Rotates Tokens", func(t *testing.T) {
+		session := login()
+		w := post("/refresh", `{"refreshToken": "`+session["refreshToken"]+`"}`, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		rotated := decode(w)
+		assert.NotEqual(t, session["refreshToken"], rotated["refreshToken"])
+		assert.Equal(t, http.StatusOK, protected(rotated["token"]))
+	})
+
+	t.Run("Reuse Revokes Family", func(t *testing.T) {
+		session := login()
+		w := post("/refresh", `{"refreshToken": "`+session["refreshToken"]+`"}`, "")
+		rotated := decode(w)
+
+		w = post("/refresh", `{"refreshToken": "`+session["refreshToken"]+`"}`, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = post("/refresh", `{"refreshToken": "`+rotated["refreshToken"]+`"}`, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, http.StatusUnauthorized, protected(rotated["token"]))
+	})
+
+	t.Run("Invalid Refresh Token", func(t *testing.T) {
+		w := post("/refresh", `{"refreshToken": "not-a-token"}`, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		session := login()
+		other := login()
+
+		w := post("/logout", "", session["token"])
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusUnauthorized, protected(session["token"]))
+		w = post("/refresh", `{"refreshToken": "`+session["refreshToken"]+`"}`, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		assert.Equal(t, http.StatusOK, protected(other["token"]))
+	})
+
+	t.Run("Logout All", func(t *testing.T) {
+		first := login()
+		second := login()
+
+		w := post("/logout-all", "", first["token"])
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusUnauthorized, protected(first["token"]))
+		assert.Equal(t, http.StatusUnauthorized, protected(second["token"]))
+		w = post("/refresh", `{"refreshToken": "`+second["refreshToken"]+`"}`, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestAuthHandler_Sessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tokens := newTestTokenService()
+	tracker := auth.NewSessionTracker(tokens.SessionRepo, time.Minute)
+	authMiddleware := middleware.AuthMiddleware(tokens, tracker, auth.NewPersonalAccessTokenService(repository.NewMockPersonalAccessTokenRepository(), repository.NewMockUserRepository()))
+	authHandler := NewAuthHandler(repository.NewMockUserRepository(), tokens, newTestPasswordHasher(), newTestPasswordPolicy(), nil, nil, nil)
+
+	router := gin.Default()
+	router.POST("/register", authHandler.Register)
+	router.POST("/login", authHandler.Login)
+	router.GET("/sessions", authMiddleware, authHandler.GetSessions)
+	router.DELETE("/sessions/:id", authMiddleware, authHandler.DeleteSession)
+
+	serve := func(method, path, body, token, userAgent string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	login := func(email, userAgent string) string {
+		w := serve(http.MethodPost, "/login", `{"email": "`+email+`", "password": "password123"}`, "", userAgent)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response map[string]string
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response["token"]
+	}
+	listSessions := func(token string) []SessionResponse {
+		w := serve(http.MethodGet, "/sessions", "", token, "laptop")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var sessions []SessionResponse
+		json.Unmarshal(w.Body.Bytes(), &sessions)
+		return sessions
+	}
+
+	serve(http.MethodPost, "/register", `{"email": "sessions@example.com", "password": "password123"}`, "", "laptop")
+	serve(http.MethodPost, "/register", `{"email": "other@example.com", "password": "password123"}`, "", "laptop")
+	laptop := login("sessions@example.com", "laptop")
+	phone := login("sessions@example.com", "phone")
+	otherUser := login("other@example.com", "laptop")
+
+	t.Run("List Sessions", func(t *testing.T) {
+		sessions := listSessions(laptop)
+		assert.Len(t, sessions, 2)
+		for _, s := range sessions {
+			assert.Equal(t, s.UserAgent == "laptop", s.Current)
+		}
+	})
+
+	t.Run("Last Seen Is Batched", func(t *testing.T) {
+		sessions := listSessions(phone)
+		var phoneSession *SessionResponse
+		for i := range sessions {
+			if sessions[i].Current {
+				phoneSession = &sessions[i]
+			}
+		}
+		before := phoneSession.LastSeenAt
+
+		time.Sleep(5 * time.Millisecond)
+		listSessions(phone)
+		stored, _ := tokens.SessionRepo.FindByID(nil, phoneSession.ID)
+		assert.Equal(t, before, stored.LastSeenAt, "last-seen is buffered until flushed")
+
+		tracker.Flush(context.Background())
+		stored, _ = tokens.SessionRepo.FindByID(nil, phoneSession.ID)
+		assert.True(t, stored.LastSeenAt.After(before))
+	})
+
+	t.Run("Revoke Another Device", func(t *testing.T) {
+		var phoneID string
+		for _, s := range listSessions(laptop) {
+			if s.UserAgent == "phone" {
+				phoneID = s.ID.Hex()
+			}
+		}
+
+		w := serve(http.MethodDelete, "/sessions/"+phoneID, "", otherUser, "laptop")
+		assert.Equal(t, http.StatusNotFound, w.Code, "sessions of other users cannot be revoked")
+
+		w = serve(http.MethodDelete, "/sessions/"+phoneID, "", laptop, "laptop")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = serve(http.MethodGet, "/sessions", "", phone, "phone")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Len(t, listSessions(laptop), 1)
+	})
 }
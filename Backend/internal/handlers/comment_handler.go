@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CommentHandler struct {
+	CommentRepo repository.CommentRepository
+	DrawingRepo repository.DrawingRepository
+}
+
+func NewCommentHandler(commentRepo repository.CommentRepository, drawingRepo repository.DrawingRepository) *CommentHandler {
+	return &CommentHandler{
+		CommentRepo: commentRepo,
+		DrawingRepo: drawingRepo,
+	}
+}
+
+// CommentThread is a root comment with its replies, oldest first.
+type CommentThread struct {
+	*models.Comment
+	Replies []*models.Comment `json:"replies"`
+}
+
+type CommentListResponse struct {
+	Threads         []CommentThread `json:"threads"`
+	UnresolvedCount int             `json:"unresolvedCount"`
+}
+
+// GetComments lists the threads on a drawing. ?resolved=true or false limits
+// the result to resolved or unresolved threads.
+func (h *CommentHandler) GetComments(c *gin.Context) {
+	_, drawingID, ok := h.authorizeDrawing(c)
+	if !ok {
+		return
+	}
+
+	comments, err := h.CommentRepo.FindByDrawingID(c.Request.Context(), drawingID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	resp := CommentListResponse{Threads: []CommentThread{}}
+	index := make(map[primitive.ObjectID]int)
+	for _, comment := range comments {
+		if comment.ParentID == nil {
+			index[comment.ID] = len(resp.Threads)
+			resp.Threads = append(resp.Threads, CommentThread{Comment: comment, Replies: []*models.Comment{}})
+			if !comment.Resolved {
+				resp.UnresolvedCount++
+			}
+		}
+	}
+	for _, comment := range comments {
+		if comment.ParentID != nil {
+			if i, found := index[*comment.ParentID]; found {
+				resp.Threads[i].Replies = append(resp.Threads[i].Replies, comment)
+			}
+		}
+	}
+
+	if filter := c.Query("resolved"); filter == "true" || filter == "false" {
+		filtered := []CommentThread{}
+		for _, thread := range resp.Threads {
+			if thread.Resolved == (filter == "true") {
+				filtered = append(filtered, thread)
+			}
+		}
+		resp.Threads = filtered
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+type CreateCommentRequest struct {
+	Body     string                `json:"body" binding:"required,max=10000"`
+	Anchor   *models.CommentAnchor `json:"anchor"`
+	ParentID string                `json:"parentId"`
+}
+
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	userID, drawingID, ok := h.authorizeDrawing(c)
+	if !ok {
+		return
+	}
+
+	var req CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	comment := &models.Comment{
+		ID:        primitive.NewObjectID(),
+		DrawingID: drawingID,
+		UserID:    userID,
+		Body:      req.Body,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if req.ParentID != "" {
+		parentID, err := primitive.ObjectIDFromHex(req.ParentID)
+		if err != nil {
+			BadRequest(c, err)
+			return
+		}
+		parent, err := h.CommentRepo.FindByID(c.Request.Context(), parentID)
+		if err != nil {
+			RepositoryError(c, err)
+			return
+		}
+		if parent == nil || parent.DrawingID != drawingID {
+			NotFound(c, "Parent comment not found")
+			return
+		}
+		// Replies always attach to the thread's root comment.
+		if parent.ParentID != nil {
+			parentID = *parent.ParentID
+		}
+		comment.ParentID = &parentID
+	} else if req.Anchor != nil {
+		if req.Anchor.ElementID == "" && (req.Anchor.X == nil || req.Anchor.Y == nil) {
+			HandleError(c, http.StatusBadRequest, problem.InvalidRequest, "Anchor requires an elementId or both x and y")
+			return
+		}
+		comment.Anchor = req.Anchor
+	}
+
+	if err := h.CommentRepo.Create(c.Request.Context(), comment); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body" binding:"required,max=10000"`
+}
+
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	var req UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	comment, ok := h.findOwnComment(c)
+	if !ok {
+		return
+	}
+
+	if err := h.CommentRepo.UpdateBody(c.Request.Context(), comment.ID, req.Body); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment updated successfully"})
+}
+
+// DeleteComment deletes a comment; deleting a thread's root comment deletes
+// its replies as well.
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	comment, ok := h.findOwnComment(c)
+	if !ok {
+		return
+	}
+
+	if err := h.CommentRepo.Delete(c.Request.Context(), comment.ID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+func (h *CommentHandler) ResolveComment(c *gin.Context) {
+	h.setResolved(c, true)
+}
+
+func (h *CommentHandler) ReopenComment(c *gin.Context) {
+	h.setResolved(c, false)
+}
+
+func (h *CommentHandler) setResolved(c *gin.Context, resolved bool) {
+	userID, comment, ok := h.findComment(c)
+	if !ok {
+		return
+	}
+	if comment.ParentID != nil {
+		HandleError(c, http.StatusBadRequest, problem.InvalidRequest, "Only a thread's root comment can be resolved or reopened")
+		return
+	}
+
+	if err := h.CommentRepo.SetResolved(c.Request.Context(), comment.ID, resolved, userID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	if resolved {
+		c.JSON(http.StatusOK, gin.H{"message": "Thread resolved"})
+	} else {
+		c.JSON(http.StatusOK, gin.H{"message": "Thread reopened"})
+	}
+}
+
+// authorizeDrawing checks that the caller can access the drawing named by the
+// :id parameter, writing an error response and returning false if not.
+func (h *CommentHandler) authorizeDrawing(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	drawingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		BadRequest(c, err)
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	drawing, err := h.DrawingRepo.FindByIDAndUserID(c.Request.Context(), drawingID, userID)
+	if err != nil {
+		RepositoryError(c, err)
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	if drawing == nil {
+		NotFound(c, "Drawing not found")
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	return userID, drawingID, true
+}
+
+// findComment loads the comment named by the :commentId parameter on an
+// accessible drawing.
+func (h *CommentHandler) findComment(c *gin.Context) (primitive.ObjectID, *models.Comment, bool) {
+	userID, drawingID, ok := h.authorizeDrawing(c)
+	if !ok {
+		return primitive.NilObjectID, nil, false
+	}
+
+	commentID, err := primitive.ObjectIDFromHex(c.Param("commentId"))
+	if err != nil {
+		BadRequest(c, err)
+		return primitive.NilObjectID, nil, false
+	}
+
+	comment, err := h.CommentRepo.FindByID(c.Request.Context(), commentID)
+	if err != nil {
+		RepositoryError(c, err)
+		return primitive.NilObjectID, nil, false
+	}
+	if comment == nil || comment.DrawingID != drawingID {
+		NotFound(c, "Comment not found")
+		return primitive.NilObjectID, nil, false
+	}
+	return userID, comment, true
+}
+
+// findOwnComment is findComment restricted to comments written by the caller.
+func (h *CommentHandler) findOwnComment(c *gin.Context) (*models.Comment, bool) {
+	userID, comment, ok := h.findComment(c)
+	if !ok {
+		return nil, false
+	}
+	if err := checkCommentAuthor(comment, userID); err != nil {
+		RepositoryError(c, err)
+		return nil, false
+	}
+	return comment, true
+}
+
+// checkCommentAuthor returns a *repository.ForbiddenError unless userID wrote
+// comment.
+func checkCommentAuthor(comment *models.Comment, userID primitive.ObjectID) error {
+	if comment.UserID != userID {
+		return &repository.ForbiddenError{Resource: "comment", Reason: "only the author can change this comment"}
+	}
+	return nil
+}
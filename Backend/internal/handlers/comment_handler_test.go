@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCommentHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID := primitive.NewObjectID()
+	mockDrawingRepo := repository.NewMockDrawingRepository()
+	mockCommentRepo := repository.NewMockCommentRepository()
+	drawing := &models.Drawing{ID: primitive.NewObjectID(), UserID: userID, Title: "Architecture", SceneData: "{}"}
+	mockDrawingRepo.Create(nil, drawing)
+
+	commentHandler := NewCommentHandler(mockCommentRepo, mockDrawingRepo)
+	drawingHandler := NewDrawingHandler(mockDrawingRepo, mockCommentRepo)
+
+	currentUser := userID
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", currentUser.Hex())
+		c.Next()
+	})
+	r.GET("/drawings", drawingHandler.GetDrawings)
+	r.GET("/drawings/:id/comments", commentHandler.GetComments)
+	r.POST("/drawings/:id/comments", commentHandler.CreateComment)
+	r.PUT("/drawings/:id/comments/:commentId", commentHandler.UpdateComment)
+	r.DELETE("/drawings/:id/comments/:commentId", commentHandler.DeleteComment)
+	r.POST("/drawings/:id/comments/:commentId/resolve", commentHandler.ResolveComment)
+	r.POST("/drawings/:id/comments/:commentId/reopen", commentHandler.ReopenComment)
+
+	base := "/drawings/" + drawing.ID.Hex() + "/comments"
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	create := func(body string) models.Comment {
+		w := serve(http.MethodPost, base, body)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var comment models.Comment
+		json.Unmarshal(w.Body.Bytes(), &comment)
+		return comment
+	}
+	list := func(query string) CommentListResponse {
+		w := serve(http.MethodGet, base+query, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp CommentListResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	root := create(`{"body": "Is this the right service?", "anchor": {"elementId": "rect-1"}}`)
+	reply := create(`{"body": "Yes", "parentId": "` + root.ID.Hex() + `"}`)
+	nested := create(`{"body": "Thanks", "parentId": "` + reply.ID.Hex() + `"}`)
+	other := create(`{"body": "Move this box", "anchor": {"x": 10, "y": 20}}`)
+
+	t.Run("Threads And Unresolved Counts", func(t *testing.T) {
+		assert.Equal(t, root.ID, *nested.ParentID, "replies attach to the root comment")
+
+		resp := list("")
+		assert.Len(t, resp.Threads, 2)
+		assert.Equal(t, 2, resp.UnresolvedCount)
+		assert.Len(t, resp.Threads[0].Replies, 2)
+		assert.Equal(t, "rect-1", resp.Threads[0].Anchor.ElementID)
+
+		w := serve(http.MethodGet, "/drawings", "")
+		assert.Contains(t, w.Body.String(), `"unresolvedComments":2`)
+	})
+
+	t.Run("Invalid Anchor", func(t *testing.T) {
+		w := serve(http.MethodPost, base, `{"body": "Here", "anchor": {"x": 10}}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Resolve And Reopen", func(t *testing.T) {
+		w := serve(http.MethodPost, base+"/"+root.ID.Hex()+"/resolve", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 1, list("").UnresolvedCount)
+		assert.Len(t, list("?resolved=true").Threads, 1)
+		assert.Len(t, list("?resolved=false").Threads, 1)
+
+		w = serve(http.MethodPost, base+"/"+reply.ID.Hex()+"/resolve", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = serve(http.MethodPost, base+"/"+root.ID.Hex()+"/reopen", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 2, list("").UnresolvedCount)
+	})
+
+	t.Run("Only Author Can Edit", func(t *testing.T) {
+		w := serve(http.MethodPut, base+"/"+other.ID.Hex(), `{"body": "Move this box left"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Move this box left", mockCommentRepo.Comments[other.ID].Body)
+
+		mockCommentRepo.Comments[other.ID].UserID = primitive.NewObjectID()
+		w = serve(http.MethodPut, base+"/"+other.ID.Hex(), `{"body": "Hijacked"}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"forbidden"`)
+		assert.Contains(t, w.Body.String(), "Only the author can change this comment")
+		w = serve(http.MethodDelete, base+"/"+other.ID.Hex(), "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Delete Thread Removes Replies", func(t *testing.T) {
+		w := serve(http.MethodDelete, base+"/"+root.ID.Hex(), "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, mockCommentRepo.Comments, reply.ID)
+		assert.NotContains(t, mockCommentRepo.Comments, nested.ID)
+	})
+
+	t.Run("No Access To Drawing", func(t *testing.T) {
+		currentUser = primitive.NewObjectID()
+		defer func() { currentUser = userID }()
+
+		w := serve(http.MethodGet, base, "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = serve(http.MethodPost, base, `{"body": "Hi"}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -1,28 +1,47 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
 
 	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
 	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/drshn/excalidraw/Backend/internal/templates"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type DrawingHandler struct {
 	DrawingRepo repository.DrawingRepository
+	CommentRepo repository.CommentRepository
 }
 
-func NewDrawingHandler(drawingRepo repository.DrawingRepository) *DrawingHandler {
-	return &DrawingHandler{DrawingRepo: drawingRepo}
+func NewDrawingHandler(drawingRepo repository.DrawingRepository, commentRepo repository.CommentRepository) *DrawingHandler {
+	return &DrawingHandler{
+		DrawingRepo: drawingRepo,
+		CommentRepo: commentRepo,
+	}
 }
 
 type CreateDrawingRequest struct {
-	Title     string `json:"title" binding:"required"`
-	SceneData string `json:"sceneData" binding:"required"`
+	Title     string   `json:"title" binding:"required"`
+	SceneData string   `json:"sceneData" binding:"required"`
+	Folder    string   `json:"folder" binding:"max=200"`
+	Tags      []string `json:"tags" binding:"max=20,dive,required,max=50"`
 }
 
 func (h *DrawingHandler) CreateDrawing(c *gin.Context) {
+	if templateID := c.Query("template"); templateID != "" {
+		h.createDrawingFromTemplate(c, templateID)
+		return
+	}
+
 	var req CreateDrawingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		BadRequest(c, err)
@@ -40,13 +59,75 @@ func (h *DrawingHandler) CreateDrawing(c *gin.Context) {
 		UserID:    userID,
 		Title:     req.Title,
 		SceneData: req.SceneData,
+		Folder:    req.Folder,
+		Tags:      req.Tags,
 	}
 
 	if err := h.DrawingRepo.Create(c.Request.Context(), drawing); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, drawing)
+}
+
+type CreateFromTemplateRequest struct {
+	Title string `json:"title"`
+}
+
+// createDrawingFromTemplate creates a drawing from a built-in template or one
+// of the user's own templates. The request body and title are optional.
+func (h *DrawingHandler) createDrawingFromTemplate(c *gin.Context, templateID string) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
 		InternalServerError(c, err)
 		return
 	}
 
+	var req CreateFromTemplateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			BadRequest(c, err)
+			return
+		}
+	}
+
+	template, found := templates.FindBuiltIn(templateID)
+	if !found {
+		if id, err := primitive.ObjectIDFromHex(templateID); err == nil {
+			source, err := h.DrawingRepo.FindByIDAndUserID(c.Request.Context(), id, userID)
+			if err != nil {
+				RepositoryError(c, err)
+				return
+			}
+			if source != nil && source.Template != nil {
+				template = &templates.Template{ID: templateID, Name: source.Title, SceneData: source.SceneData}
+				found = true
+			}
+		}
+	}
+	if !found {
+		NotFound(c, "Template not found")
+		return
+	}
+
+	title := req.Title
+	if title == "" {
+		title = template.Name
+	}
+
+	drawing := &models.Drawing{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Title:     title,
+		SceneData: template.SceneData,
+	}
+
+	if err := h.DrawingRepo.Create(c.Request.Context(), drawing); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusCreated, drawing)
 }
 
@@ -59,9 +140,22 @@ func (h *DrawingHandler) GetDrawings(c *gin.Context) {
 
 	drawings, err := h.DrawingRepo.FindAllByUserID(c.Request.Context(), userID)
 	if err != nil {
-		InternalServerError(c, err)
+		RepositoryError(c, err)
+		return
+	}
+
+	ids := make([]primitive.ObjectID, len(drawings))
+	for i, d := range drawings {
+		ids[i] = d.ID
+	}
+	counts, err := h.CommentRepo.CountUnresolvedByDrawingIDs(c.Request.Context(), ids)
+	if err != nil {
+		RepositoryError(c, err)
 		return
 	}
+	for _, d := range drawings {
+		d.UnresolvedComments = counts[d.ID]
+	}
 
 	c.JSON(http.StatusOK, drawings)
 }
@@ -81,7 +175,7 @@ func (h *DrawingHandler) GetDrawingByID(c *gin.Context) {
 
 	drawing, err := h.DrawingRepo.FindByIDAndUserID(c.Request.Context(), drawingID, userID)
 	if err != nil {
-		InternalServerError(c, err)
+		RepositoryError(c, err)
 		return
 	}
 	if drawing == nil {
@@ -92,9 +186,13 @@ func (h *DrawingHandler) GetDrawingByID(c *gin.Context) {
 	c.JSON(http.StatusOK, drawing)
 }
 
+// UpdateDrawingRequest replaces a drawing. Folder and tags left out are
+// removed; use PATCH to change some fields only.
 type UpdateDrawingRequest struct {
-	Title     string `json:"title"`
-	SceneData string `json:"sceneData"`
+	Title     string   `json:"title" binding:"required"`
+	SceneData string   `json:"sceneData" binding:"required"`
+	Folder    string   `json:"folder" binding:"max=200"`
+	Tags      []string `json:"tags" binding:"max=20,dive,required,max=50"`
 }
 
 func (h *DrawingHandler) UpdateDrawing(c *gin.Context) {
@@ -121,16 +219,120 @@ func (h *DrawingHandler) UpdateDrawing(c *gin.Context) {
 		UserID:    userID,
 		Title:     req.Title,
 		SceneData: req.SceneData,
+		Folder:    req.Folder,
+		Tags:      req.Tags,
 	}
 
 	if err := h.DrawingRepo.Update(c.Request.Context(), drawing); err != nil {
-		InternalServerError(c, err)
+		RepositoryError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Drawing updated successfully"})
 }
 
+// MergePatchContentType is the media type of a JSON Merge Patch (RFC 7396).
+const MergePatchContentType = "application/merge-patch+json"
+
+// PatchDrawingRequest is a merge patch of a drawing's metadata. A member left
+// out is unchanged and a null folder or tags removes it.
+type PatchDrawingRequest struct {
+	Title     *string   `json:"title" binding:"omitempty,min=1"`
+	SceneData *string   `json:"sceneData" binding:"omitempty,min=1"`
+	Folder    *string   `json:"folder" binding:"omitempty,max=200"`
+	Tags      *[]string `json:"tags" binding:"omitempty,max=20,dive,required,max=50"`
+}
+
+// PatchDrawing applies a JSON Merge Patch to a drawing, so renaming or
+// refiling it never resends the scene. It responds with the drawing without
+// its scene data.
+func (h *DrawingHandler) PatchDrawing(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	drawingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	if ct := c.ContentType(); ct != MergePatchContentType && ct != binding.MIMEJSON {
+		HandleError(c, http.StatusUnsupportedMediaType, problem.UnsupportedMediaType, "Send the patch as "+MergePatchContentType)
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			HandleError(c, http.StatusBadRequest, problem.InvalidRequest, "A merge patch must be a JSON object")
+			return
+		}
+		BadRequest(c, err)
+		return
+	}
+	if members == nil {
+		HandleError(c, http.StatusBadRequest, problem.InvalidRequest, "A merge patch must be a JSON object")
+		return
+	}
+
+	var fieldErrors []problem.FieldError
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch name {
+		case "title", "sceneData":
+			if string(members[name]) == "null" {
+				fieldErrors = append(fieldErrors, problem.FieldError{Field: name, Rule: "required", Message: "cannot be removed"})
+			}
+		case "folder", "tags":
+		default:
+			fieldErrors = append(fieldErrors, problem.FieldError{Field: name, Rule: "readonly", Message: "cannot be changed"})
+		}
+	}
+	if len(fieldErrors) > 0 {
+		problem.Invalid(c, fieldErrors...)
+		return
+	}
+
+	var req PatchDrawingRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	patch := repository.DrawingPatch{Title: req.Title, SceneData: req.SceneData, Folder: req.Folder, Tags: req.Tags}
+	if _, ok := members["folder"]; ok && patch.Folder == nil {
+		patch.Folder = new(string)
+	}
+	if _, ok := members["tags"]; ok && patch.Tags == nil {
+		patch.Tags = &[]string{}
+	}
+
+	drawing, err := h.DrawingRepo.Patch(c.Request.Context(), drawingID, userID, patch)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, drawing)
+}
+
 func (h *DrawingHandler) DeleteDrawing(c *gin.Context) {
 	userID, err := getUserIDFromContext(c)
 	if err != nil {
@@ -144,12 +346,132 @@ func (h *DrawingHandler) DeleteDrawing(c *gin.Context) {
 		return
 	}
 
+	permanent, _ := strconv.ParseBool(c.Query("permanent"))
+	if permanent {
+		if err := h.DrawingRepo.Purge(c.Request.Context(), drawingID, userID); err != nil {
+			RepositoryError(c, err)
+			return
+		}
+		if err := h.CommentRepo.DeleteByDrawingID(c.Request.Context(), drawingID); err != nil {
+			RepositoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Drawing permanently deleted"})
+		return
+	}
+
 	if err := h.DrawingRepo.Delete(c.Request.Context(), drawingID, userID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Drawing moved to trash"})
+}
+
+type DuplicateDrawingRequest struct {
+	Title string `json:"title"`
+	// Folder files the copy elsewhere; it stays in the source's folder when
+	// omitted.
+	Folder *string `json:"folder" binding:"omitempty,max=200"`
+}
+
+// DuplicateDrawing copies one of the user's drawings, including the files
+// embedded in its scene data, and records the source in ForkedFrom.
+func (h *DrawingHandler) DuplicateDrawing(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
 		InternalServerError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Drawing deleted successfully"})
+	drawingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	// The body is optional; an empty request keeps the default title and
+	// the source's folder.
+	var req DuplicateDrawingRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			BadRequest(c, err)
+			return
+		}
+	}
+
+	source, err := h.DrawingRepo.FindByIDAndUserID(c.Request.Context(), drawingID, userID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if source == nil {
+		NotFound(c, "Drawing not found")
+		return
+	}
+
+	title := req.Title
+	if title == "" {
+		title = source.Title + " (copy)"
+	}
+	folder := source.Folder
+	if req.Folder != nil {
+		folder = *req.Folder
+	}
+
+	drawing := &models.Drawing{
+		ID:         primitive.NewObjectID(),
+		UserID:     userID,
+		Title:      title,
+		SceneData:  source.SceneData,
+		Folder:     folder,
+		Tags:       source.Tags,
+		ForkedFrom: &source.ID,
+	}
+
+	if err := h.DrawingRepo.Create(c.Request.Context(), drawing); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, drawing)
+}
+
+func (h *DrawingHandler) GetTrash(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	drawings, err := h.DrawingRepo.FindDeletedByUserID(c.Request.Context(), userID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, drawings)
+}
+
+func (h *DrawingHandler) RestoreDrawing(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	drawingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	if err := h.DrawingRepo.Restore(c.Request.Context(), drawingID, userID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Drawing restored successfully"})
 }
 
 // getUserIDFromContext is a helper to reduce repetition
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// setupDrawingRouter returns a router whose requests are authenticated as userID.
+func setupDrawingRouter(drawingRepo repository.DrawingRepository, userID primitive.ObjectID) *gin.Engine {
+	drawingHandler := NewDrawingHandler(drawingRepo, repository.NewMockCommentRepository())
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", userID.Hex())
+		c.Next()
+	})
+	r.GET("/drawings", drawingHandler.GetDrawings)
+	r.GET("/drawings/trash", drawingHandler.GetTrash)
+	r.GET("/drawings/:id", drawingHandler.GetDrawingByID)
+	r.PUT("/drawings/:id", drawingHandler.UpdateDrawing)
+	r.PATCH("/drawings/:id", drawingHandler.PatchDrawing)
+	r.DELETE("/drawings/:id", drawingHandler.DeleteDrawing)
+	r.POST("/drawings/:id/restore", drawingHandler.RestoreDrawing)
+	r.POST("/drawings/:id/duplicate", drawingHandler.DuplicateDrawing)
+	return r
+}
+
+func TestDrawingHandler_Trash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID := primitive.NewObjectID()
+
+	newRepo := func() (*repository.MockDrawingRepository, *models.Drawing) {
+		mockDrawingRepo := repository.NewMockDrawingRepository()
+		drawing := &models.Drawing{ID: primitive.NewObjectID(), UserID: userID, Title: "Diagram", SceneData: "{}"}
+		mockDrawingRepo.Create(nil, drawing)
+		return mockDrawingRepo, drawing
+	}
+
+	serve := func(r *gin.Engine, method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Delete Moves To Trash", func(t *testing.T) {
+		mockDrawingRepo, drawing := newRepo()
+		r := setupDrawingRouter(mockDrawingRepo, userID)
+
+		w := serve(r, http.MethodDelete, "/drawings/"+drawing.ID.Hex())
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotNil(t, drawing.DeletedAt)
+
+		w = serve(r, http.MethodGet, "/drawings/"+drawing.ID.Hex())
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = serve(r, http.MethodGet, "/drawings/trash")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), drawing.ID.Hex())
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		mockDrawingRepo, drawing := newRepo()
+		r := setupDrawingRouter(mockDrawingRepo, userID)
+
+		serve(r, http.MethodDelete, "/drawings/"+drawing.ID.Hex())
+		w := serve(r, http.MethodPost, "/drawings/"+drawing.ID.Hex()+"/restore")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, drawing.DeletedAt)
+
+		w = serve(r, http.MethodGet, "/drawings/"+drawing.ID.Hex())
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Restore Drawing Not In Trash", func(t *testing.T) {
+		mockDrawingRepo, drawing := newRepo()
+		r := setupDrawingRouter(mockDrawingRepo, userID)
+
+		w := serve(r, http.MethodPost, "/drawings/"+drawing.ID.Hex()+"/restore")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Permanent Delete", func(t *testing.T) {
+		mockDrawingRepo, drawing := newRepo()
+		r := setupDrawingRouter(mockDrawingRepo, userID)
+
+		w := serve(r, http.MethodDelete, "/drawings/"+drawing.ID.Hex()+"?permanent=true")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, mockDrawingRepo.Drawings, drawing.ID)
+	})
+}
+
+func TestDrawingHandler_Duplicate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID := primitive.NewObjectID()
+	mockDrawingRepo := repository.NewMockDrawingRepository()
+	source := &models.Drawing{ID: primitive.NewObjectID(), UserID: userID, Title: "Diagram", SceneData: `{"elements":[]}`}
+	mockDrawingRepo.Create(nil, source)
+	r := setupDrawingRouter(mockDrawingRepo, userID)
+
+	t.Run("Default Title", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/drawings/"+source.ID.Hex()+"/duplicate", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var copied models.Drawing
+		json.Unmarshal(w.Body.Bytes(), &copied)
+		assert.NotEqual(t, source.ID, copied.ID)
+		assert.Equal(t, "Diagram (copy)", copied.Title)
+		assert.Equal(t, source.SceneData, copied.SceneData)
+		assert.Equal(t, source.ID, *copied.ForkedFrom)
+	})
+
+	t.Run("Custom Title", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/drawings/"+source.ID.Hex()+"/duplicate", bytes.NewBufferString(`{"title": "Variant"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"title":"Variant"`)
+	})
+
+	t.Run("Folder", func(t *testing.T) {
+		duplicate := func(body string) (*httptest.ResponseRecorder, models.Drawing) {
+			req, _ := http.NewRequest(http.MethodPost, "/drawings/"+source.ID.Hex()+"/duplicate", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			var copied models.Drawing
+			json.Unmarshal(w.Body.Bytes(), &copied)
+			return w, copied
+		}
+		source.Folder = "work"
+		defer func() { source.Folder = "" }()
+
+		w, copied := duplicate(`{}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "work", copied.Folder)
+
+		w, copied = duplicate(`{"folder": "work/archive"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "work/archive", copied.Folder)
+
+		w, _ = duplicate(`{"folder": "` + strings.Repeat("a", 201) + `"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Other User's Drawing", func(t *testing.T) {
+		other := setupDrawingRouter(mockDrawingRepo, primitive.NewObjectID())
+		req, _ := http.NewRequest(http.MethodPost, "/drawings/"+source.ID.Hex()+"/duplicate", nil)
+		w := httptest.NewRecorder()
+		other.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestDrawingHandler_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockDrawingRepo := repository.NewMockDrawingRepository()
+	drawing := &models.Drawing{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Title: "Diagram", SceneData: "{}"}
+	mockDrawingRepo.Create(nil, drawing)
+	r := setupDrawingRouter(mockDrawingRepo, primitive.NewObjectID())
+
+	for _, id := range []string{primitive.NewObjectID().Hex(), drawing.ID.Hex()} {
+		for _, tc := range []struct{ method, path, body string }{
+			{http.MethodPut, "/drawings/" + id, `{"title": "Renamed", "sceneData": "{}"}`},
+			{http.MethodPatch, "/drawings/" + id, `{"title": "Renamed"}`},
+			{http.MethodDelete, "/drawings/" + id, ""},
+			{http.MethodDelete, "/drawings/" + id + "?permanent=true", ""},
+			{http.MethodPost, "/drawings/" + id + "/restore", ""},
+		} {
+			req, _ := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusNotFound, w.Code, "%s %s", tc.method, tc.path)
+			var resp map[string]interface{}
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			assert.Equal(t, "not_found", resp["code"])
+			assert.Equal(t, "Drawing not found", resp["detail"])
+		}
+	}
+	assert.Equal(t, "Diagram", drawing.Title, "other users' drawings are left alone")
+	assert.Nil(t, drawing.DeletedAt)
+}
+
+func TestDrawingHandler_Update(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID := primitive.NewObjectID()
+
+	newRepo := func() (*repository.MockDrawingRepository, *models.Drawing) {
+		mockDrawingRepo := repository.NewMockDrawingRepository()
+		drawing := &models.Drawing{ID: primitive.NewObjectID(), UserID: userID, Title: "Diagram", SceneData: `{"elements":[]}`, Folder: "Work", Tags: []string{"draft"}}
+		mockDrawingRepo.Create(nil, drawing)
+		return mockDrawingRepo, drawing
+	}
+
+	serve := func(r *gin.Engine, method, path, contentType, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	t.Run("Put Replaces Drawing", func(t *testing.T) {
+		mockDrawingRepo, drawing := newRepo()
+		r := setupDrawingRouter(mockDrawingRepo, userID)
+
+		w, _ := serve(r, http.MethodPut, "/drawings/"+drawing.ID.Hex(), "application/json", `{"title": "Renamed", "sceneData": "{}"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Renamed", drawing.Title)
+		assert.Equal(t, "{}", drawing.SceneData)
+		assert.Empty(t, drawing.Folder, "fields left out of a replacement are removed")
+		assert.Empty(t, drawing.Tags)
+	})
+
+	t.Run("Put Requires Scene", func(t *testing.T) {
+		mockDrawingRepo, drawing := newRepo()
+		r := setupDrawingRouter(mockDrawingRepo, userID)
+
+		w, resp := serve(r, http.MethodPut, "/drawings/"+drawing.ID.Hex(), "application/json", `{"title": "Renamed"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "validation_failed", resp["code"])
+		assert.Equal(t, "sceneData is required", resp["detail"])
+		assert.Equal(t, `{"elements":[]}`, drawing.SceneData, "scene should be untouched")
+	})
+
+	t.Run("Patch Renames Without Scene", func(t *testing.T) {
+		mockDrawingRepo, drawing := newRepo()
+		r := setupDrawingRouter(mockDrawingRepo, userID)
+
+		w, resp := serve(r, http.MethodPatch, "/drawings/"+drawing.ID.Hex(), MergePatchContentType, `{"title": "Renamed"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Renamed", resp["title"])
+		assert.Empty(t, resp["sceneData"], "the scene is not sent back")
+		assert.Equal(t, "Renamed", drawing.Title)
+		assert.Equal(t, `{"elements":[]}`, drawing.SceneData)
+		assert.Equal(t, "Work", drawing.Folder)
+		assert.Equal(t, []string{"draft"}, drawing.Tags)
+	})
+
+	t.Run("Patch Null Removes Member", func(t *testing.T) {
+		mockDrawingRepo, drawing := newRepo()
+		r := setupDrawingRouter(mockDrawingRepo, userID)
+
+		w, resp := serve(r, http.MethodPatch, "/drawings/"+drawing.ID.Hex(), MergePatchContentType, `{"folder": null, "tags": ["final", "q3"]}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, resp["folder"])
+		assert.Empty(t, drawing.Folder)
+		assert.Equal(t, []string{"final", "q3"}, drawing.Tags)
+		assert.Equal(t, "Diagram", drawing.Title)
+
+		w, _ = serve(r, http.MethodPatch, "/drawings/"+drawing.ID.Hex(), "application/json", `{"tags": null}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, drawing.Tags)
+	})
+
+	t.Run("Patch Rejected", func(t *testing.T) {
+		mockDrawingRepo, drawing := newRepo()
+		r := setupDrawingRouter(mockDrawingRepo, userID)
+		path := "/drawings/" + drawing.ID.Hex()
+
+		w, resp := serve(r, http.MethodPatch, path, MergePatchContentType, `{"title": null, "userId": "abc"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "validation_failed", resp["code"])
+		assert.Equal(t, "title cannot be removed; userId cannot be changed", resp["detail"])
+
+		w, resp = serve(r, http.MethodPatch, path, MergePatchContentType, `{"title": ""}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "validation_failed", resp["code"])
+
+		w, resp = serve(r, http.MethodPatch, path, MergePatchContentType, `["title"]`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "invalid_request", resp["code"])
+
+		w, resp = serve(r, http.MethodPatch, path, "text/plain", `{"title": "Renamed"}`)
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+		assert.Equal(t, "unsupported_media_type", resp["code"])
+
+		assert.Equal(t, "Diagram", drawing.Title)
+	})
+}
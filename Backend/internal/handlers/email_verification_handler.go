@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/mail"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/drshn/excalidraw/Backend/internal/ratelimit"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type EmailVerificationHandler struct {
+	UserRepo      repository.UserRepository
+	UserTokenRepo repository.UserTokenRepository
+	Mailer        mail.Mailer
+	Limiter       *ratelimit.Limiter
+	AppBaseURL    string
+	TokenTTL      time.Duration
+}
+
+func NewEmailVerificationHandler(userRepo repository.UserRepository, userTokenRepo repository.UserTokenRepository, mailer mail.Mailer, appBaseURL string, tokenTTL time.Duration) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		UserRepo:      userRepo,
+		UserTokenRepo: userTokenRepo,
+		Mailer:        mailer,
+		// At most 3 verification emails per user every 15 minutes.
+		Limiter:    ratelimit.NewLimiter(3, 15*time.Minute),
+		AppBaseURL: appBaseURL,
+		TokenTTL:   tokenTTL,
+	}
+}
+
+// SendVerification emails the user a link to verify their address,
+// invalidating any earlier link.
+func (h *EmailVerificationHandler) SendVerification(ctx context.Context, user *models.User) error {
+	rawToken, err := issueUserToken(ctx, h.UserTokenRepo, user.ID, models.TokenPurposeEmailVerification, "", h.TokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(h.AppBaseURL, "/") + "/verify-email?token=" + url.QueryEscape(rawToken)
+	sendMailAsync(h.Mailer, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Welcome! Please confirm your email address by opening this link:\n%s\n\n"+
+			"The link expires in %s.\n",
+			link, h.TokenTTL),
+	})
+	return nil
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func (h *EmailVerificationHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	token, err := consumeUserToken(c.Request.Context(), h.UserTokenRepo, models.TokenPurposeEmailVerification, req.Token)
+	if err != nil {
+		if errors.Is(err, errInvalidUserToken) {
+			HandleError(c, http.StatusBadRequest, problem.InvalidEmailToken, "Invalid or expired token")
+			return
+		}
+		RepositoryError(c, err)
+		return
+	}
+
+	if err := h.UserRepo.SetEmailVerified(c.Request.Context(), token.UserID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email address verified"})
+}
+
+// ResendVerification sends a new verification email to the current user.
+func (h *EmailVerificationHandler) ResendVerification(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	user, err := h.UserRepo.FindByID(c.Request.Context(), userID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if user == nil {
+		NotFound(c, "User not found")
+		return
+	}
+	if user.EmailVerified {
+		HandleError(c, http.StatusConflict, problem.EmailAlreadyVerified, "Email address is already verified")
+		return
+	}
+
+	if ok, retryAfter := h.Limiter.Allow(user.ID.Hex()); !ok {
+		TooManyRequests(c, retryAfter)
+		return
+	}
+
+	if err := h.SendVerification(c.Request.Context(), user); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/middleware"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailVerificationHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserRepo := repository.NewMockUserRepository()
+	tokens := newTestTokenService()
+	mailer := newRecordingMailer()
+	verifier := NewEmailVerificationHandler(mockUserRepo, repository.NewMockUserTokenRepository(), mailer, "https://draw.example.com/", 48*time.Hour)
+	authHandler := NewAuthHandler(mockUserRepo, tokens, newTestPasswordHasher(), newTestPasswordPolicy(), verifier, nil, nil)
+	drawingHandler := NewDrawingHandler(repository.NewMockDrawingRepository(), repository.NewMockCommentRepository())
+	authMiddleware := newTestAuthMiddleware(tokens)
+
+	router := gin.Default()
+	router.POST("/register", authHandler.Register)
+	router.POST("/login", authHandler.Login)
+	router.POST("/verify-email", verifier.VerifyEmail)
+	router.POST("/verify-email/resend", authMiddleware, verifier.ResendVerification)
+	router.POST("/drawings", authMiddleware, middleware.RequireVerifiedEmail(mockUserRepo, true), drawingHandler.CreateDrawing)
+
+	post := func(path, body, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/register", `{"email": "verify@example.com", "password": "password123"}`, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	msg := mailer.next(t)
+	assert.Equal(t, "verify@example.com", msg.To)
+	assert.Contains(t, msg.Body, "https://draw.example.com/verify-email?token=")
+	assert.False(t, mockUserRepo.Users["verify@example.com"].EmailVerified)
+
+	w = post("/login", `{"email": "verify@example.com", "password": "password123"}`, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var login map[string]string
+	json.Unmarshal(w.Body.Bytes(), &login)
+	accessToken := login["token"]
+
+	t.Run("Unverified Users Cannot Create Drawings", func(t *testing.T) {
+		w := post("/drawings", `{"title": "Blocked", "sceneData": "{}"}`, accessToken)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Resend Invalidates Earlier Link", func(t *testing.T) {
+		w := post("/verify-email/resend", "", accessToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		latest := tokenFromEmail(t, mailer.next(t))
+
+		w = post("/verify-email", `{"token": "`+tokenFromEmail(t, msg)+`"}`, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = post("/verify-email", `{"token": "`+latest+`"}`, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, mockUserRepo.Users["verify@example.com"].EmailVerified)
+
+		w = post("/verify-email", `{"token": "`+latest+`"}`, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, "verification tokens are single-use")
+	})
+
+	t.Run("Verified Users Can Create Drawings", func(t *testing.T) {
+		w := post("/drawings", `{"title": "Allowed", "sceneData": "{}"}`, accessToken)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("Resend After Verification", func(t *testing.T) {
+		w := post("/verify-email/resend", "", accessToken)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
@@ -1,48 +1,93 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
-// APIError represents a standard error response format.
-type APIError struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-	Error   string `json:"error,omitempty"`
-}
-
-// HandleError provides a consistent way to handle errors across the API.
-func HandleError(c *gin.Context, status int, message string, err error) {
-	apiError := APIError{
-		Status:  status,
-		Message: message,
-	}
-	if err != nil {
-		apiError.Error = err.Error()
-	}
-	c.JSON(status, apiError)
-	c.Abort()
+// HandleError provides a consistent way to handle errors across the API. It
+// responds with an application/problem+json body carrying code; message
+// becomes the problem's detail, so it must be safe to show to clients.
+func HandleError(c *gin.Context, status int, code problem.Code, message string) {
+	problem.Write(c, status, code, message)
 }
 
 // Pre-defined error helpers
+
+// BadRequest responds with 400 for a binding, JSON or ID error, listing
+// invalid fields when there are any.
 func BadRequest(c *gin.Context, err error) {
-	HandleError(c, http.StatusBadRequest, "Invalid request payload", err)
+	problem.BadRequest(c, err)
 }
 
 func Unauthorized(c *gin.Context, message string) {
-	HandleError(c, http.StatusUnauthorized, message, nil)
+	HandleError(c, http.StatusUnauthorized, problem.Unauthorized, message)
 }
 
 func NotFound(c *gin.Context, message string) {
-	HandleError(c, http.StatusNotFound, message, nil)
+	HandleError(c, http.StatusNotFound, problem.NotFound, message)
 }
 
+// InternalServerError logs err with the request's correlation id and hides
+// it from the client.
 func InternalServerError(c *gin.Context, err error) {
-	HandleError(c, http.StatusInternalServerError, "An unexpected error occurred", err)
+	problem.Internal(c, err)
+}
+
+// RepositoryError responds to an error returned by a repository, or by code
+// that passes repository errors through: 404 for *repository.NotFoundError,
+// 409 for *repository.ConflictError, 403 for *repository.ForbiddenError, 503
+// when the password hasher is saturated and 500 for anything else.
+func RepositoryError(c *gin.Context, err error) {
+	var notFound *repository.NotFoundError
+	var conflict *repository.ConflictError
+	var forbidden *repository.ForbiddenError
+	switch {
+	case errors.As(err, &notFound):
+		NotFound(c, sentence(notFound.Error()))
+	case errors.As(err, &conflict):
+		code := problem.Conflict
+		if conflict.Resource == "user" && conflict.Field == "email" {
+			code = problem.EmailTaken
+		}
+		HandleError(c, http.StatusConflict, code, sentence(conflict.Error()))
+	case errors.As(err, &forbidden):
+		Forbidden(c, sentence(forbidden.Error()))
+	case errors.Is(err, auth.ErrPasswordHasherBusy):
+		c.Header("Retry-After", "1")
+		HandleError(c, http.StatusServiceUnavailable, problem.ServerBusy, "The server is busy, please try again shortly")
+	default:
+		InternalServerError(c, err)
+	}
+}
+
+// sentence capitalizes the first letter of a lowercase error message.
+func sentence(message string) string {
+	if message == "" {
+		return message
+	}
+	return strings.ToUpper(message[:1]) + message[1:]
 }
 
 func Conflict(c *gin.Context, message string) {
-	HandleError(c, http.StatusConflict, message, nil)
+	HandleError(c, http.StatusConflict, problem.Conflict, message)
+}
+
+func Forbidden(c *gin.Context, message string) {
+	HandleError(c, http.StatusForbidden, problem.Forbidden, message)
+}
+
+// TooManyRequests responds with 429 and a Retry-After header in whole seconds.
+func TooManyRequests(c *gin.Context, retryAfter time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	HandleError(c, http.StatusTooManyRequests, problem.RateLimited, "Too many requests, please try again later")
 }
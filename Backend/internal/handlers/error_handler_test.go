@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		err    error
+		status int
+		code   string
+		detail string
+	}{
+		{&repository.NotFoundError{Resource: "library"}, http.StatusNotFound, "not_found", "Library not found"},
+		{fmt.Errorf("renaming: %w", &repository.NotFoundError{Resource: "drawing"}), http.StatusNotFound, "not_found", "Drawing not found"},
+		{&repository.ConflictError{Resource: "user", Field: "email"}, http.StatusConflict, "email_taken", "User with this email already exists"},
+		{&repository.ConflictError{Resource: "library"}, http.StatusConflict, "conflict", "Library already exists"},
+		{&repository.ForbiddenError{Resource: "comment", Reason: "only the author can change this comment"}, http.StatusForbidden, "forbidden", "Only the author can change this comment"},
+		{fmt.Errorf("login: %w", auth.ErrPasswordHasherBusy), http.StatusServiceUnavailable, "server_busy", "The server is busy, please try again shortly"},
+		{errors.New("server selection error: context deadline exceeded"), http.StatusInternalServerError, "internal_error", "An unexpected error occurred"},
+	}
+
+	for _, tc := range tests {
+		r := gin.New()
+		r.GET("/", func(c *gin.Context) { RepositoryError(c, tc.err) })
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.status, w.Code, tc.err.Error())
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.Equal(t, tc.code, resp["code"])
+		assert.Equal(t, tc.detail, resp["detail"])
+	}
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Registration modes, set with REGISTRATION_MODE.
+const (
+	RegistrationOpen     = "open"
+	RegistrationDisabled = "disabled"
+	// RegistrationDomains only checks the domain of the address someone
+	// types in, which proves nothing until they verify it, so the mode is
+	// refused unless REQUIRE_EMAIL_VERIFICATION keeps unverified accounts
+	// from creating drawings.
+	RegistrationDomains = "domains"
+	RegistrationInvite  = "invite"
+)
+
+// registrationRefused is why Register turned someone away, worded for them.
+type registrationRefused string
+
+func (r registrationRefused) Error() string { return string(r) }
+
+func (r registrationRefused) code() problem.Code {
+	switch r {
+	case errRegistrationDisabled:
+		return problem.RegistrationDisabled
+	case errEmailDomainNotAllowed:
+		return problem.EmailDomainNotAllowed
+	case errInvitationRequired:
+		return problem.InvitationRequired
+	}
+	return problem.InvalidInvitation
+}
+
+const (
+	errRegistrationDisabled  registrationRefused = "Registration is disabled; ask an administrator for an account"
+	errEmailDomainNotAllowed registrationRefused = "Registration is limited to approved email domains"
+	errInvitationRequired    registrationRefused = "Registration requires an invitation"
+	errInvalidInvitation     registrationRefused = "Invalid or expired invitation"
+)
+
+// Registration decides who may create an account through Register or a
+// first single sign-on login. Users created through the command line are
+// not affected.
+type Registration struct {
+	Mode string
+	// AllowedDomains are lowercase email domains, used in domains mode.
+	AllowedDomains []string
+	Invitations    *InvitationHandler
+}
+
+// NewRegistration validates a mode and a comma-separated list of domains.
+// requireEmailVerification reports whether REQUIRE_EMAIL_VERIFICATION is
+// set, which domains mode depends on.
+func NewRegistration(mode, allowedDomains string, requireEmailVerification bool, invitations *InvitationHandler) (*Registration, error) {
+	r := &Registration{Mode: mode, Invitations: invitations}
+	for _, domain := range strings.Split(allowedDomains, ",") {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if domain != "" {
+			r.AllowedDomains = append(r.AllowedDomains, domain)
+		}
+	}
+
+	switch mode {
+	case RegistrationOpen, RegistrationDisabled, RegistrationInvite:
+	case RegistrationDomains:
+		if len(r.AllowedDomains) == 0 {
+			return nil, errors.New("REGISTRATION_MODE domains requires REGISTRATION_ALLOWED_DOMAINS")
+		}
+		if !requireEmailVerification {
+			return nil, errors.New("REGISTRATION_MODE domains requires REQUIRE_EMAIL_VERIFICATION, since an unverified address proves nothing about its domain")
+		}
+	default:
+		return nil, fmt.Errorf("unknown registration mode %q; use open, disabled, domains or invite", mode)
+	}
+	return r, nil
+}
+
+// admit checks whether email may register. In invite-only mode it uses up
+// the invitation for userID; release hands it back if the account cannot be
+// created after all. A nil Registration is open.
+func (r *Registration) admit(ctx context.Context, email, code string, userID primitive.ObjectID) (*models.Invitation, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	switch r.Mode {
+	case RegistrationDisabled:
+		return nil, errRegistrationDisabled
+	case RegistrationDomains:
+		_, domain, _ := strings.Cut(strings.ToLower(email), "@")
+		for _, allowed := range r.AllowedDomains {
+			if domain == allowed {
+				return nil, nil
+			}
+		}
+		return nil, errEmailDomainNotAllowed
+	case RegistrationInvite:
+		if code == "" {
+			return nil, errInvitationRequired
+		}
+		return r.Invitations.redeem(ctx, code, email, userID)
+	}
+	return nil, nil
+}
+
+func (r *Registration) release(ctx context.Context, invitation *models.Invitation) {
+	if invitation == nil {
+		return
+	}
+	if err := r.Invitations.InvitationRepo.Release(ctx, invitation.ID); err != nil {
+		log.Printf("Failed to release invitation %s: %v", invitation.ID.Hex(), err)
+	}
+}
+
+type InvitationHandler struct {
+	InvitationRepo repository.InvitationRepository
+	UserRepo       repository.UserRepository
+	// MembersCanInvite lets users without the admin role issue invitations.
+	MembersCanInvite bool
+	AppBaseURL       string
+	TTL              time.Duration
+}
+
+func NewInvitationHandler(invitationRepo repository.InvitationRepository, userRepo repository.UserRepository, membersCanInvite bool, appBaseURL string, ttl time.Duration) *InvitationHandler {
+	return &InvitationHandler{
+		InvitationRepo:   invitationRepo,
+		UserRepo:         userRepo,
+		MembersCanInvite: membersCanInvite,
+		AppBaseURL:       appBaseURL,
+		TTL:              ttl,
+	}
+}
+
+type CreateInvitationRequest struct {
+	// Email optionally restricts the invitation to one address.
+	Email string `json:"email" binding:"omitempty,email"`
+	// ExpiresInDays overrides the default lifetime.
+	ExpiresInDays int `json:"expiresInDays" binding:"omitempty,min=1,max=90"`
+}
+
+// CreateInvitationResponse includes the raw code, which cannot be retrieved
+// again, and a registration link containing it.
+type CreateInvitationResponse struct {
+	*models.Invitation
+	Code string `json:"code"`
+	Link string `json:"link"`
+}
+
+// CreateInvitation issues a single-use invitation code.
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	user, ok := findCurrentUser(c, h.UserRepo)
+	if !ok {
+		return
+	}
+	if user.Role != models.RoleAdmin && !h.MembersCanInvite {
+		HandleError(c, http.StatusForbidden, problem.PermissionDenied, "Only administrators can invite people")
+		return
+	}
+
+	var req CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	ttl := h.TTL
+	if req.ExpiresInDays > 0 {
+		ttl = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+
+	code, err := auth.GenerateToken()
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+	now := time.Now().UTC()
+	invitation := &models.Invitation{
+		ID:        primitive.NewObjectID(),
+		CodeHash:  auth.HashToken(code),
+		CreatedBy: user.ID,
+		Email:     strings.ToLower(req.Email),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := h.InvitationRepo.Create(c.Request.Context(), invitation); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	link := strings.TrimRight(h.AppBaseURL, "/") + "/register?invitation=" + url.QueryEscape(code)
+	c.JSON(http.StatusCreated, CreateInvitationResponse{Invitation: invitation, Code: code, Link: link})
+}
+
+// GetInvitations lists the invitations the current user has issued.
+func (h *InvitationHandler) GetInvitations(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	invitations, err := h.InvitationRepo.FindAllByCreator(c.Request.Context(), userID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// DeleteInvitation revokes an invitation. Admins may revoke anyone's.
+func (h *InvitationHandler) DeleteInvitation(c *gin.Context) {
+	user, ok := findCurrentUser(c, h.UserRepo)
+	if !ok {
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	invitation, err := h.InvitationRepo.FindByID(c.Request.Context(), id)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if invitation == nil || (invitation.CreatedBy != user.ID && user.Role != models.RoleAdmin) {
+		NotFound(c, "Invitation not found")
+		return
+	}
+
+	if err := h.InvitationRepo.Delete(c.Request.Context(), id); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// redeem uses up an invitation for a new user with the given email.
+func (h *InvitationHandler) redeem(ctx context.Context, code, email string, userID primitive.ObjectID) (*models.Invitation, error) {
+	invitation, err := h.InvitationRepo.FindByHash(ctx, auth.HashToken(code))
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil || invitation.UsedAt != nil || time.Now().After(invitation.ExpiresAt) {
+		return nil, errInvalidInvitation
+	}
+	if invitation.Email != "" && !strings.EqualFold(invitation.Email, email) {
+		return nil, errInvalidInvitation
+	}
+
+	consumed, err := h.InvitationRepo.Consume(ctx, invitation.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, errInvalidInvitation
+	}
+	return invitation, nil
+}
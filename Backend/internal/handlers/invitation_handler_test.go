@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestInvitationHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	mockUserRepo := repository.NewMockUserRepository()
+	mockInvitationRepo := repository.NewMockInvitationRepository()
+	tokens := newTestTokenService()
+	passwords := newTestPasswordHasher()
+	invitationHandler := NewInvitationHandler(mockInvitationRepo, mockUserRepo, false, "https://draw.example.com", 7*24*time.Hour)
+	registration, err := NewRegistration(RegistrationInvite, "", false, invitationHandler)
+	assert.NoError(t, err)
+	authHandler := NewAuthHandler(mockUserRepo, tokens, passwords, newTestPasswordPolicy(), nil, nil, registration)
+
+	router := gin.Default()
+	router.GET("/registration", authHandler.GetRegistration)
+	router.POST("/register", authHandler.Register)
+	router.POST("/login", authHandler.Login)
+	invitations := router.Group("/invitations", newTestAuthMiddleware(tokens))
+	invitations.POST("", invitationHandler.CreateInvitation)
+	invitations.GET("", invitationHandler.GetInvitations)
+	invitations.DELETE("/:id", invitationHandler.DeleteInvitation)
+
+	do := func(method, path, body, token string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+	login := func(email string) string {
+		_, resp := do(http.MethodPost, "/login", `{"email": "`+email+`", "password": "password123"}`, "")
+		token, _ := resp["token"].(string)
+		return token
+	}
+
+	// Registration is closed, so the first users are created directly.
+	hash, _ := passwords.Hash("password123")
+	mockUserRepo.Create(ctx, &models.User{ID: primitive.NewObjectID(), Email: "admin@example.com", Password: hash, Role: models.RoleAdmin})
+	mockUserRepo.Create(ctx, &models.User{ID: primitive.NewObjectID(), Email: "alice@example.com", Password: hash})
+	adminToken := login("admin@example.com")
+	aliceToken := login("alice@example.com")
+
+	_, resp := do(http.MethodGet, "/registration", "", "")
+	assert.Equal(t, "invite", resp["mode"])
+
+	w, resp := do(http.MethodPost, "/register", `{"email": "carol@example.com", "password": "password123"}`, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "invitation_required", resp["code"])
+
+	t.Run("Members Cannot Invite By Default", func(t *testing.T) {
+		w, _ := do(http.MethodPost, "/invitations", `{}`, aliceToken)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Invitation For An Address", func(t *testing.T) {
+		w, resp := do(http.MethodPost, "/invitations", `{"email": "Carol@example.com"}`, adminToken)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		code, _ := resp["code"].(string)
+		assert.NotEmpty(t, code)
+		assert.Equal(t, "https://draw.example.com/register?invitation="+code, resp["link"])
+		assert.Equal(t, "carol@example.com", resp["email"])
+		assert.NotContains(t, resp, "codeHash")
+
+		w, _ = do(http.MethodPost, "/register", `{"email": "dave@example.com", "password": "password123", "invitationCode": "`+code+`"}`, "")
+		assert.Equal(t, http.StatusForbidden, w.Code, "the invitation is for another address")
+
+		w, _ = do(http.MethodPost, "/register", `{"email": "carol@example.com", "password": "short", "invitationCode": "`+code+`"}`, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, _ = do(http.MethodPost, "/register", `{"email": "carol@example.com", "password": "password123", "invitationCode": "`+code+`"}`, "")
+		assert.Equal(t, http.StatusCreated, w.Code, "a rejected password does not use up the invitation")
+
+		invitation, _ := mockInvitationRepo.FindByHash(ctx, auth.HashToken(code))
+		assert.NotNil(t, invitation.UsedAt)
+		assert.Equal(t, mockUserRepo.Users["carol@example.com"].ID, *invitation.UsedBy)
+
+		w, _ = do(http.MethodPost, "/register", `{"email": "erin@example.com", "password": "password123", "invitationCode": "`+code+`"}`, "")
+		assert.Equal(t, http.StatusForbidden, w.Code, "invitations are single-use")
+	})
+
+	t.Run("Expired Invitation", func(t *testing.T) {
+		_, resp := do(http.MethodPost, "/invitations", `{}`, adminToken)
+		code := resp["code"].(string)
+		invitation, _ := mockInvitationRepo.FindByHash(ctx, auth.HashToken(code))
+		invitation.ExpiresAt = time.Now().Add(-time.Minute)
+
+		w, resp := do(http.MethodPost, "/register", `{"email": "erin@example.com", "password": "password123", "invitationCode": "`+code+`"}`, "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, "invalid_invitation", resp["code"])
+	})
+
+	t.Run("List And Revoke", func(t *testing.T) {
+		_, resp := do(http.MethodPost, "/invitations", `{"expiresInDays": 1}`, adminToken)
+		id := resp["_id"].(string)
+		code := resp["code"].(string)
+
+		req, _ := http.NewRequest(http.MethodGet, "/invitations", nil)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var list []map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &list)
+		assert.Len(t, list, 3)
+		assert.Equal(t, id, list[0]["_id"], "newest first")
+
+		w, _ = do(http.MethodDelete, "/invitations/"+id, "", aliceToken)
+		assert.Equal(t, http.StatusNotFound, w.Code, "members cannot revoke other people's invitations")
+		w, _ = do(http.MethodDelete, "/invitations/"+id, "", adminToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w, _ = do(http.MethodPost, "/register", `{"email": "frank@example.com", "password": "password123", "invitationCode": "`+code+`"}`, "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Members Can Invite When Allowed", func(t *testing.T) {
+		invitationHandler.MembersCanInvite = true
+		defer func() { invitationHandler.MembersCanInvite = false }()
+
+		w, resp := do(http.MethodPost, "/invitations", `{}`, aliceToken)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		w, _ = do(http.MethodPost, "/register", `{"email": "grace@example.com", "password": "password123", "invitationCode": "`+resp["code"].(string)+`"}`, "")
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+}
+
+func TestRegistrationModes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	register := func(registration *Registration, email string) *httptest.ResponseRecorder {
+		authHandler := NewAuthHandler(repository.NewMockUserRepository(), newTestTokenService(), newTestPasswordHasher(), newTestPasswordPolicy(), nil, nil, registration)
+		r := gin.Default()
+		r.POST("/register", authHandler.Register)
+
+		req, _ := http.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(`{"email": "`+email+`", "password": "password123"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	disabled, err := NewRegistration(RegistrationDisabled, "", false, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, register(disabled, "alice@example.com").Code)
+
+	domains, err := NewRegistration(RegistrationDomains, " @Example.com, corp.example.org ", true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"example.com", "corp.example.org"}, domains.AllowedDomains)
+	assert.Equal(t, http.StatusCreated, register(domains, "alice@EXAMPLE.com").Code)
+	assert.Equal(t, http.StatusCreated, register(domains, "bob@corp.example.org").Code)
+	assert.Equal(t, http.StatusForbidden, register(domains, "mallory@example.com.evil.test").Code)
+	assert.Equal(t, http.StatusForbidden, register(domains, "mallory@sub.example.com").Code)
+
+	_, err = NewRegistration(RegistrationDomains, "", true, nil)
+	assert.Error(t, err, "domains mode needs domains")
+	_, err = NewRegistration(RegistrationDomains, "example.com", false, nil)
+	assert.ErrorContains(t, err, "REQUIRE_EMAIL_VERIFICATION", "domains mode needs verified addresses")
+	_, err = NewRegistration("closed", "", false, nil)
+	assert.Error(t, err)
+}
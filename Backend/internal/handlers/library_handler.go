@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LibraryHandler struct {
+	LibraryRepo repository.LibraryRepository
+}
+
+func NewLibraryHandler(libraryRepo repository.LibraryRepository) *LibraryHandler {
+	return &LibraryHandler{LibraryRepo: libraryRepo}
+}
+
+type CreateLibraryRequest struct {
+	Name string `json:"name" binding:"required,max=128"`
+}
+
+func (h *LibraryHandler) CreateLibrary(c *gin.Context) {
+	var req CreateLibraryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	library := &models.Library{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Name:      req.Name,
+		Items:     []models.LibraryItem{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := h.LibraryRepo.Create(c.Request.Context(), library); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, library)
+}
+
+func (h *LibraryHandler) GetLibraries(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	libraries, err := h.LibraryRepo.FindAllByUserID(c.Request.Context(), userID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, libraries)
+}
+
+func (h *LibraryHandler) GetLibraryByID(c *gin.Context) {
+	library, ok := h.findLibrary(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, library)
+}
+
+type UpdateLibraryRequest struct {
+	Name string `json:"name" binding:"required,max=128"`
+}
+
+func (h *LibraryHandler) UpdateLibrary(c *gin.Context) {
+	var req UpdateLibraryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	library, ok := h.findLibrary(c)
+	if !ok {
+		return
+	}
+
+	library.Name = req.Name
+	if err := h.LibraryRepo.Update(c.Request.Context(), library); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, library)
+}
+
+func (h *LibraryHandler) DeleteLibrary(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	libraryID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	if err := h.LibraryRepo.Delete(c.Request.Context(), libraryID, userID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Library deleted successfully"})
+}
+
+type AddLibraryItemRequest struct {
+	Name     string                   `json:"name" binding:"max=128"`
+	Elements []map[string]interface{} `json:"elements" binding:"required,min=1"`
+}
+
+func (h *LibraryHandler) AddLibraryItem(c *gin.Context) {
+	var req AddLibraryItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	library, ok := h.findLibrary(c)
+	if !ok {
+		return
+	}
+
+	added, _, err := h.addLibraryItems(c.Request.Context(), library, []models.LibraryItem{{Name: req.Name, Elements: req.Elements}})
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if len(added) == 0 {
+		Conflict(c, "An identical item already exists in this library")
+		return
+	}
+
+	c.JSON(http.StatusCreated, added[0])
+}
+
+func (h *LibraryHandler) DeleteLibraryItem(c *gin.Context) {
+	library, ok := h.findLibrary(c)
+	if !ok {
+		return
+	}
+
+	if err := h.LibraryRepo.DeleteItem(c.Request.Context(), library.ID, library.UserID, c.Param("itemId")); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Library item deleted successfully"})
+}
+
+// ExcalidrawLibFile is the .excalidrawlib v2 file format.
+type ExcalidrawLibFile struct {
+	Type         string               `json:"type" binding:"required,eq=excalidrawlib"`
+	Version      int                  `json:"version" binding:"required,eq=2"`
+	Source       string               `json:"source,omitempty"`
+	LibraryItems []models.LibraryItem `json:"libraryItems" binding:"required"`
+}
+
+// ImportLibraryResponse counts the imported items and those skipped as
+// duplicates.
+type ImportLibraryResponse struct {
+	Added   int `json:"added"`
+	Skipped int `json:"skipped"`
+}
+
+// ImportLibrary merges the items of an uploaded .excalidrawlib file into the
+// library, skipping items identical to ones it already contains.
+func (h *LibraryHandler) ImportLibrary(c *gin.Context) {
+	var req ExcalidrawLibFile
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	library, ok := h.findLibrary(c)
+	if !ok {
+		return
+	}
+
+	added, skipped, err := h.addLibraryItems(c.Request.Context(), library, req.LibraryItems)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ImportLibraryResponse{Added: len(added), Skipped: skipped})
+}
+
+func (h *LibraryHandler) ExportLibrary(c *gin.Context) {
+	library, ok := h.findLibrary(c)
+	if !ok {
+		return
+	}
+
+	file := ExcalidrawLibFile{
+		Type:         "excalidrawlib",
+		Version:      2,
+		Source:       "drawcali",
+		LibraryItems: library.Items,
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+library.ID.Hex()+`.excalidrawlib"`)
+	c.JSON(http.StatusOK, file)
+}
+
+// findLibrary loads the library named by the :id parameter, writing an error
+// response and returning false if it cannot.
+func (h *LibraryHandler) findLibrary(c *gin.Context) (*models.Library, bool) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return nil, false
+	}
+
+	libraryID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		BadRequest(c, err)
+		return nil, false
+	}
+
+	library, err := h.LibraryRepo.FindByIDAndUserID(c.Request.Context(), libraryID, userID)
+	if err != nil {
+		RepositoryError(c, err)
+		return nil, false
+	}
+	if library == nil {
+		NotFound(c, "Library not found")
+		return nil, false
+	}
+	return library, true
+}
+
+// addLibraryItems adds the items not already present in the library, as
+// loaded, and returns those added and how many were skipped as duplicates.
+// Items keep their IDs unless another item has the ID already.
+func (h *LibraryHandler) addLibraryItems(ctx context.Context, library *models.Library, items []models.LibraryItem) (added []models.LibraryItem, skipped int, err error) {
+	seen := make(map[string]bool, len(library.Items))
+	ids := make(map[string]bool, len(library.Items))
+	for _, item := range library.Items {
+		seen[item.Hash] = true
+		ids[item.ID] = true
+	}
+
+	for _, item := range items {
+		item.Hash = libraryItemHash(item.Elements)
+		if len(item.Elements) == 0 || seen[item.Hash] {
+			skipped++
+			continue
+		}
+		if item.ID == "" || ids[item.ID] {
+			item.ID = newLibraryItemID()
+		}
+		if item.Status == "" {
+			item.Status = "unpublished"
+		}
+		if item.Created == 0 {
+			item.Created = time.Now().UnixMilli()
+		}
+
+		ok, err := h.LibraryRepo.AddItem(ctx, library.ID, library.UserID, item)
+		if err == nil && !ok {
+			// Someone else added the item, or took its ID, since the
+			// library was loaded; a fresh ID tells the two apart.
+			item.ID = newLibraryItemID()
+			ok, err = h.LibraryRepo.AddItem(ctx, library.ID, library.UserID, item)
+		}
+		if err != nil {
+			return added, skipped, err
+		}
+		seen[item.Hash] = true
+		ids[item.ID] = true
+		if !ok {
+			skipped++
+			continue
+		}
+		added = append(added, item)
+	}
+	return added, skipped, nil
+}
+
+// volatileElementKeys change every time Excalidraw copies an element and are
+// ignored when comparing library items.
+var volatileElementKeys = []string{"id", "seed", "version", "versionNonce", "updated", "index"}
+
+func libraryItemHash(elements []map[string]interface{}) string {
+	normalized := make([]map[string]interface{}, len(elements))
+	for i, element := range elements {
+		copied := make(map[string]interface{}, len(element))
+		for k, v := range element {
+			copied[k] = v
+		}
+		for _, k := range volatileElementKeys {
+			delete(copied, k)
+		}
+		normalized[i] = copied
+	}
+
+	// encoding/json sorts map keys, so equal elements encode identically.
+	data, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func newLibraryItemID() string {
+	b := make([]byte, 10)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testLibraryFile = `{
+	"type": "excalidrawlib",
+	"version": 2,
+	"source": "https://excalidraw.com",
+	"libraryItems": [
+		{"id": "a", "status": "published", "created": 1, "name": "Server", "elements": [{"id": "e1", "type": "rectangle", "x": 0, "y": 0, "width": 100, "height": 50, "seed": 1}]},
+		{"id": "b", "status": "unpublished", "created": 2, "elements": [{"id": "e2", "type": "ellipse", "x": 0, "y": 0, "width": 80, "height": 80, "seed": 2}]},
+		{"id": "c", "status": "unpublished", "created": 3, "elements": [{"id": "e3", "type": "rectangle", "x": 0, "y": 0, "width": 100, "height": 50, "seed": 99}]}
+	]
+}`
+
+func TestLibraryHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID := primitive.NewObjectID()
+	mockLibraryRepo := repository.NewMockLibraryRepository()
+	libraryHandler := NewLibraryHandler(mockLibraryRepo)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", userID.Hex())
+		c.Next()
+	})
+	r.POST("/libraries", libraryHandler.CreateLibrary)
+	r.GET("/libraries/:id", libraryHandler.GetLibraryByID)
+	r.POST("/libraries/:id/items", libraryHandler.AddLibraryItem)
+	r.DELETE("/libraries/:id/items/:itemId", libraryHandler.DeleteLibraryItem)
+	r.POST("/libraries/:id/import", libraryHandler.ImportLibrary)
+	r.GET("/libraries/:id/export", libraryHandler.ExportLibrary)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodPost, "/libraries", `{"name": "Team shapes"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var library models.Library
+	json.Unmarshal(w.Body.Bytes(), &library)
+	base := "/libraries/" + library.ID.Hex()
+
+	t.Run("Import De-duplicates Items", func(t *testing.T) {
+		w := serve(http.MethodPost, base+"/import", testLibraryFile)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"added": 2, "skipped": 1}`, w.Body.String())
+
+		w = serve(http.MethodPost, base+"/import", testLibraryFile)
+		assert.JSONEq(t, `{"added": 0, "skipped": 3}`, w.Body.String())
+	})
+
+	t.Run("Import Rejects Other Formats", func(t *testing.T) {
+		w := serve(http.MethodPost, base+"/import", `{"type": "excalidrawlib", "version": 1, "library": []}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Add Item", func(t *testing.T) {
+		w := serve(http.MethodPost, base+"/items", `{"name": "Diamond", "elements": [{"id": "x", "type": "diamond", "width": 10, "height": 10}]}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		w = serve(http.MethodPost, base+"/items", `{"elements": [{"id": "y", "type": "diamond", "width": 10, "height": 10}]}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Delete Item", func(t *testing.T) {
+		w := serve(http.MethodDelete, base+"/items/b", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = serve(http.MethodDelete, base+"/items/b", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		w := serve(http.MethodGet, base+"/export", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Disposition"), ".excalidrawlib")
+
+		var file ExcalidrawLibFile
+		json.Unmarshal(w.Body.Bytes(), &file)
+		assert.Equal(t, "excalidrawlib", file.Type)
+		assert.Equal(t, 2, file.Version)
+		assert.Len(t, file.LibraryItems, 2)
+		assert.Equal(t, "a", file.LibraryItems[0].ID)
+		assert.Equal(t, "Server", file.LibraryItems[0].Name)
+	})
+
+	t.Run("Import Renames Clashing IDs", func(t *testing.T) {
+		w := serve(http.MethodPost, base+"/import", `{"type": "excalidrawlib", "version": 2, "libraryItems": [
+			{"id": "a", "status": "unpublished", "created": 4, "elements": [{"id": "e4", "type": "line", "x": 0, "y": 0, "width": 30, "height": 0}]}
+		]}`)
+		assert.JSONEq(t, `{"added": 1, "skipped": 0}`, w.Body.String())
+
+		items := mockLibraryRepo.Libraries[library.ID].Items
+		assert.Len(t, items, 3)
+		assert.Equal(t, "a", items[0].ID)
+		assert.NotEqual(t, "a", items[2].ID)
+
+		w = serve(http.MethodDelete, base+"/items/a", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, mockLibraryRepo.Libraries[library.ID].Items, 2)
+	})
+
+	t.Run("Other User's Library", func(t *testing.T) {
+		mockLibraryRepo.Libraries[library.ID].UserID = primitive.NewObjectID()
+		defer func() { mockLibraryRepo.Libraries[library.ID].UserID = userID }()
+
+		w := serve(http.MethodGet, base, "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/oidc"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// oidcLoginTTL is how long the user has to complete a login at the IdP.
+const oidcLoginTTL = 10 * time.Minute
+
+var errIdentityEmailNotVerified = errors.New("identity provider did not return a verified email")
+
+// errAccountEmailNotVerified is returned when an identity's email belongs to
+// an account that never verified it. Whoever registered it may not own the
+// address, so linking would hand them the real owner's logins.
+var errAccountEmailNotVerified = errors.New("the account with this email has not verified it")
+
+type OIDCHandler struct {
+	UserRepo  repository.UserRepository
+	StateRepo repository.OIDCStateRepository
+	Tokens    *auth.TokenService
+	TwoFactor *TwoFactorHandler
+	// Registration decides whether a first login may create an account.
+	Registration *Registration
+	Providers    map[string]*oidc.Provider
+	AppBaseURL   string
+}
+
+func NewOIDCHandler(userRepo repository.UserRepository, stateRepo repository.OIDCStateRepository, tokens *auth.TokenService, twoFactor *TwoFactorHandler, registration *Registration, providers []*oidc.Provider, appBaseURL string) *OIDCHandler {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Config.Name] = p
+	}
+	return &OIDCHandler{
+		UserRepo:     userRepo,
+		StateRepo:    stateRepo,
+		Tokens:       tokens,
+		TwoFactor:    twoFactor,
+		Registration: registration,
+		Providers:    byName,
+		AppBaseURL:   appBaseURL,
+	}
+}
+
+type OIDCProviderResponse struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// GetProviders lists the configured identity providers for the login page.
+func (h *OIDCHandler) GetProviders(c *gin.Context) {
+	resp := make([]OIDCProviderResponse, 0, len(h.Providers))
+	for _, p := range h.Providers {
+		resp = append(resp, OIDCProviderResponse{Name: p.Config.Name, DisplayName: p.Config.DisplayName})
+	}
+	sort.Slice(resp, func(i, j int) bool { return resp[i].Name < resp[j].Name })
+	c.JSON(http.StatusOK, resp)
+}
+
+// Login redirects the browser to the identity provider. An optional
+// invitation query parameter is kept for the callback, in case the login
+// creates an account in invite-only mode.
+func (h *OIDCHandler) Login(c *gin.Context) {
+	provider, ok := h.Providers[c.Param("provider")]
+	if !ok {
+		NotFound(c, "Identity provider not found")
+		return
+	}
+
+	var secrets [3]string
+	for i := range secrets {
+		secret, err := auth.GenerateToken()
+		if err != nil {
+			InternalServerError(c, err)
+			return
+		}
+		secrets[i] = secret
+	}
+	rawState, nonce, codeVerifier := secrets[0], secrets[1], secrets[2]
+
+	redirectURL, err := provider.AuthCodeURL(c.Request.Context(), rawState, nonce, codeVerifier)
+	if err != nil {
+		log.Printf("OIDC login with %s failed: %v", provider.Config.Name, err)
+		HandleError(c, http.StatusBadGateway, problem.IdentityProviderUnavailable, "Identity provider is unavailable")
+		return
+	}
+
+	now := time.Now().UTC()
+	state := &models.OIDCLoginState{
+		ID:           primitive.NewObjectID(),
+		Provider:     provider.Config.Name,
+		StateHash:    auth.HashToken(rawState),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		Invitation:   c.Query("invitation"),
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(oidcLoginTTL),
+	}
+	if err := h.StateRepo.Create(c.Request.Context(), state); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// Callback completes a login started by Login and hands the tokens to the
+// frontend in the URL fragment, which is never sent to a server. Users with
+// 2FA enabled get a challenge token for TwoFactorHandler.VerifyLogin instead,
+// as after a password login.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	provider, ok := h.Providers[c.Param("provider")]
+	if !ok {
+		NotFound(c, "Identity provider not found")
+		return
+	}
+
+	if idpErr := c.Query("error"); idpErr != "" {
+		h.redirectToApp(c, url.Values{"error": {idpErr}})
+		return
+	}
+
+	state, err := h.StateRepo.Consume(c.Request.Context(), auth.HashToken(c.Query("state")))
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if state == nil || state.Provider != provider.Config.Name || time.Now().After(state.ExpiresAt) {
+		h.redirectToApp(c, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	claims, err := provider.Exchange(c.Request.Context(), c.Query("code"), state.CodeVerifier, state.Nonce)
+	if err != nil {
+		log.Printf("OIDC callback from %s failed: %v", provider.Config.Name, err)
+		h.redirectToApp(c, url.Values{"error": {"login_failed"}})
+		return
+	}
+
+	user, err := h.findOrCreateUser(c.Request.Context(), provider.Config.Name, claims, state.Invitation)
+	if err != nil {
+		if errors.Is(err, errIdentityEmailNotVerified) {
+			h.redirectToApp(c, url.Values{"error": {"email_not_verified"}})
+			return
+		}
+		if errors.Is(err, errAccountEmailNotVerified) {
+			h.redirectToApp(c, url.Values{"error": {"account_not_verified"}})
+			return
+		}
+		var refused registrationRefused
+		if errors.As(err, &refused) {
+			h.redirectToApp(c, url.Values{"error": {string(refused.code())}})
+			return
+		}
+		RepositoryError(c, err)
+		return
+	}
+	if user.DisabledAt != nil {
+		h.redirectToApp(c, url.Values{"error": {"account_disabled"}})
+		return
+	}
+
+	if user.TwoFactor != nil && user.TwoFactor.Enabled && h.TwoFactor != nil {
+		challenge, err := h.TwoFactor.StartChallenge(c.Request.Context(), user)
+		if err != nil {
+			RepositoryError(c, err)
+			return
+		}
+		h.redirectToApp(c, url.Values{"twoFactorRequired": {"true"}, "challengeToken": {challenge}})
+		return
+	}
+
+	device := auth.DeviceInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+	tokens, err := h.Tokens.IssueTokens(c.Request.Context(), user.ID, device)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	h.redirectToApp(c, url.Values{
+		"token":        {tokens.AccessToken},
+		"refreshToken": {tokens.RefreshToken},
+		"expiresAt":    {tokens.ExpiresAt.UTC().Format(time.RFC3339)},
+	})
+}
+
+// findOrCreateUser returns the user linked to the identity. An identity
+// seen for the first time is linked to the account with the same email, but
+// only if both the IdP and the account have verified that email; otherwise
+// anyone able to register the address at the IdP, or here, could take over
+// the other side. Creating a new account is subject to the registration
+// mode, like Register.
+func (h *OIDCHandler) findOrCreateUser(ctx context.Context, providerName string, claims *oidc.IDTokenClaims, invitationCode string) (*models.User, error) {
+	user, err := h.UserRepo.FindByIdentity(ctx, providerName, claims.Subject)
+	if err != nil || user != nil {
+		return user, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errIdentityEmailNotVerified
+	}
+	identity := models.UserIdentity{Provider: providerName, Subject: claims.Subject}
+
+	user, err = h.UserRepo.FindByEmail(ctx, claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		if !user.EmailVerified {
+			return nil, errAccountEmailNotVerified
+		}
+		if err := h.UserRepo.AddIdentity(ctx, user.ID, identity); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	// SSO-only accounts have no password, so password login always fails
+	// until the user sets one through the reset flow.
+	user = &models.User{
+		ID:            primitive.NewObjectID(),
+		Email:         claims.Email,
+		EmailVerified: true,
+		Identities:    []models.UserIdentity{identity},
+	}
+	invitation, err := h.Registration.admit(ctx, user.Email, invitationCode, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.UserRepo.Create(ctx, user); err != nil {
+		h.Registration.release(ctx, invitation)
+		return nil, err
+	}
+	return user, nil
+}
+
+func (h *OIDCHandler) redirectToApp(c *gin.Context, fragment url.Values) {
+	target := strings.TrimRight(h.AppBaseURL, "/") + "/auth/callback#" + fragment.Encode()
+	c.Redirect(http.StatusFound, target)
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/oidc"
+	"github.com/drshn/excalidraw/Backend/internal/oidc/oidctest"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestOIDCHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	idp := oidctest.NewServer("excalidraw")
+	defer idp.Close()
+
+	mockUserRepo := repository.NewMockUserRepository()
+	provider := oidc.NewProvider(oidc.ProviderConfig{Name: "corp", DisplayName: "Corp SSO", Issuer: idp.Issuer(), ClientID: "excalidraw"}, "http://api.example.com/auth/oidc/corp/callback")
+	tokens := newTestTokenService()
+	twoFactor := NewTwoFactorHandler(mockUserRepo, repository.NewMockUserTokenRepository(), tokens, newTestPasswordHasher(), "Excalidraw")
+	handler := NewOIDCHandler(mockUserRepo, repository.NewMockOIDCStateRepository(), tokens, twoFactor, nil, []*oidc.Provider{provider}, "https://draw.example.com")
+
+	router := gin.Default()
+	router.GET("/auth/oidc/providers", handler.GetProviders)
+	router.GET("/auth/oidc/:provider/login", handler.Login)
+	router.GET("/auth/oidc/:provider/callback", handler.Callback)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	noRedirects := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	// signInWith runs the browser side of the flow, starting with the login
+	// query, and returns the fragment of the final redirect to the frontend.
+	signInWith := func(t *testing.T, user oidctest.User, query string) url.Values {
+		idp.SetUser(user)
+
+		w := get("/auth/oidc/corp/login" + query)
+		assert.Equal(t, http.StatusFound, w.Code)
+		authorizeURL, _ := url.Parse(w.Header().Get("Location"))
+		assert.Equal(t, "S256", authorizeURL.Query().Get("code_challenge_method"))
+		assert.NotEmpty(t, authorizeURL.Query().Get("nonce"))
+
+		resp, err := noRedirects.Get(authorizeURL.String())
+		assert.NoError(t, err)
+		resp.Body.Close()
+		callbackURL, _ := url.Parse(resp.Header.Get("Location"))
+
+		w = get("/auth/oidc/corp/callback?" + callbackURL.RawQuery)
+		assert.Equal(t, http.StatusFound, w.Code)
+		appURL, _ := url.Parse(w.Header().Get("Location"))
+		assert.Equal(t, "draw.example.com", appURL.Host)
+		assert.Equal(t, "/auth/callback", appURL.Path)
+		fragment, _ := url.ParseQuery(appURL.Fragment)
+		return fragment
+	}
+	signIn := func(t *testing.T, user oidctest.User) url.Values {
+		return signInWith(t, user, "")
+	}
+
+	t.Run("List Providers", func(t *testing.T) {
+		w := get("/auth/oidc/providers")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var providers []OIDCProviderResponse
+		json.Unmarshal(w.Body.Bytes(), &providers)
+		assert.Equal(t, []OIDCProviderResponse{{Name: "corp", DisplayName: "Corp SSO"}}, providers)
+	})
+
+	t.Run("Unknown Provider", func(t *testing.T) {
+		w := get("/auth/oidc/other/login")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("New User Is Created", func(t *testing.T) {
+		fragment := signIn(t, oidctest.User{Subject: "sub-1", Email: "new@example.com", EmailVerified: true})
+		assert.NotEmpty(t, fragment.Get("token"))
+		assert.NotEmpty(t, fragment.Get("refreshToken"))
+
+		user := mockUserRepo.Users["new@example.com"]
+		assert.NotNil(t, user)
+		assert.True(t, user.EmailVerified)
+		assert.Empty(t, user.Password)
+		assert.Equal(t, []models.UserIdentity{{Provider: "corp", Subject: "sub-1"}}, user.Identities)
+	})
+
+	t.Run("Existing User Is Linked By Verified Email", func(t *testing.T) {
+		existing := &models.User{ID: primitive.NewObjectID(), Email: "existing@example.com", EmailVerified: true, Password: "hash"}
+		mockUserRepo.Users[existing.Email] = existing
+
+		fragment := signIn(t, oidctest.User{Subject: "sub-2", Email: "existing@example.com", EmailVerified: true})
+		assert.NotEmpty(t, fragment.Get("token"))
+		assert.Equal(t, []models.UserIdentity{{Provider: "corp", Subject: "sub-2"}}, existing.Identities)
+
+		// Later logins match on the identity even if the email changed.
+		fragment = signIn(t, oidctest.User{Subject: "sub-2", Email: "renamed@example.com", EmailVerified: true})
+		assert.NotEmpty(t, fragment.Get("token"))
+		assert.Nil(t, mockUserRepo.Users["renamed@example.com"])
+	})
+
+	t.Run("Two-Factor Users Get A Challenge", func(t *testing.T) {
+		secured := &models.User{
+			ID:         primitive.NewObjectID(),
+			Email:      "secured@example.com",
+			Identities: []models.UserIdentity{{Provider: "corp", Subject: "sub-4"}},
+			TwoFactor:  &models.TwoFactor{Secret: "JBSWY3DPEHPK3PXP", Enabled: true},
+		}
+		mockUserRepo.Users[secured.Email] = secured
+
+		fragment := signIn(t, oidctest.User{Subject: "sub-4", Email: "secured@example.com", EmailVerified: true})
+		assert.Equal(t, "true", fragment.Get("twoFactorRequired"))
+		assert.NotEmpty(t, fragment.Get("challengeToken"))
+		assert.Empty(t, fragment.Get("token"))
+		assert.Empty(t, fragment.Get("refreshToken"))
+	})
+
+	t.Run("Unverified Account Is Not Linked", func(t *testing.T) {
+		// Someone registered the address without owning it and hopes the
+		// real owner's first SSO login lands in their account.
+		squatted := &models.User{ID: primitive.NewObjectID(), Email: "squatted@example.com", Password: "attacker-hash"}
+		mockUserRepo.Users[squatted.Email] = squatted
+
+		fragment := signIn(t, oidctest.User{Subject: "sub-9", Email: "squatted@example.com", EmailVerified: true})
+		assert.Equal(t, "account_not_verified", fragment.Get("error"))
+		assert.Empty(t, fragment.Get("token"))
+		assert.Empty(t, squatted.Identities)
+		assert.False(t, squatted.EmailVerified)
+	})
+
+	t.Run("Unverified Email Is Not Linked", func(t *testing.T) {
+		victim := &models.User{ID: primitive.NewObjectID(), Email: "victim@example.com", Password: "hash"}
+		mockUserRepo.Users[victim.Email] = victim
+
+		fragment := signIn(t, oidctest.User{Subject: "sub-3", Email: "victim@example.com", EmailVerified: false})
+		assert.Equal(t, "email_not_verified", fragment.Get("error"))
+		assert.Empty(t, fragment.Get("token"))
+		assert.Empty(t, victim.Identities)
+	})
+
+	t.Run("Registration Mode Applies To New Users", func(t *testing.T) {
+		mockInvitationRepo := repository.NewMockInvitationRepository()
+		invitations := NewInvitationHandler(mockInvitationRepo, mockUserRepo, false, "https://draw.example.com", time.Hour)
+		defer func() { handler.Registration = nil }()
+
+		t.Run("Disabled", func(t *testing.T) {
+			handler.Registration, _ = NewRegistration(RegistrationDisabled, "", false, invitations)
+
+			fragment := signIn(t, oidctest.User{Subject: "sub-5", Email: "refused@example.com", EmailVerified: true})
+			assert.Equal(t, "registration_disabled", fragment.Get("error"))
+			assert.Empty(t, fragment.Get("token"))
+			assert.Nil(t, mockUserRepo.Users["refused@example.com"])
+
+			// Existing accounts can still sign in.
+			fragment = signIn(t, oidctest.User{Subject: "sub-1", Email: "new@example.com", EmailVerified: true})
+			assert.NotEmpty(t, fragment.Get("token"))
+		})
+
+		t.Run("Domains", func(t *testing.T) {
+			handler.Registration, _ = NewRegistration(RegistrationDomains, "corp.example.com", true, invitations)
+
+			fragment := signIn(t, oidctest.User{Subject: "sub-6", Email: "outsider@example.com", EmailVerified: true})
+			assert.Equal(t, "email_domain_not_allowed", fragment.Get("error"))
+			assert.Nil(t, mockUserRepo.Users["outsider@example.com"])
+
+			fragment = signIn(t, oidctest.User{Subject: "sub-7", Email: "insider@corp.example.com", EmailVerified: true})
+			assert.NotEmpty(t, fragment.Get("token"))
+			assert.NotNil(t, mockUserRepo.Users["insider@corp.example.com"])
+		})
+
+		t.Run("Invite", func(t *testing.T) {
+			handler.Registration, _ = NewRegistration(RegistrationInvite, "", false, invitations)
+			invitation := &models.Invitation{ID: primitive.NewObjectID(), CodeHash: auth.HashToken("welcome"), ExpiresAt: time.Now().Add(time.Hour)}
+			mockInvitationRepo.Create(context.Background(), invitation)
+
+			fragment := signIn(t, oidctest.User{Subject: "sub-8", Email: "invited@example.com", EmailVerified: true})
+			assert.Equal(t, "invitation_required", fragment.Get("error"))
+			fragment = signInWith(t, oidctest.User{Subject: "sub-8", Email: "invited@example.com", EmailVerified: true}, "?invitation=wrong")
+			assert.Equal(t, "invalid_invitation", fragment.Get("error"))
+			assert.Nil(t, mockUserRepo.Users["invited@example.com"])
+
+			fragment = signInWith(t, oidctest.User{Subject: "sub-8", Email: "invited@example.com", EmailVerified: true}, "?invitation=welcome")
+			assert.NotEmpty(t, fragment.Get("token"))
+			user := mockUserRepo.Users["invited@example.com"]
+			assert.NotNil(t, user)
+			assert.NotNil(t, invitation.UsedAt)
+			assert.Equal(t, &user.ID, invitation.UsedBy)
+		})
+	})
+
+	t.Run("Unknown State Is Rejected", func(t *testing.T) {
+		w := get("/auth/oidc/corp/callback?code=abc&state=forged")
+		appURL, _ := url.Parse(w.Header().Get("Location"))
+		fragment, _ := url.ParseQuery(appURL.Fragment)
+		assert.Equal(t, "invalid_state", fragment.Get("error"))
+	})
+
+	t.Run("ID Token Validation", func(t *testing.T) {
+		claims := func(nonce, audience string, expiresAt time.Time) *oidc.IDTokenClaims {
+			return &oidc.IDTokenClaims{
+				RegisteredClaims: jwt.RegisteredClaims{
+					Issuer:    idp.Issuer(),
+					Subject:   "sub-1",
+					Audience:  jwt.ClaimStrings{audience},
+					ExpiresAt: jwt.NewNumericDate(expiresAt),
+				},
+				Nonce: nonce,
+			}
+		}
+		ctx := context.Background()
+		future := time.Now().Add(time.Minute)
+
+		_, err := provider.VerifyIDToken(ctx, idp.SignIDToken(claims("n", "excalidraw", future)), "n")
+		assert.NoError(t, err)
+		_, err = provider.VerifyIDToken(ctx, idp.SignIDToken(claims("other", "excalidraw", future)), "n")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+		_, err = provider.VerifyIDToken(ctx, idp.SignIDToken(claims("n", "someone-else", future)), "n")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+		_, err = provider.VerifyIDToken(ctx, idp.SignIDToken(claims("n", "excalidraw", time.Now().Add(-time.Minute))), "n")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+}
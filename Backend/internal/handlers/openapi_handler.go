@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/drshn/excalidraw/Backend/internal/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// APIInfo describes the API in its OpenAPI document.
+var APIInfo = openapi.Info{
+	Title:       "Excalidraw Backend API",
+	Description: "Storage, sharing and accounts for Excalidraw drawings. Errors are returned as RFC 7807 problem details; see /api/v1/problems for the error codes.",
+	Version:     "v1",
+}
+
+// OpenAPIHandler serves the OpenAPI document of the routes registered on the
+// router, and a page that renders it.
+type OpenAPIHandler struct {
+	// Routes lists the registered routes. It is called on the first request,
+	// once every route has been added.
+	Routes func() gin.RoutesInfo
+
+	once sync.Once
+	spec []byte
+	err  error
+}
+
+func NewOpenAPIHandler(routes func() gin.RoutesInfo) *OpenAPIHandler {
+	return &OpenAPIHandler{Routes: routes}
+}
+
+func (h *OpenAPIHandler) GetSpec(c *gin.Context) {
+	h.once.Do(func() {
+		h.spec, h.err = json.Marshal(openapi.Build(APIInfo, h.Routes(), APIRoutes))
+	})
+	if h.err != nil {
+		InternalServerError(c, h.err)
+		return
+	}
+	c.Data(http.StatusOK, gin.MIMEJSON, h.spec)
+}
+
+func (h *OpenAPIHandler) GetDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", openapi.DocsPage)
+}
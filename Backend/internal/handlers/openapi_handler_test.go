@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drshn/excalidraw/Backend/internal/openapi"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPIHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	openAPIHandler := NewOpenAPIHandler(r.Routes)
+	r.GET("/api/v1/openapi.json", openAPIHandler.GetSpec)
+	r.GET("/api/v1/docs", openAPIHandler.GetDocs)
+	// Registered after the handler is created, as in main.
+	r.PATCH("/api/v1/drawings/:id", func(c *gin.Context) {})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var doc openapi.Document
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, APIInfo.Title, doc.Info.Title)
+	assert.Len(t, doc.Paths, 3)
+	patch := doc.Paths["/api/v1/drawings/{id}"]["patch"]
+	assert.Equal(t, "#/components/schemas/PatchDrawingRequest", patch.RequestBody.Content[MergePatchContentType].Schema.Ref)
+	assert.Contains(t, patch.Description, "`drawings:write`")
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/docs", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `fetch("openapi.json")`)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/mail"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/drshn/excalidraw/Backend/internal/ratelimit"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordResetHandler struct {
+	UserRepo      repository.UserRepository
+	UserTokenRepo repository.UserTokenRepository
+	Tokens        *auth.TokenService
+	Passwords     *auth.PasswordHasher
+	Policy        *auth.PasswordPolicy
+	Mailer        mail.Mailer
+	Limiter       *ratelimit.Limiter
+	IPLimiter     *ratelimit.Limiter
+	AppBaseURL    string
+	TokenTTL      time.Duration
+}
+
+func NewPasswordResetHandler(userRepo repository.UserRepository, userTokenRepo repository.UserTokenRepository, tokens *auth.TokenService, passwords *auth.PasswordHasher, policy *auth.PasswordPolicy, mailer mail.Mailer, appBaseURL string, tokenTTL time.Duration) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		UserRepo:      userRepo,
+		UserTokenRepo: userTokenRepo,
+		Tokens:        tokens,
+		Passwords:     passwords,
+		Policy:        policy,
+		Mailer:        mailer,
+		// At most 3 reset emails per address every 15 minutes.
+		Limiter: ratelimit.NewLimiter(3, 15*time.Minute),
+		// Callers need no account, so also cap how many addresses one
+		// client can have mailed.
+		IPLimiter:  ratelimit.NewLimiter(20, 15*time.Minute),
+		AppBaseURL: appBaseURL,
+		TokenTTL:   tokenTTL,
+	}
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ForgotPassword emails a reset link if an account exists. The response is
+// the same either way so it cannot be used to discover accounts.
+func (h *PasswordResetHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	if ok, retryAfter := h.IPLimiter.Allow(c.ClientIP()); !ok {
+		TooManyRequests(c, retryAfter)
+		return
+	}
+	if ok, retryAfter := h.Limiter.Allow(strings.ToLower(req.Email)); !ok {
+		TooManyRequests(c, retryAfter)
+		return
+	}
+
+	user, err := h.UserRepo.FindByEmail(c.Request.Context(), req.Email)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	if user != nil {
+		if err := h.SendReset(c.Request.Context(), user); err != nil {
+			RepositoryError(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If an account exists for this email, a reset link has been sent"})
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ResetPassword sets a new password using a reset token and signs the user
+// out of every session.
+func (h *PasswordResetHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	// Check the password before using up the token, so a rejected password
+	// can be retried with the same link.
+	token, err := findUserToken(c.Request.Context(), h.UserTokenRepo, models.TokenPurposePasswordReset, req.Token)
+	if err != nil {
+		if errors.Is(err, errInvalidUserToken) {
+			HandleError(c, http.StatusBadRequest, problem.InvalidEmailToken, "Invalid or expired token")
+			return
+		}
+		RepositoryError(c, err)
+		return
+	}
+	user, err := h.UserRepo.FindByID(c.Request.Context(), token.UserID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if user == nil {
+		HandleError(c, http.StatusBadRequest, problem.InvalidEmailToken, "Invalid or expired token")
+		return
+	}
+	if err := h.Policy.Check(req.Password, user.Email); err != nil {
+		if passwordRejected(c, "password", err) {
+			return
+		}
+		InternalServerError(c, err)
+		return
+	}
+
+	if _, err := consumeUserToken(c.Request.Context(), h.UserTokenRepo, models.TokenPurposePasswordReset, req.Token); err != nil {
+		if errors.Is(err, errInvalidUserToken) {
+			HandleError(c, http.StatusBadRequest, problem.InvalidEmailToken, "Invalid or expired token")
+			return
+		}
+		RepositoryError(c, err)
+		return
+	}
+
+	if err := h.SetPassword(c.Request.Context(), user, req.Password); err != nil {
+		if passwordRejected(c, "password", err) {
+			return
+		}
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset; please log in again"})
+}
+
+// SetPassword replaces the user's password, invalidates outstanding reset
+// links and signs the user out of every session. It returns a
+// *auth.PasswordPolicyError if the password is not allowed.
+func (h *PasswordResetHandler) SetPassword(ctx context.Context, user *models.User, password string) error {
+	if err := h.Policy.Check(password, user.Email); err != nil {
+		return err
+	}
+	hashedPassword, err := h.Passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+	if err := h.UserRepo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return err
+	}
+	if err := h.UserTokenRepo.DeleteByUserID(ctx, user.ID, models.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+	return h.Tokens.RevokeAllSessions(ctx, user.ID)
+}
+
+// SendReset emails the user a reset link.
+func (h *PasswordResetHandler) SendReset(ctx context.Context, user *models.User) error {
+	link, err := h.ResetLink(ctx, user)
+	if err != nil {
+		return err
+	}
+	sendMailAsync(h.Mailer, h.resetEmail(user.Email, link))
+	return nil
+}
+
+// ResetLink issues a reset token for the user and returns the link to use
+// it.
+func (h *PasswordResetHandler) ResetLink(ctx context.Context, user *models.User) (string, error) {
+	rawToken, err := issueUserToken(ctx, h.UserTokenRepo, user.ID, models.TokenPurposePasswordReset, "", h.TokenTTL)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(h.AppBaseURL, "/") + "/reset-password?token=" + url.QueryEscape(rawToken), nil
+}
+
+func (h *PasswordResetHandler) resetEmail(email, link string) mail.Message {
+	return mail.Message{
+		To:      email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your account.\n\n"+
+			"Open this link to choose a new password:\n%s\n\n"+
+			"The link expires in %s. If you did not ask for this, you can ignore this email.\n",
+			link, h.TokenTTL),
+	}
+}
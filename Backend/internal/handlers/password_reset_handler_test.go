@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/mail"
+	"github.com/drshn/excalidraw/Backend/internal/ratelimit"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingMailer delivers messages to a channel.
+type recordingMailer struct {
+	sent chan mail.Message
+}
+
+func newRecordingMailer() *recordingMailer {
+	return &recordingMailer{sent: make(chan mail.Message, 10)}
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.sent <- msg
+	return nil
+}
+
+func (m *recordingMailer) next(t *testing.T) mail.Message {
+	select {
+	case msg := <-m.sent:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no email was sent")
+		return mail.Message{}
+	}
+}
+
+var tokenInLink = regexp.MustCompile(`token=([^\s]+)`)
+
+func tokenFromEmail(t *testing.T, msg mail.Message) string {
+	match := tokenInLink.FindStringSubmatch(msg.Body)
+	if match == nil {
+		t.Fatalf("no token in email body: %s", msg.Body)
+	}
+	token, _ := url.QueryUnescape(match[1])
+	return token
+}
+
+func TestPasswordResetHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserRepo := repository.NewMockUserRepository()
+	tokens := newTestTokenService()
+	mailer := newRecordingMailer()
+	authHandler := NewAuthHandler(mockUserRepo, tokens, newTestPasswordHasher(), newTestPasswordPolicy(), nil, nil, nil)
+	resetHandler := NewPasswordResetHandler(mockUserRepo, repository.NewMockUserTokenRepository(), tokens, newTestPasswordHasher(), newTestPasswordPolicy(), mailer, "https://draw.example.com", time.Hour)
+
+	router := gin.Default()
+	router.POST("/register", authHandler.Register)
+	router.POST("/login", authHandler.Login)
+	router.POST("/refresh", authHandler.Refresh)
+	router.POST("/password/forgot", resetHandler.ForgotPassword)
+	router.POST("/password/reset", resetHandler.ResetPassword)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	post("/register", `{"email": "reset@example.com", "password": "password123"}`)
+	w := post("/login", `{"email": "reset@example.com", "password": "password123"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	t.Run("Unknown Email Looks The Same", func(t *testing.T) {
+		w := post("/password/forgot", `{"email": "nobody@example.com"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, mailer.sent)
+	})
+
+	t.Run("Reset Flow", func(t *testing.T) {
+		w := post("/password/forgot", `{"email": "reset@example.com"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		msg := mailer.next(t)
+		assert.Equal(t, "reset@example.com", msg.To)
+		assert.Contains(t, msg.Body, "https://draw.example.com/reset-password?token=")
+		token := tokenFromEmail(t, msg)
+
+		w = post("/password/reset", `{"token": "`+token+`", "password": "reset-me-123"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "must not contain your email address")
+
+		w = post("/password/reset", `{"token": "`+token+`", "password": "newpassword456"}`)
+		assert.Equal(t, http.StatusOK, w.Code, "a rejected password does not use up the token")
+
+		user := mockUserRepo.Users["reset@example.com"]
+		match, _, _ := resetHandler.Passwords.Verify("newpassword456", user.Password)
+		assert.True(t, match)
+
+		sessions, _ := tokens.ListSessions(context.Background(), user.ID)
+		assert.Empty(t, sessions, "existing sessions are revoked")
+
+		w = post("/password/reset", `{"token": "`+token+`", "password": "anotherpassword"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "reset tokens are single-use")
+	})
+
+	t.Run("New Request Invalidates Old Token", func(t *testing.T) {
+		post("/password/forgot", `{"email": "reset@example.com"}`)
+		first := tokenFromEmail(t, mailer.next(t))
+		post("/password/forgot", `{"email": "reset@example.com"}`)
+		second := tokenFromEmail(t, mailer.next(t))
+
+		w := post("/password/reset", `{"token": "`+first+`", "password": "newpassword789"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = post("/password/reset", `{"token": "`+second+`", "password": "newpassword789"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Rate Limited Per Email", func(t *testing.T) {
+		w := post("/password/forgot", `{"email": "reset@example.com"}`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+
+	t.Run("Rate Limited Per IP", func(t *testing.T) {
+		resetHandler.IPLimiter = ratelimit.NewLimiter(2, time.Minute)
+
+		for _, email := range []string{"one@example.com", "two@example.com"} {
+			w := post("/password/forgot", `{"email": "`+email+`"}`)
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+		w := post("/password/forgot", `{"email": "three@example.com"}`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+}
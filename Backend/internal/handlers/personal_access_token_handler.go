@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PersonalAccessTokenHandler struct {
+	Tokens *auth.PersonalAccessTokenService
+}
+
+func NewPersonalAccessTokenHandler(tokens *auth.PersonalAccessTokenService) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{Tokens: tokens}
+}
+
+type CreatePersonalAccessTokenRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=drawings:read drawings:write"`
+	// ExpiresInDays is optional; tokens without it never expire.
+	ExpiresInDays int `json:"expiresInDays" binding:"omitempty,min=1,max=3650"`
+}
+
+// CreatePersonalAccessTokenResponse includes the raw token, which cannot be
+// retrieved again.
+type CreatePersonalAccessTokenResponse struct {
+	*models.PersonalAccessToken
+	Token string `json:"token"`
+}
+
+func (h *PersonalAccessTokenHandler) CreateToken(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	var req CreatePersonalAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().UTC().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	raw, token, err := h.Tokens.Create(c.Request.Context(), userID, req.Name, req.Scopes, expiresAt)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreatePersonalAccessTokenResponse{PersonalAccessToken: token, Token: raw})
+}
+
+func (h *PersonalAccessTokenHandler) GetTokens(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	tokens, err := h.Tokens.Repo.FindAllByUserID(c.Request.Context(), userID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *PersonalAccessTokenHandler) DeleteToken(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	if err := h.Tokens.Repo.Delete(c.Request.Context(), id, userID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked successfully"})
+}
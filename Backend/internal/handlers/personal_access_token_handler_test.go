@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/middleware"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPersonalAccessTokenHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID := primitive.NewObjectID()
+	tokenRepo := repository.NewMockPersonalAccessTokenRepository()
+	userRepo := repository.NewMockUserRepository()
+	userRepo.Create(context.Background(), &models.User{ID: userID, Email: "ci@example.com"})
+	service := auth.NewPersonalAccessTokenService(tokenRepo, userRepo)
+	handler := NewPersonalAccessTokenHandler(service)
+
+	tokens := newTestTokenService()
+	authMiddleware := middleware.AuthMiddleware(tokens, auth.NewSessionTracker(tokens.SessionRepo, time.Minute), service)
+	drawingScopes := middleware.AllowScopes(models.ScopeDrawingsRead, models.ScopeDrawingsWrite)
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"userID": c.GetString("userID")}) }
+
+	router := gin.Default()
+	manage := router.Group("/tokens", func(c *gin.Context) { c.Set("userID", userID.Hex()) })
+	manage.POST("", handler.CreateToken)
+	manage.GET("", handler.GetTokens)
+	manage.DELETE("/:id", handler.DeleteToken)
+	router.GET("/drawings", drawingScopes, authMiddleware, ok)
+	router.POST("/drawings", drawingScopes, authMiddleware, ok)
+	router.GET("/sessions", authMiddleware, ok)
+
+	do := func(method, path, body, bearer string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	create := func(body string) CreatePersonalAccessTokenResponse {
+		w := do(http.MethodPost, "/tokens", body, "")
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var resp CreatePersonalAccessTokenResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	t.Run("Create Validates Scopes", func(t *testing.T) {
+		w := do(http.MethodPost, "/tokens", `{"name": "ci", "scopes": ["admin"]}`, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = do(http.MethodPost, "/tokens", `{"name": "ci", "scopes": []}`, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Scopes Are Enforced", func(t *testing.T) {
+		readOnly := create(`{"name": "reader", "scopes": ["drawings:read"]}`)
+		assert.Contains(t, readOnly.Token, auth.PersonalAccessTokenPrefix)
+		assert.Nil(t, readOnly.ExpiresAt)
+
+		w := do(http.MethodGet, "/drawings", "", readOnly.Token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), userID.Hex())
+		assert.NotNil(t, tokenRepo.Tokens[readOnly.ID].LastUsedAt)
+
+		w = do(http.MethodPost, "/drawings", "", readOnly.Token)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		writer := create(`{"name": "ci", "scopes": ["drawings:read", "drawings:write"], "expiresInDays": 30}`)
+		assert.NotNil(t, writer.ExpiresAt)
+		w = do(http.MethodPost, "/drawings", "", writer.Token)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Routes Without Scopes Require A Session", func(t *testing.T) {
+		token := create(`{"name": "ci", "scopes": ["drawings:read", "drawings:write"]}`)
+		w := do(http.MethodGet, "/sessions", "", token.Token)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Expired And Unknown Tokens", func(t *testing.T) {
+		token := create(`{"name": "old", "scopes": ["drawings:read"], "expiresInDays": 1}`)
+		past := time.Now().Add(-time.Minute)
+		tokenRepo.Tokens[token.ID].ExpiresAt = &past
+
+		w := do(http.MethodGet, "/drawings", "", token.Token)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = do(http.MethodGet, "/drawings", "", auth.PersonalAccessTokenPrefix+"unknown")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Disabled Users", func(t *testing.T) {
+		token := create(`{"name": "ci", "scopes": ["drawings:read"]}`)
+		disabledAt := time.Now()
+		userRepo.SetDisabled(context.Background(), userID, &disabledAt)
+		defer userRepo.SetDisabled(context.Background(), userID, nil)
+
+		w := do(http.MethodGet, "/drawings", "", token.Token)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("List And Revoke", func(t *testing.T) {
+		token := create(`{"name": "revoke-me", "scopes": ["drawings:read"]}`)
+
+		w := do(http.MethodGet, "/tokens", "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "revoke-me")
+		assert.NotContains(t, w.Body.String(), token.Token)
+		assert.NotContains(t, w.Body.String(), "tokenHash")
+
+		w = do(http.MethodDelete, "/tokens/"+token.ID.Hex(), "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = do(http.MethodGet, "/drawings", "", token.Token)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = do(http.MethodDelete, "/tokens/"+token.ID.Hex(), "", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemHandler serves the catalogue of error codes, which problem type
+// URIs point to.
+type ProblemHandler struct{}
+
+func NewProblemHandler() *ProblemHandler {
+	return &ProblemHandler{}
+}
+
+// GetProblems lists every error code the API returns.
+func (h *ProblemHandler) GetProblems(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, problem.Catalogue)
+}
+
+// GetProblem describes one error code.
+func (h *ProblemHandler) GetProblem(c *gin.Context) {
+	entry, ok := problem.Lookup(problem.Code(c.Param("code")))
+	if !ok {
+		NotFound(c, "Unknown error code")
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, entry)
+}
+
+// NoRoute responds with not_found to requests that match no route.
+func (h *ProblemHandler) NoRoute(c *gin.Context) {
+	NotFound(c, "No route matches "+c.Request.Method+" "+c.Request.URL.Path)
+}
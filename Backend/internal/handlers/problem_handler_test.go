@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tokens := newTestTokenService()
+	problemHandler := NewProblemHandler()
+	router := gin.Default()
+	router.NoRoute(problemHandler.NoRoute)
+	router.GET("/problems", problemHandler.GetProblems)
+	router.GET("/problems/:code", problemHandler.GetProblem)
+	router.GET("/private", newTestAuthMiddleware(tokens), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	get := func(path string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	t.Run("Problem Types Resolve To The Catalogue", func(t *testing.T) {
+		w, resp := get("/private")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+		assert.Equal(t, "authentication_required", resp["code"])
+		assert.Equal(t, "/api/v1/problems/authentication_required", resp["type"])
+
+		w, resp = get("/problems/authentication_required")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Authentication required", resp["title"])
+
+		w, resp = get("/problems/no_such_code")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "not_found", resp["code"])
+	})
+
+	t.Run("List", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/problems", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var list []problem.Entry
+		json.Unmarshal(w.Body.Bytes(), &list)
+		assert.Equal(t, problem.Catalogue, list)
+	})
+
+	t.Run("Unknown Route", func(t *testing.T) {
+		w, resp := get("/nowhere")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "No route matches GET /nowhere", resp["detail"])
+	})
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/drshn/excalidraw/Backend/internal/templates"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TemplateHandler struct {
+	DrawingRepo repository.DrawingRepository
+}
+
+func NewTemplateHandler(drawingRepo repository.DrawingRepository) *TemplateHandler {
+	return &TemplateHandler{DrawingRepo: drawingRepo}
+}
+
+// GetTemplates lists the built-in templates followed by the user's own,
+// optionally filtered by ?category=.
+func (h *TemplateHandler) GetTemplates(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	drawings, err := h.DrawingRepo.FindTemplatesByUserID(c.Request.Context(), userID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	category := c.Query("category")
+	list := []templates.Template{}
+	for _, t := range templates.BuiltIn() {
+		if category == "" || t.Category == category {
+			list = append(list, t)
+		}
+	}
+	for _, d := range drawings {
+		if category == "" || d.Template.Category == category {
+			list = append(list, templates.Template{
+				ID:        d.ID.Hex(),
+				Name:      d.Title,
+				Category:  d.Template.Category,
+				Thumbnail: d.Template.Thumbnail,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+type SetTemplateRequest struct {
+	Category  string `json:"category" binding:"required,max=64"`
+	Thumbnail string `json:"thumbnail" binding:"omitempty,startswith=data:image/,max=262144"`
+}
+
+func (h *TemplateHandler) SetDrawingTemplate(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	drawingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	var req SetTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	info := &models.TemplateInfo{Category: req.Category, Thumbnail: req.Thumbnail}
+	if err := h.DrawingRepo.SetTemplate(c.Request.Context(), drawingID, userID, info); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Drawing marked as template"})
+}
+
+func (h *TemplateHandler) UnsetDrawingTemplate(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	drawingID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	if err := h.DrawingRepo.SetTemplate(c.Request.Context(), drawingID, userID, nil); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Drawing is no longer a template"})
+}
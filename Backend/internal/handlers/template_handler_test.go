@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/drshn/excalidraw/Backend/internal/templates"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTemplateHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID := primitive.NewObjectID()
+	mockDrawingRepo := repository.NewMockDrawingRepository()
+	drawing := &models.Drawing{ID: primitive.NewObjectID(), UserID: userID, Title: "Retro board", SceneData: `{"elements":[]}`}
+	mockDrawingRepo.Create(nil, drawing)
+
+	drawingHandler := NewDrawingHandler(mockDrawingRepo, repository.NewMockCommentRepository())
+	templateHandler := NewTemplateHandler(mockDrawingRepo)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", userID.Hex())
+		c.Next()
+	})
+	r.POST("/drawings", drawingHandler.CreateDrawing)
+	r.PUT("/drawings/:id/template", templateHandler.SetDrawingTemplate)
+	r.DELETE("/drawings/:id/template", templateHandler.UnsetDrawingTemplate)
+	r.GET("/templates", templateHandler.GetTemplates)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	listTemplates := func(query string) []templates.Template {
+		w := serve(http.MethodGet, "/templates"+query, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var list []templates.Template
+		json.Unmarshal(w.Body.Bytes(), &list)
+		return list
+	}
+
+	t.Run("Create From Built-in Template", func(t *testing.T) {
+		w := serve(http.MethodPost, "/drawings?template=kanban", "")
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var created models.Drawing
+		json.Unmarshal(w.Body.Bytes(), &created)
+		kanban, _ := templates.FindBuiltIn("kanban")
+		assert.Equal(t, "Kanban board", created.Title)
+		assert.Equal(t, kanban.SceneData, created.SceneData)
+	})
+
+	t.Run("Unknown Template", func(t *testing.T) {
+		w := serve(http.MethodPost, "/drawings?template=nope", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = serve(http.MethodPost, "/drawings?template="+drawing.ID.Hex(), "")
+		assert.Equal(t, http.StatusNotFound, w.Code, "unmarked drawings are not templates")
+	})
+
+	t.Run("Mark Drawing As Template", func(t *testing.T) {
+		w := serve(http.MethodPut, "/drawings/"+drawing.ID.Hex()+"/template", `{"category": "meetings"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		list := listTemplates("?category=meetings")
+		assert.Len(t, list, 1)
+		assert.Equal(t, drawing.ID.Hex(), list[0].ID)
+		assert.False(t, list[0].BuiltIn)
+		assert.Len(t, listTemplates(""), len(templates.BuiltIn())+1)
+
+		w = serve(http.MethodPost, "/drawings?template="+drawing.ID.Hex(), `{"title": "Sprint 12 retro"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"title":"Sprint 12 retro"`)
+	})
+
+	t.Run("Invalid Thumbnail", func(t *testing.T) {
+		w := serve(http.MethodPut, "/drawings/"+drawing.ID.Hex()+"/template", `{"category": "meetings", "thumbnail": "https://example.com/x.png"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Unmark Template", func(t *testing.T) {
+		w := serve(http.MethodDelete, "/drawings/"+drawing.ID.Hex()+"/template", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, listTemplates("?category=meetings"))
+	})
+}
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/drshn/excalidraw/Backend/internal/ratelimit"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// twoFactorChallengeTTL is how long the user has to enter a code after
+	// their password was accepted.
+	twoFactorChallengeTTL = 5 * time.Minute
+	recoveryCodeCount     = 10
+)
+
+type TwoFactorHandler struct {
+	UserRepo      repository.UserRepository
+	UserTokenRepo repository.UserTokenRepository
+	Tokens        *auth.TokenService
+	Passwords     *auth.PasswordHasher
+	Limiter       *ratelimit.Limiter
+	Issuer        string
+}
+
+func NewTwoFactorHandler(userRepo repository.UserRepository, userTokenRepo repository.UserTokenRepository, tokens *auth.TokenService, passwords *auth.PasswordHasher, issuer string) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		UserRepo:      userRepo,
+		UserTokenRepo: userTokenRepo,
+		Tokens:        tokens,
+		Passwords:     passwords,
+		// At most 5 code attempts per user every 15 minutes; a 6-digit code
+		// cannot be guessed at that rate.
+		Limiter: ratelimit.NewLimiter(5, 15*time.Minute),
+		Issuer:  issuer,
+	}
+}
+
+// StartChallenge returns a challenge token that VerifyLogin exchanges,
+// together with a valid code, for a session.
+func (h *TwoFactorHandler) StartChallenge(ctx context.Context, user *models.User) (string, error) {
+	return issueUserToken(ctx, h.UserTokenRepo, user.ID, models.TokenPurposeTwoFactorLogin, "", twoFactorChallengeTTL)
+}
+
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+}
+
+// Setup starts enrollment with a new secret. 2FA is not enforced until the
+// user confirms a code from their authenticator app.
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if user.TwoFactor != nil && user.TwoFactor.Enabled {
+		HandleError(c, http.StatusConflict, problem.TwoFactorAlreadyEnabled, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+	if err := h.UserRepo.UpdateTwoFactor(c.Request.Context(), user.ID, &models.TwoFactor{Secret: secret}); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(h.Issuer, user.Email, secret),
+	})
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// Confirm enables 2FA once the user proves their app generates valid codes,
+// and returns the recovery codes, which are shown only this once.
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if user.TwoFactor == nil {
+		HandleError(c, http.StatusBadRequest, problem.TwoFactorSetupNotStarted, "Two-factor setup has not been started")
+		return
+	}
+	if user.TwoFactor.Enabled {
+		HandleError(c, http.StatusConflict, problem.TwoFactorAlreadyEnabled, "Two-factor authentication is already enabled")
+		return
+	}
+
+	step, valid := auth.ValidateTOTP(user.TwoFactor.Secret, req.Code, time.Now())
+	if !valid {
+		HandleError(c, http.StatusBadRequest, problem.InvalidTwoFactorCode, "Invalid code")
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+	now := time.Now().UTC()
+	twoFactor := &models.TwoFactor{
+		Secret:        user.TwoFactor.Secret,
+		Enabled:       true,
+		RecoveryCodes: hashes,
+		LastUsedStep:  step,
+		EnabledAt:     &now,
+	}
+	if err := h.UserRepo.UpdateTwoFactor(c.Request.Context(), user.ID, twoFactor); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactorRequest confirms turning 2FA off. Users who sign in only
+// through single sign-on have no password and send the code alone.
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// Disable turns 2FA off. It requires both the password, if the user has
+// one, and a current code (or recovery code), so a stolen session alone
+// cannot weaken the account.
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if user.TwoFactor == nil || !user.TwoFactor.Enabled {
+		HandleError(c, http.StatusBadRequest, problem.TwoFactorNotEnabled, "Two-factor authentication is not enabled")
+		return
+	}
+	// Limit before checking the password so a session cannot be used to
+	// guess it.
+	if !h.checkRateLimit(c, user) {
+		return
+	}
+	if user.Password != "" && !confirmPassword(c, h.Passwords, req.Password, user.Password) {
+		return
+	}
+
+	valid, err := h.verifyCode(c.Request.Context(), user, req.Code)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if !valid {
+		HandleError(c, http.StatusForbidden, problem.InvalidTwoFactorCode, "Invalid code")
+		return
+	}
+
+	if err := h.UserRepo.UpdateTwoFactor(c.Request.Context(), user.ID, nil); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes with new ones.
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if user.TwoFactor == nil || !user.TwoFactor.Enabled {
+		HandleError(c, http.StatusBadRequest, problem.TwoFactorNotEnabled, "Two-factor authentication is not enabled")
+		return
+	}
+	if !h.checkRateLimit(c, user) {
+		return
+	}
+
+	step, valid := auth.ValidateTOTP(user.TwoFactor.Secret, req.Code, time.Now())
+	if valid {
+		var err error
+		valid, err = h.UserRepo.UseTOTPStep(c.Request.Context(), user.ID, step)
+		if err != nil {
+			RepositoryError(c, err)
+			return
+		}
+	}
+	if !valid {
+		HandleError(c, http.StatusForbidden, problem.InvalidTwoFactorCode, "Invalid code")
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+	twoFactor := *user.TwoFactor
+	twoFactor.RecoveryCodes = hashes
+	twoFactor.LastUsedStep = step
+	if err := h.UserRepo.UpdateTwoFactor(c.Request.Context(), user.ID, &twoFactor); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+type VerifyTwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	// Code is a TOTP code or a recovery code.
+	Code string `json:"code" binding:"required"`
+}
+
+// VerifyLogin completes a login that Login answered with a challenge.
+func (h *TwoFactorHandler) VerifyLogin(c *gin.Context) {
+	var req VerifyTwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	challenge, err := h.UserTokenRepo.FindByHash(c.Request.Context(), models.TokenPurposeTwoFactorLogin, auth.HashToken(req.ChallengeToken))
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if challenge == nil || challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) {
+		HandleError(c, http.StatusUnauthorized, problem.InvalidChallenge, "Invalid or expired challenge, please log in again")
+		return
+	}
+
+	user, err := h.UserRepo.FindByID(c.Request.Context(), challenge.UserID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if user == nil || user.TwoFactor == nil || !user.TwoFactor.Enabled {
+		HandleError(c, http.StatusUnauthorized, problem.InvalidChallenge, "Invalid or expired challenge, please log in again")
+		return
+	}
+	if user.DisabledAt != nil {
+		HandleError(c, http.StatusForbidden, problem.AccountDisabled, accountDisabledMessage)
+		return
+	}
+	if !h.checkRateLimit(c, user) {
+		return
+	}
+
+	valid, err := h.verifyCode(c.Request.Context(), user, req.Code)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if !valid {
+		HandleError(c, http.StatusUnauthorized, problem.InvalidTwoFactorCode, "Invalid code")
+		return
+	}
+
+	consumed, err := h.UserTokenRepo.Consume(c.Request.Context(), challenge.ID)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if !consumed {
+		HandleError(c, http.StatusUnauthorized, problem.InvalidChallenge, "Invalid or expired challenge, please log in again")
+		return
+	}
+
+	device := auth.DeviceInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+	tokens, err := h.Tokens.IssueTokens(c.Request.Context(), user.ID, device)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	respondWithTokens(c, tokens)
+}
+
+// verifyCode accepts a TOTP code that has not been used before, or one of
+// the user's recovery codes, which is used up.
+func (h *TwoFactorHandler) verifyCode(ctx context.Context, user *models.User, code string) (bool, error) {
+	if step, ok := auth.ValidateTOTP(user.TwoFactor.Secret, code, time.Now()); ok {
+		return h.UserRepo.UseTOTPStep(ctx, user.ID, step)
+	}
+	return h.UserRepo.UseRecoveryCode(ctx, user.ID, auth.HashRecoveryCode(code))
+}
+
+func (h *TwoFactorHandler) checkRateLimit(c *gin.Context, user *models.User) bool {
+	if ok, retryAfter := h.Limiter.Allow(user.ID.Hex()); !ok {
+		TooManyRequests(c, retryAfter)
+		return false
+	}
+	return true
+}
+
+func (h *TwoFactorHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return nil, false
+	}
+	user, err := h.UserRepo.FindByID(c.Request.Context(), userID)
+	if err != nil {
+		RepositoryError(c, err)
+		return nil, false
+	}
+	if user == nil {
+		NotFound(c, "User not found")
+		return nil, false
+	}
+	return user, true
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
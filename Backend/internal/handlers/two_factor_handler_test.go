@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/ratelimit"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTwoFactorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserRepo := repository.NewMockUserRepository()
+	tokens := newTestTokenService()
+	twoFactor := NewTwoFactorHandler(mockUserRepo, repository.NewMockUserTokenRepository(), tokens, newTestPasswordHasher(), "Excalidraw")
+	twoFactor.Limiter = ratelimit.NewLimiter(100, time.Minute)
+	authHandler := NewAuthHandler(mockUserRepo, tokens, newTestPasswordHasher(), newTestPasswordPolicy(), nil, twoFactor, nil)
+	authMiddleware := newTestAuthMiddleware(tokens)
+
+	router := gin.Default()
+	router.POST("/register", authHandler.Register)
+	router.POST("/login", authHandler.Login)
+	router.POST("/2fa/setup", authMiddleware, twoFactor.Setup)
+	router.POST("/2fa/confirm", authMiddleware, twoFactor.Confirm)
+	router.POST("/2fa/disable", authMiddleware, twoFactor.Disable)
+	router.POST("/2fa/recovery-codes", authMiddleware, twoFactor.RegenerateRecoveryCodes)
+	router.POST("/2fa/verify", twoFactor.VerifyLogin)
+
+	post := func(path, body, token string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+	const credentials = `{"email": "2fa@example.com", "password": "password123"}`
+
+	post("/register", credentials, "")
+	_, login := post("/login", credentials, "")
+	accessToken := login["token"].(string)
+	user := mockUserRepo.Users["2fa@example.com"]
+
+	// A TOTP code can be used once per period, so each code below is
+	// generated for a later period than the one before it.
+	codeAt := func(offset time.Duration) string {
+		code, _ := auth.TOTPCode(user.TwoFactor.Secret, time.Now().Add(offset))
+		return code
+	}
+
+	var recoveryCodes []string
+	t.Run("Enroll", func(t *testing.T) {
+		w, resp := post("/2fa/setup", "", accessToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, resp["otpauthUri"], "otpauth://totp/Excalidraw:2fa@example.com?")
+		assert.False(t, user.TwoFactor.Enabled, "not enforced until confirmed")
+
+		w, _ = post("/2fa/confirm", `{"code": "000000"}`, accessToken)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, resp = post("/2fa/confirm", `{"code": "`+codeAt(-30*time.Second)+`"}`, accessToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, user.TwoFactor.Enabled)
+		for _, code := range resp["recoveryCodes"].([]interface{}) {
+			recoveryCodes = append(recoveryCodes, code.(string))
+		}
+		assert.Len(t, recoveryCodes, 10)
+		assert.NotContains(t, user.TwoFactor.RecoveryCodes, recoveryCodes[0], "only hashes are stored")
+
+		w, _ = post("/2fa/setup", "", accessToken)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Login Requires A Code", func(t *testing.T) {
+		w, resp := post("/login", credentials, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, true, resp["twoFactorRequired"])
+		assert.Nil(t, resp["token"])
+		challenge := resp["challengeToken"].(string)
+
+		w, _ = post("/2fa/verify", `{"challengeToken": "`+challenge+`", "code": "000000"}`, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		code := codeAt(0)
+		w, resp = post("/2fa/verify", `{"challengeToken": "`+challenge+`", "code": "`+code+`"}`, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, resp["token"])
+
+		w, _ = post("/2fa/verify", `{"challengeToken": "`+challenge+`", "code": "`+code+`"}`, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "challenges are single-use")
+
+		_, resp = post("/login", credentials, "")
+		w, _ = post("/2fa/verify", `{"challengeToken": "`+resp["challengeToken"].(string)+`", "code": "`+code+`"}`, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "codes cannot be replayed")
+	})
+
+	t.Run("Login With Recovery Code", func(t *testing.T) {
+		_, resp := post("/login", credentials, "")
+		challenge := resp["challengeToken"].(string)
+		w, resp := post("/2fa/verify", `{"challengeToken": "`+challenge+`", "code": "`+recoveryCodes[0]+`"}`, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, resp["token"])
+		assert.Len(t, user.TwoFactor.RecoveryCodes, 9)
+
+		_, resp = post("/login", credentials, "")
+		w, _ = post("/2fa/verify", `{"challengeToken": "`+resp["challengeToken"].(string)+`", "code": "`+recoveryCodes[0]+`"}`, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "recovery codes are single-use")
+	})
+
+	var newCodes []string
+	t.Run("Regenerate Recovery Codes", func(t *testing.T) {
+		w, resp := post("/2fa/recovery-codes", `{"code": "`+codeAt(30*time.Second)+`"}`, accessToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, resp["recoveryCodes"], 10)
+		assert.Len(t, user.TwoFactor.RecoveryCodes, 10)
+		newCodes = nil
+		for _, code := range resp["recoveryCodes"].([]interface{}) {
+			newCodes = append(newCodes, code.(string))
+		}
+	})
+
+	t.Run("Disable Is Rate Limited Before The Password", func(t *testing.T) {
+		twoFactor.Limiter = ratelimit.NewLimiter(1, time.Minute)
+		defer func() { twoFactor.Limiter = ratelimit.NewLimiter(100, time.Minute) }()
+
+		w, _ := post("/2fa/disable", `{"password": "wrong-password", "code": "000000"}`, accessToken)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = post("/2fa/disable", `{"password": "wrong-password", "code": "000000"}`, accessToken)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		w, _ := post("/2fa/disable", `{"password": "wrong-password", "code": "`+recoveryCodes[1]+`"}`, accessToken)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = post("/2fa/disable", `{"code": "`+newCodes[0]+`"}`, accessToken)
+		assert.Equal(t, http.StatusForbidden, w.Code, "users with a password must send it")
+		w, _ = post("/2fa/disable", `{"password": "password123", "code": "`+recoveryCodes[1]+`"}`, accessToken)
+		assert.Equal(t, http.StatusForbidden, w.Code, "old recovery codes were replaced")
+
+		w, _ = post("/2fa/disable", `{"password": "password123", "code": "`+newCodes[0]+`"}`, accessToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, user.TwoFactor)
+
+		_, resp := post("/login", credentials, "")
+		assert.NotEmpty(t, resp["token"])
+	})
+
+	t.Run("Disable Without A Password For Single Sign-On Users", func(t *testing.T) {
+		ssoUser := &models.User{
+			ID:         primitive.NewObjectID(),
+			Email:      "sso-2fa@example.com",
+			Identities: []models.UserIdentity{{Provider: "corp", Subject: "sub-1"}},
+			TwoFactor:  &models.TwoFactor{Secret: "JBSWY3DPEHPK3PXP", Enabled: true},
+		}
+		mockUserRepo.Users[ssoUser.Email] = ssoUser
+		pair, err := tokens.IssueTokens(context.Background(), ssoUser.ID, auth.DeviceInfo{})
+		if err != nil {
+			t.Fatalf("could not issue tokens: %v", err)
+		}
+
+		w, _ := post("/2fa/disable", `{"code": "000000"}`, pair.AccessToken)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		code, _ := auth.TOTPCode(ssoUser.TwoFactor.Secret, time.Now())
+		w, _ = post("/2fa/disable", `{"code": "`+code+`"}`, pair.AccessToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, ssoUser.TwoFactor)
+	})
+}
@@ -0,0 +1,451 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/mail"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/drshn/excalidraw/Backend/internal/ratelimit"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxAvatarSize is the largest avatar image accepted, in bytes.
+const maxAvatarSize = 512 << 10
+
+var avatarContentTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+type UserHandler struct {
+	UserRepo       repository.UserRepository
+	UserTokenRepo  repository.UserTokenRepository
+	Tokens         *auth.TokenService
+	Passwords      *auth.PasswordHasher
+	Policy         *auth.PasswordPolicy
+	Mailer         mail.Mailer
+	Limiter        *ratelimit.Limiter
+	AppBaseURL     string
+	EmailChangeTTL time.Duration
+}
+
+func NewUserHandler(userRepo repository.UserRepository, userTokenRepo repository.UserTokenRepository, tokens *auth.TokenService, passwords *auth.PasswordHasher, policy *auth.PasswordPolicy, mailer mail.Mailer, appBaseURL string, emailChangeTTL time.Duration) *UserHandler {
+	return &UserHandler{
+		UserRepo:      userRepo,
+		UserTokenRepo: userTokenRepo,
+		Tokens:        tokens,
+		Passwords:     passwords,
+		Policy:        policy,
+		Mailer:        mailer,
+		// At most 3 email change requests per user every 15 minutes.
+		Limiter:        ratelimit.NewLimiter(3, 15*time.Minute),
+		AppBaseURL:     appBaseURL,
+		EmailChangeTTL: emailChangeTTL,
+	}
+}
+
+// UserProfile is the current user as shown to themselves.
+type UserProfile struct {
+	ID               primitive.ObjectID     `json:"_id"`
+	Email            string                 `json:"email"`
+	EmailVerified    bool                   `json:"emailVerified"`
+	PendingEmail     string                 `json:"pendingEmail,omitempty"`
+	DisplayName      string                 `json:"displayName,omitempty"`
+	AvatarURL        string                 `json:"avatarUrl,omitempty"`
+	Preferences      models.UserPreferences `json:"preferences"`
+	HasPassword      bool                   `json:"hasPassword"`
+	TwoFactorEnabled bool                   `json:"twoFactorEnabled"`
+	Identities       []models.UserIdentity  `json:"identities,omitempty"`
+}
+
+func newUserProfile(user *models.User) UserProfile {
+	profile := UserProfile{
+		ID:               user.ID,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerified,
+		PendingEmail:     user.PendingEmail,
+		DisplayName:      user.DisplayName,
+		Preferences:      user.Preferences,
+		HasPassword:      user.Password != "",
+		TwoFactorEnabled: user.TwoFactor != nil && user.TwoFactor.Enabled,
+		Identities:       user.Identities,
+	}
+	if profile.Preferences.Theme == "" {
+		profile.Preferences.Theme = models.ThemeSystem
+	}
+	if user.Avatar != nil {
+		profile.AvatarURL = fmt.Sprintf("/api/v1/users/me/avatar?v=%d", user.Avatar.UpdatedAt.Unix())
+	}
+	return profile
+}
+
+func (h *UserHandler) GetMe(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, newUserProfile(user))
+}
+
+type UpdatePreferencesRequest struct {
+	Theme *string `json:"theme" binding:"omitempty,oneof=system light dark"`
+}
+
+// UpdateProfileRequest changes only the fields that are present.
+type UpdateProfileRequest struct {
+	DisplayName *string                   `json:"displayName" binding:"omitempty,max=100"`
+	Preferences *UpdatePreferencesRequest `json:"preferences"`
+}
+
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if req.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.Preferences != nil && req.Preferences.Theme != nil {
+		user.Preferences.Theme = *req.Preferences.Theme
+	}
+
+	if err := h.UserRepo.UpdateProfile(c.Request.Context(), user.ID, user.DisplayName, user.Preferences); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserProfile(user))
+}
+
+// UploadAvatar replaces the avatar with the image in the "avatar" form field.
+func (h *UserHandler) UploadAvatar(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+	if file.Size > maxAvatarSize {
+		HandleError(c, http.StatusRequestEntityTooLarge, problem.PayloadTooLarge, fmt.Sprintf("Avatar must be at most %d KB", maxAvatarSize>>10))
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxAvatarSize))
+	if err != nil {
+		InternalServerError(c, err)
+		return
+	}
+
+	// Trust the content, not the client's Content-Type.
+	contentType := http.DetectContentType(data)
+	if !avatarContentTypes[contentType] {
+		HandleError(c, http.StatusUnsupportedMediaType, problem.UnsupportedMediaType, "Avatar must be a PNG, JPEG, GIF or WebP image")
+		return
+	}
+
+	user.Avatar = &models.Avatar{ContentType: contentType, Data: data, UpdatedAt: time.Now().UTC()}
+	if err := h.UserRepo.SetAvatar(c.Request.Context(), user.ID, user.Avatar); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserProfile(user))
+}
+
+func (h *UserHandler) GetAvatar(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if user.Avatar == nil {
+		NotFound(c, "No avatar set")
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, user.Avatar.ContentType, user.Avatar.Data)
+}
+
+func (h *UserHandler) DeleteAvatar(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	user.Avatar = nil
+	if err := h.UserRepo.SetAvatar(c.Request.Context(), user.ID, nil); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserProfile(user))
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required"`
+}
+
+// ChangePassword sets a new password and signs out every other session.
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if !confirmPassword(c, h.Passwords, req.CurrentPassword, user.Password) {
+		return
+	}
+
+	if err := h.Policy.Check(req.NewPassword, user.Email); err != nil {
+		if passwordRejected(c, "newPassword", err) {
+			return
+		}
+		InternalServerError(c, err)
+		return
+	}
+	hashedPassword, err := h.Passwords.Hash(req.NewPassword)
+	if err != nil {
+		if passwordRejected(c, "newPassword", err) {
+			return
+		}
+		InternalServerError(c, err)
+		return
+	}
+	if err := h.UserRepo.UpdatePassword(c.Request.Context(), user.ID, hashedPassword); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	sessionID, _ := primitive.ObjectIDFromHex(c.GetString("sessionID"))
+	if err := h.Tokens.RevokeOtherSessions(c.Request.Context(), user.ID, sessionID); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
+
+// recentLoginWindow is how long after signing in a user without a password
+// may make changes that would otherwise ask for it.
+const recentLoginWindow = 10 * time.Minute
+
+// ChangeEmailRequest asks for an email change. Users who sign in only
+// through single sign-on have no password; they must have signed in within
+// recentLoginWindow instead.
+type ChangeEmailRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password"`
+}
+
+// RequestEmailChange sends a confirmation link to the new address. The
+// email only changes once the link is used, proving the user owns it.
+func (h *UserHandler) RequestEmailChange(c *gin.Context) {
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if user.Password != "" {
+		if !confirmPassword(c, h.Passwords, req.Password, user.Password) {
+			return
+		}
+	} else if !h.requireRecentLogin(c) {
+		return
+	}
+	if strings.EqualFold(req.Email, user.Email) {
+		HandleError(c, http.StatusBadRequest, problem.InvalidRequest, "The new email is the same as the current one")
+		return
+	}
+	if ok, retryAfter := h.Limiter.Allow(user.ID.Hex()); !ok {
+		TooManyRequests(c, retryAfter)
+		return
+	}
+
+	existing, err := h.UserRepo.FindByEmail(c.Request.Context(), req.Email)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if existing != nil {
+		HandleError(c, http.StatusConflict, problem.EmailTaken, "User with this email already exists")
+		return
+	}
+
+	// The token carries the address it is mailed to, so a link confirms
+	// that address even if another change was requested meanwhile.
+	rawToken, err := issueUserToken(c.Request.Context(), h.UserTokenRepo, user.ID, models.TokenPurposeEmailChange, req.Email, h.EmailChangeTTL)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if err := h.UserRepo.SetPendingEmail(c.Request.Context(), user.ID, req.Email); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+
+	link := strings.TrimRight(h.AppBaseURL, "/") + "/confirm-email?token=" + url.QueryEscape(rawToken)
+	sendMailAsync(h.Mailer, mail.Message{
+		To:      req.Email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("To use this address for your account, open this link:\n%s\n\n"+
+			"The link expires in %s.\n",
+			link, h.EmailChangeTTL),
+	})
+	sendMailAsync(h.Mailer, mail.Message{
+		To:      user.Email,
+		Subject: "Email change requested",
+		Body: fmt.Sprintf("Someone asked to change your account's email address to %s.\n\n"+
+			"If this was not you, change your password now.\n",
+			req.Email),
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Confirmation email sent to the new address"})
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func (h *UserHandler) ConfirmEmailChange(c *gin.Context) {
+	var req ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	// Only use the token up once the change can go through, so a link
+	// opened in the wrong session still works in the right one.
+	token, err := findUserToken(c.Request.Context(), h.UserTokenRepo, models.TokenPurposeEmailChange, req.Token)
+	if err != nil {
+		if errors.Is(err, errInvalidUserToken) {
+			HandleError(c, http.StatusBadRequest, problem.InvalidEmailToken, "Invalid or expired token")
+			return
+		}
+		RepositoryError(c, err)
+		return
+	}
+	if token.UserID != user.ID || token.Data == "" {
+		HandleError(c, http.StatusBadRequest, problem.InvalidEmailToken, "Invalid or expired token")
+		return
+	}
+
+	// The address may have been registered since the change was requested.
+	email := token.Data
+	existing, err := h.UserRepo.FindByEmail(c.Request.Context(), email)
+	if err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	if existing != nil {
+		HandleError(c, http.StatusConflict, problem.EmailTaken, "User with this email already exists")
+		return
+	}
+
+	if _, err := consumeUserToken(c.Request.Context(), h.UserTokenRepo, models.TokenPurposeEmailChange, req.Token); err != nil {
+		if errors.Is(err, errInvalidUserToken) {
+			HandleError(c, http.StatusBadRequest, problem.InvalidEmailToken, "Invalid or expired token")
+			return
+		}
+		RepositoryError(c, err)
+		return
+	}
+
+	if err := h.UserRepo.UpdateEmail(c.Request.Context(), user.ID, email); err != nil {
+		RepositoryError(c, err)
+		return
+	}
+	user.Email, user.EmailVerified, user.PendingEmail = email, true, ""
+
+	c.JSON(http.StatusOK, newUserProfile(user))
+}
+
+// requireRecentLogin stands in for the password of users without one: the
+// request's session must have started within recentLoginWindow. It responds
+// with 403 and returns false otherwise.
+func (h *UserHandler) requireRecentLogin(c *gin.Context) bool {
+	sessionID, err := primitive.ObjectIDFromHex(c.GetString("sessionID"))
+	if err != nil {
+		HandleError(c, http.StatusForbidden, problem.ReauthenticationRequired, "Sign in again to confirm this change")
+		return false
+	}
+	session, err := h.Tokens.SessionRepo.FindByID(c.Request.Context(), sessionID)
+	if err != nil {
+		RepositoryError(c, err)
+		return false
+	}
+	if session == nil || time.Since(session.CreatedAt) > recentLoginWindow {
+		HandleError(c, http.StatusForbidden, problem.ReauthenticationRequired, "Sign in again to confirm this change")
+		return false
+	}
+	return true
+}
+
+func (h *UserHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	return findCurrentUser(c, h.UserRepo)
+}
+
+// findCurrentUser loads the signed-in user, writing an error response and
+// returning false if it cannot.
+func findCurrentUser(c *gin.Context, userRepo repository.UserRepository) (*models.User, bool) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		InternalServerError(c, err)
+		return nil, false
+	}
+	user, err := userRepo.FindByID(c.Request.Context(), userID)
+	if err != nil {
+		RepositoryError(c, err)
+		return nil, false
+	}
+	if user == nil {
+		NotFound(c, "User not found")
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/mail"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/ratelimit"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUserHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserRepo := repository.NewMockUserRepository()
+	tokens := newTestTokenService()
+	mailer := newRecordingMailer()
+	authHandler := NewAuthHandler(mockUserRepo, tokens, newTestPasswordHasher(), newTestPasswordPolicy(), nil, nil, nil)
+	userHandler := NewUserHandler(mockUserRepo, repository.NewMockUserTokenRepository(), tokens, newTestPasswordHasher(), newTestPasswordPolicy(), mailer, "https://draw.example.com", time.Hour)
+	authMiddleware := newTestAuthMiddleware(tokens)
+
+	router := gin.Default()
+	router.POST("/register", authHandler.Register)
+	router.POST("/login", authHandler.Login)
+	router.POST("/refresh", authHandler.Refresh)
+	users := router.Group("/users", authMiddleware)
+	users.GET("/me", userHandler.GetMe)
+	users.PATCH("/me", userHandler.UpdateMe)
+	users.GET("/me/avatar", userHandler.GetAvatar)
+	users.POST("/me/avatar", userHandler.UploadAvatar)
+	users.DELETE("/me/avatar", userHandler.DeleteAvatar)
+	users.POST("/me/password", userHandler.ChangePassword)
+	users.POST("/me/email", userHandler.RequestEmailChange)
+	users.POST("/me/email/confirm", userHandler.ConfirmEmailChange)
+
+	do := func(method, path, body, token string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+	login := func(password string) map[string]interface{} {
+		w, resp := do(http.MethodPost, "/login", `{"email": "me@example.com", "password": "`+password+`"}`, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		return resp
+	}
+
+	do(http.MethodPost, "/register", `{"email": "taken@example.com", "password": "password123"}`, "")
+	do(http.MethodPost, "/register", `{"email": "me@example.com", "password": "password123"}`, "")
+	session := login("password123")
+	token := session["token"].(string)
+
+	t.Run("Get And Update Profile", func(t *testing.T) {
+		w, resp := do(http.MethodGet, "/users/me", "", token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "me@example.com", resp["email"])
+		assert.Equal(t, true, resp["hasPassword"])
+		assert.Equal(t, "system", resp["preferences"].(map[string]interface{})["theme"])
+		assert.NotContains(t, resp, "password")
+
+		w, _ = do(http.MethodPatch, "/users/me", `{"preferences": {"theme": "purple"}}`, token)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, resp = do(http.MethodPatch, "/users/me", `{"displayName": " Ada ", "preferences": {"theme": "dark"}}`, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Ada", resp["displayName"])
+
+		// Omitted fields are left alone.
+		w, resp = do(http.MethodPatch, "/users/me", `{"preferences": {"theme": "light"}}`, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Ada", resp["displayName"])
+		assert.Equal(t, "light", resp["preferences"].(map[string]interface{})["theme"])
+	})
+
+	t.Run("Avatar", func(t *testing.T) {
+		upload := func(data []byte) *httptest.ResponseRecorder {
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			part, _ := form.CreateFormFile("avatar", "avatar.png")
+			part.Write(data)
+			form.Close()
+			req, _ := http.NewRequest(http.MethodPost, "/users/me/avatar", &body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		w := upload([]byte("<html>not an image</html>"))
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+		w = upload(make([]byte, maxAvatarSize+1))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+		png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+		w = upload(png)
+		assert.Equal(t, http.StatusOK, w.Code)
+		_, resp := do(http.MethodGet, "/users/me", "", token)
+		assert.Contains(t, resp["avatarUrl"], "/api/v1/users/me/avatar")
+
+		w, _ = do(http.MethodGet, "/users/me/avatar", "", token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, png, w.Body.Bytes())
+
+		w, _ = do(http.MethodDelete, "/users/me/avatar", "", token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w, _ = do(http.MethodGet, "/users/me/avatar", "", token)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Change Password", func(t *testing.T) {
+		other := login("password123")
+
+		w, _ := do(http.MethodPost, "/users/me/password", `{"currentPassword": "wrong-password", "newPassword": "new-password123"}`, token)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w, _ = do(http.MethodPost, "/users/me/password", `{"currentPassword": "password123", "newPassword": "new-password123"}`, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w, _ = do(http.MethodPost, "/refresh", `{"refreshToken": "`+other["refreshToken"].(string)+`"}`, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "other sessions are signed out")
+		w, _ = do(http.MethodGet, "/users/me", "", token)
+		assert.Equal(t, http.StatusOK, w.Code, "the current session survives")
+
+		login("new-password123")
+	})
+
+	t.Run("Change Email", func(t *testing.T) {
+		w, _ := do(http.MethodPost, "/users/me/email", `{"email": "taken@example.com", "password": "new-password123"}`, token)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		w, _ = do(http.MethodPost, "/users/me/email", `{"email": "new@example.com", "password": "password123"}`, token)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w, _ = do(http.MethodPost, "/users/me/email", `{"email": "new@example.com", "password": "new-password123"}`, token)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		// One message goes to each address, in either order.
+		sent := map[string]mail.Message{}
+		for i := 0; i < 2; i++ {
+			msg := mailer.next(t)
+			sent[msg.To] = msg
+		}
+		assert.Contains(t, sent["me@example.com"].Body, "new@example.com")
+		confirmation := tokenFromEmail(t, sent["new@example.com"])
+
+		_, resp := do(http.MethodGet, "/users/me", "", token)
+		assert.Equal(t, "me@example.com", resp["email"], "unchanged until confirmed")
+		assert.Equal(t, "new@example.com", resp["pendingEmail"])
+
+		w, _ = do(http.MethodPost, "/users/me/email/confirm", `{"token": "bogus"}`, token)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, resp = do(http.MethodPost, "/users/me/email/confirm", `{"token": "`+confirmation+`"}`, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "new@example.com", resp["email"])
+		assert.Equal(t, true, resp["emailVerified"])
+		assert.NotContains(t, resp, "pendingEmail")
+
+		w, _ = do(http.MethodPost, "/users/me/email/confirm", `{"token": "`+confirmation+`"}`, token)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "tokens are single use")
+
+		w, _ = do(http.MethodPost, "/login", `{"email": "new@example.com", "password": "new-password123"}`, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Email Change Confirms The Address The Link Was Sent To", func(t *testing.T) {
+		w, _ := do(http.MethodPost, "/users/me/email", `{"email": "second@example.com", "password": "new-password123"}`, token)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		sent := map[string]mail.Message{}
+		for i := 0; i < 2; i++ {
+			msg := mailer.next(t)
+			sent[msg.To] = msg
+		}
+		confirmation := tokenFromEmail(t, sent["second@example.com"])
+
+		// An overlapping request left another address pending.
+		user := mockUserRepo.Users["new@example.com"]
+		mockUserRepo.SetPendingEmail(context.Background(), user.ID, "unproven@example.com")
+
+		w, resp := do(http.MethodPost, "/users/me/email/confirm", `{"token": "`+confirmation+`"}`, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "second@example.com", resp["email"])
+		assert.Nil(t, mockUserRepo.Users["unproven@example.com"])
+	})
+
+	t.Run("Email Change Token Survives The Wrong Session", func(t *testing.T) {
+		userHandler.Limiter = ratelimit.NewLimiter(3, 15*time.Minute)
+		w, _ := do(http.MethodPost, "/users/me/email", `{"email": "third@example.com", "password": "new-password123"}`, token)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		sent := map[string]mail.Message{}
+		for i := 0; i < 2; i++ {
+			msg := mailer.next(t)
+			sent[msg.To] = msg
+		}
+		confirmation := tokenFromEmail(t, sent["third@example.com"])
+
+		_, other := do(http.MethodPost, "/login", `{"email": "taken@example.com", "password": "password123"}`, "")
+		w, _ = do(http.MethodPost, "/users/me/email/confirm", `{"token": "`+confirmation+`"}`, other["token"].(string))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, resp := do(http.MethodPost, "/users/me/email/confirm", `{"token": "`+confirmation+`"}`, token)
+		assert.Equal(t, http.StatusOK, w.Code, "the owner can still use the link")
+		assert.Equal(t, "third@example.com", resp["email"])
+	})
+
+	t.Run("Single Sign-On Users Need A Recent Login To Change Email", func(t *testing.T) {
+		ctx := context.Background()
+		ssoUser := &models.User{ID: primitive.NewObjectID(), Email: "sso@example.com", EmailVerified: true}
+		mockUserRepo.Create(ctx, ssoUser)
+		pair, err := tokens.IssueTokens(ctx, ssoUser.ID, auth.DeviceInfo{})
+		if err != nil {
+			t.Fatalf("could not issue tokens: %v", err)
+		}
+		sessions, _ := tokens.ListSessions(ctx, ssoUser.ID)
+		sessions[0].CreatedAt = time.Now().Add(-time.Hour)
+
+		w, resp := do(http.MethodPost, "/users/me/email", `{"email": "sso-new@example.com"}`, pair.AccessToken)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, "reauthentication_required", resp["code"])
+
+		sessions[0].CreatedAt = time.Now()
+		w, _ = do(http.MethodPost, "/users/me/email", `{"email": "sso-new@example.com"}`, pair.AccessToken)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		mailer.next(t)
+		mailer.next(t)
+	})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/mail"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errInvalidUserToken = errors.New("invalid or expired token")
+
+// issueUserToken replaces the user's outstanding tokens for purpose with a
+// new one that carries data, and returns its raw value.
+func issueUserToken(ctx context.Context, repo repository.UserTokenRepository, userID primitive.ObjectID, purpose, data string, ttl time.Duration) (string, error) {
+	if err := repo.DeleteByUserID(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	rawToken, err := auth.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	token := &models.UserToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: auth.HashToken(rawToken),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+		Data:      data,
+	}
+	if err := repo.Create(ctx, token); err != nil {
+		return "", err
+	}
+	return rawToken, nil
+}
+
+// findUserToken returns a token without using it up, or returns
+// errInvalidUserToken if it is unknown, expired or already used.
+func findUserToken(ctx context.Context, repo repository.UserTokenRepository, purpose, rawToken string) (*models.UserToken, error) {
+	token, err := repo.FindByHash(ctx, purpose, auth.HashToken(rawToken))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, errInvalidUserToken
+	}
+	return token, nil
+}
+
+// consumeUserToken marks a token as used and returns it, or returns
+// errInvalidUserToken if it is unknown, expired or already used.
+func consumeUserToken(ctx context.Context, repo repository.UserTokenRepository, purpose, rawToken string) (*models.UserToken, error) {
+	token, err := findUserToken(ctx, repo, purpose, rawToken)
+	if err != nil {
+		return nil, err
+	}
+
+	consumed, err := repo.Consume(ctx, token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, errInvalidUserToken
+	}
+	return token, nil
+}
+
+// sendMailAsync sends msg in the background, so response times do not depend
+// on mail delivery or reveal whether an account exists.
+func sendMailAsync(mailer mail.Mailer, msg mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := mailer.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send %q email: %v", msg.Subject, err)
+		}
+	}()
+}
@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+)
+
+// TrashPurger periodically removes drawings that have been in the trash for
+// longer than the configured retention period, together with their comments.
+type TrashPurger struct {
+	DrawingRepo repository.DrawingRepository
+	CommentRepo repository.CommentRepository
+	Retention   time.Duration
+	Interval    time.Duration
+}
+
+func NewTrashPurger(drawingRepo repository.DrawingRepository, commentRepo repository.CommentRepository, retention, interval time.Duration) *TrashPurger {
+	return &TrashPurger{
+		DrawingRepo: drawingRepo,
+		CommentRepo: commentRepo,
+		Retention:   retention,
+		Interval:    interval,
+	}
+}
+
+// Run purges expired drawings once immediately and then on every interval
+// until ctx is cancelled.
+func (p *TrashPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.PurgeOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *TrashPurger) PurgeOnce(ctx context.Context) {
+	cutoff := time.Now().UTC().Add(-p.Retention)
+	ids, err := p.DrawingRepo.FindIDsDeletedBefore(ctx, cutoff)
+	if err != nil {
+		log.Printf("Failed to find expired drawings in the trash: %v", err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	// Comments go first so a failure leaves drawings to retry, not
+	// comments nobody can reach.
+	if err := p.CommentRepo.DeleteByDrawingIDs(ctx, ids); err != nil {
+		log.Printf("Failed to delete comments of trashed drawings: %v", err)
+		return
+	}
+	n, err := p.DrawingRepo.PurgeDeleted(ctx, ids)
+	if err != nil {
+		log.Printf("Failed to purge trashed drawings: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("Purged %d drawings from the trash", n)
+	}
+}
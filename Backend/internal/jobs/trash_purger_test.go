@@ -0,0 +1,41 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTrashPurger_PurgeOnce(t *testing.T) {
+	drawingRepo := repository.NewMockDrawingRepository()
+	commentRepo := repository.NewMockCommentRepository()
+	purger := NewTrashPurger(drawingRepo, commentRepo, 24*time.Hour, time.Hour)
+
+	longAgo := time.Now().UTC().Add(-48 * time.Hour)
+	recently := time.Now().UTC().Add(-time.Hour)
+	expired := &models.Drawing{ID: primitive.NewObjectID(), DeletedAt: &longAgo}
+	trashed := &models.Drawing{ID: primitive.NewObjectID(), DeletedAt: &recently}
+	kept := &models.Drawing{ID: primitive.NewObjectID()}
+	for _, d := range []*models.Drawing{expired, trashed, kept} {
+		drawingRepo.Create(context.Background(), d)
+		commentRepo.Create(context.Background(), &models.Comment{ID: primitive.NewObjectID(), DrawingID: d.ID, Body: "Looks good"})
+	}
+
+	purger.PurgeOnce(context.Background())
+
+	assert.NotContains(t, drawingRepo.Drawings, expired.ID)
+	assert.Contains(t, drawingRepo.Drawings, trashed.ID)
+	assert.Contains(t, drawingRepo.Drawings, kept.ID)
+
+	comments, _ := commentRepo.FindByDrawingID(context.Background(), expired.ID)
+	assert.Empty(t, comments)
+	for _, d := range []*models.Drawing{trashed, kept} {
+		comments, _ := commentRepo.FindByDrawingID(context.Background(), d.ID)
+		assert.Len(t, comments, 1)
+	}
+}
@@ -0,0 +1,25 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewLogMailer(dir, "no-reply@example.com")
+
+	err := mailer.Send(context.Background(), Message{To: "user@example.com", Subject: "Hello", Body: "Line one\nLine two"})
+	assert.NoError(t, err)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.Len(t, files, 1)
+	data, _ := os.ReadFile(files[0])
+	assert.Contains(t, string(data), "To: user@example.com\r\n")
+	assert.Contains(t, string(data), "Subject: Hello\r\n")
+	assert.Contains(t, string(data), "\r\n\r\nLine one\r\nLine two")
+}
@@ -1,59 +1,92 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+func AuthMiddleware(tokens *auth.TokenService, sessions *auth.SessionTracker, personalAccessTokens *auth.PersonalAccessTokenService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			c.Abort()
+			problem.Write(c, http.StatusUnauthorized, problem.AuthenticationRequired, "Authorization header is required")
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
-			c.Abort()
+			problem.Write(c, http.StatusUnauthorized, problem.AuthenticationRequired, "Authorization header format must be Bearer {token}")
 			return
 		}
 
 		tokenString := parts[1]
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, http.ErrAbortHandler
-			}
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+		if auth.IsPersonalAccessToken(tokenString) {
+			authenticatePersonalAccessToken(c, personalAccessTokens, tokenString)
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
+		claims, err := tokens.ValidateAccessToken(c.Request.Context(), tokenString)
+		if err != nil {
+			switch {
+			case errors.Is(err, auth.ErrRevokedToken):
+				problem.Write(c, http.StatusUnauthorized, problem.TokenRevoked, "Token has been revoked")
+			case errors.Is(err, auth.ErrInvalidToken):
+				problem.Write(c, http.StatusUnauthorized, problem.InvalidToken, "Invalid or expired token")
+			default:
+				problem.Internal(c, err)
+			}
 			return
 		}
 
-		userID, ok := claims["sub"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
-			c.Abort()
-			return
+		if sessionID, err := primitive.ObjectIDFromHex(claims.SessionID); err == nil {
+			sessions.Touch(sessionID)
 		}
 
-		c.Set("userID", userID)
+		c.Set("userID", claims.Subject)
+		c.Set("sessionID", claims.SessionID)
 		c.Next()
 	}
 }
+
+func authenticatePersonalAccessToken(c *gin.Context, personalAccessTokens *auth.PersonalAccessTokenService, tokenString string) {
+	requiredScope := c.GetString(requiredScopeKey)
+	if requiredScope == "" {
+		problem.Write(c, http.StatusForbidden, problem.InsufficientScope, "Personal access tokens cannot be used for this endpoint")
+		return
+	}
+
+	token, err := personalAccessTokens.Validate(c.Request.Context(), tokenString)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			problem.Write(c, http.StatusUnauthorized, problem.InvalidToken, "Invalid or expired token")
+		} else {
+			problem.Internal(c, err)
+		}
+		return
+	}
+
+	if !hasScope(token.Scopes, requiredScope) {
+		problem.Write(c, http.StatusForbidden, problem.InsufficientScope, "Token is missing the "+requiredScope+" scope")
+		return
+	}
+
+	c.Set("userID", token.UserID.Hex())
+	c.Set("personalAccessTokenID", token.ID.Hex())
+	c.Next()
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestID gives every request a correlation id, echoed in the X-Request-ID
+// response header and included in error responses and logs. A usable id sent
+// by the client or a proxy is kept.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		problem.RequestID(c)
+		c.Next()
+	}
+}
+
+// Recovery turns a panic into a logged internal_error response.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		problem.Internal(c, fmt.Errorf("panic: %v", recovered))
+	})
+}
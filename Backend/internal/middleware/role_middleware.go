@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RequireRole rejects requests from users without the given role. The role
+// is read from the database on every request, so demoting a user takes
+// effect immediately. It must run after AuthMiddleware.
+func RequireRole(userRepo repository.UserRepository, role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+		if err != nil {
+			problem.Write(c, http.StatusUnauthorized, problem.InvalidToken, "Invalid user ID in token")
+			return
+		}
+
+		user, err := userRepo.FindByID(c.Request.Context(), userID)
+		if err != nil {
+			problem.Internal(c, err)
+			return
+		}
+		if user == nil || user.DisabledAt != nil || userRole(user) != role {
+			problem.Write(c, http.StatusForbidden, problem.PermissionDenied, "You do not have permission to access this resource")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func userRole(user *models.User) string {
+	if user.Role == "" {
+		return models.RoleUser
+	}
+	return user.Role
+}
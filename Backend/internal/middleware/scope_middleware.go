@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const requiredScopeKey = "requiredScope"
+
+// AllowScopes opens the routes that follow to personal access tokens: safe
+// methods require readScope and the others writeScope. It must run before
+// AuthMiddleware; routes without it accept only session tokens.
+func AllowScopes(readScope, writeScope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Set(requiredScopeKey, readScope)
+		default:
+			c.Set(requiredScopeKey, writeScope)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RequireVerifiedEmail rejects requests from users who have not verified
+// their email address. It does nothing unless enabled, and must run after
+// AuthMiddleware.
+func RequireVerifiedEmail(userRepo repository.UserRepository, enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled {
+			c.Next()
+			return
+		}
+
+		userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+		if err != nil {
+			problem.Write(c, http.StatusUnauthorized, problem.InvalidToken, "Invalid user ID in token")
+			return
+		}
+
+		user, err := userRepo.FindByID(c.Request.Context(), userID)
+		if err != nil {
+			problem.Internal(c, err)
+			return
+		}
+		if user == nil || !user.EmailVerified {
+			problem.Write(c, http.StatusForbidden, problem.EmailNotVerified, "Please verify your email address first")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,11 +1,76 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type User struct {
-	ID       primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-	Email    string             `bson:"email" json:"email"`
-	Password string             `bson:"password" json:"password,omitempty"`
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	Email         string             `bson:"email" json:"email"`
+	Password      string             `bson:"password" json:"password,omitempty"`
+	EmailVerified bool               `bson:"emailVerified" json:"emailVerified"`
+	Identities    []UserIdentity     `bson:"identities,omitempty" json:"identities,omitempty"`
+	TwoFactor     *TwoFactor         `bson:"twoFactor,omitempty" json:"-"`
+
+	DisplayName string          `bson:"displayName,omitempty" json:"displayName,omitempty"`
+	Avatar      *Avatar         `bson:"avatar,omitempty" json:"-"`
+	Preferences UserPreferences `bson:"preferences" json:"preferences"`
+	// PendingEmail is the address the user last asked to change to, shown
+	// until they confirm it. The confirmation link's token, not this field,
+	// decides which address is applied.
+	PendingEmail string `bson:"pendingEmail,omitempty" json:"pendingEmail,omitempty"`
+
+	// Role is RoleAdmin for administrators; empty means RoleUser.
+	Role string `bson:"role,omitempty" json:"role,omitempty"`
+	// DisabledAt is set while an administrator has disabled the account.
+	// Disabled users cannot sign in or use their access tokens.
+	DisabledAt *time.Time `bson:"disabledAt,omitempty" json:"disabledAt,omitempty"`
+}
+
+// Roles a user can have.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Avatar is a small profile image stored with the user.
+type Avatar struct {
+	ContentType string    `bson:"contentType"`
+	Data        []byte    `bson:"data"`
+	UpdatedAt   time.Time `bson:"updatedAt"`
+}
+
+// Themes a user can choose as their default.
+const (
+	ThemeSystem = "system"
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+)
+
+type UserPreferences struct {
+	// Theme is the default theme for new drawings; empty means ThemeSystem.
+	Theme string `bson:"theme,omitempty" json:"theme,omitempty"`
+}
+
+// TwoFactor holds a user's TOTP settings. Enrollment stores the secret with
+// Enabled false until the user confirms a first code.
+type TwoFactor struct {
+	Secret  string `bson:"secret"`
+	Enabled bool   `bson:"enabled"`
+	// RecoveryCodes are hashes of the unused recovery codes.
+	RecoveryCodes []string `bson:"recoveryCodes,omitempty"`
+	// LastUsedStep is the TOTP time step of the last accepted code, so a
+	// code cannot be used twice.
+	LastUsedStep int64      `bson:"lastUsedStep"`
+	EnabledAt    *time.Time `bson:"enabledAt,omitempty"`
+}
+
+// UserIdentity links a user to an account at an external identity provider.
+type UserIdentity struct {
+	Provider string `bson:"provider" json:"provider"`
+	Subject  string `bson:"subject" json:"subject"`
 }
 
 type Drawing struct {
@@ -13,4 +78,173 @@ type Drawing struct {
 	UserID    primitive.ObjectID `bson:"userId" json:"userId"`
 	Title     string             `bson:"title" json:"title"`
 	SceneData string             `bson:"sceneData" json:"sceneData"`
+	// Folder is a slash-separated path the user files the drawing under.
+	Folder string   `bson:"folder,omitempty" json:"folder,omitempty"`
+	Tags   []string `bson:"tags,omitempty" json:"tags,omitempty"`
+	// ForkedFrom is the drawing this one was duplicated from, if any.
+	ForkedFrom *primitive.ObjectID `bson:"forkedFrom,omitempty" json:"forkedFrom,omitempty"`
+	// Template is set when the user has marked the drawing as a template.
+	Template *TemplateInfo `bson:"template,omitempty" json:"template,omitempty"`
+	// DeletedAt is set when the drawing is moved to the trash.
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+	// UnresolvedComments is computed when listing drawings and never stored.
+	UnresolvedComments int `bson:"-" json:"unresolvedComments,omitempty"`
+}
+
+// StorageUsage is how many documents of one kind a user has and their total
+// size in bytes.
+type StorageUsage struct {
+	Count int64 `bson:"count" json:"count"`
+	Bytes int64 `bson:"bytes" json:"bytes"`
+}
+
+type TemplateInfo struct {
+	Category  string `bson:"category" json:"category"`
+	Thumbnail string `bson:"thumbnail,omitempty" json:"thumbnail,omitempty"`
+}
+
+// Library is a named collection of reusable Excalidraw elements, stored
+// server-side so it is available in every browser.
+type Library struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+	Name      string             `bson:"name" json:"name"`
+	Items     []LibraryItem      `bson:"items" json:"items"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// LibraryItem mirrors an entry of libraryItems in the .excalidrawlib v2
+// format. Hash identifies items with the same elements for de-duplication.
+type LibraryItem struct {
+	ID       string                   `bson:"id" json:"id"`
+	Name     string                   `bson:"name,omitempty" json:"name,omitempty"`
+	Status   string                   `bson:"status" json:"status"`
+	Created  int64                    `bson:"created" json:"created"`
+	Elements []map[string]interface{} `bson:"elements" json:"elements"`
+	Hash     string                   `bson:"hash" json:"-"`
+}
+
+// Comment is a message in a discussion thread on a drawing. A thread is a
+// root comment plus the replies whose ParentID points at it; resolution is
+// tracked on the root.
+type Comment struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"_id,omitempty"`
+	DrawingID  primitive.ObjectID  `bson:"drawingId" json:"drawingId"`
+	UserID     primitive.ObjectID  `bson:"userId" json:"userId"`
+	ParentID   *primitive.ObjectID `bson:"parentId,omitempty" json:"parentId,omitempty"`
+	Body       string              `bson:"body" json:"body"`
+	Anchor     *CommentAnchor      `bson:"anchor,omitempty" json:"anchor,omitempty"`
+	Resolved   bool                `bson:"resolved" json:"resolved"`
+	ResolvedBy *primitive.ObjectID `bson:"resolvedBy,omitempty" json:"resolvedBy,omitempty"`
+	ResolvedAt *time.Time          `bson:"resolvedAt,omitempty" json:"resolvedAt,omitempty"`
+	CreatedAt  time.Time           `bson:"createdAt" json:"createdAt"`
+	UpdatedAt  time.Time           `bson:"updatedAt" json:"updatedAt"`
+}
+
+// CommentAnchor pins a thread to an element or to a point on the canvas.
+type CommentAnchor struct {
+	ElementID string   `bson:"elementId,omitempty" json:"elementId,omitempty"`
+	X         *float64 `bson:"x,omitempty" json:"x,omitempty"`
+	Y         *float64 `bson:"y,omitempty" json:"y,omitempty"`
+}
+
+// RefreshToken is a single-use token that can be exchanged for a new access
+// token. Every refresh rotates the token; all tokens descending from one
+// login share a FamilyID.
+type RefreshToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+	FamilyID  primitive.ObjectID `bson:"familyId" json:"familyId"`
+	TokenHash string             `bson:"tokenHash" json:"-"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+	UsedAt    *time.Time         `bson:"usedAt,omitempty" json:"usedAt,omitempty"`
+	RevokedAt *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
+
+// Session is a signed-in device. Its ID is the FamilyID of the refresh
+// tokens issued to that device.
+type Session struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserID     primitive.ObjectID `bson:"userId" json:"userId"`
+	UserAgent  string             `bson:"userAgent" json:"userAgent"`
+	IP         string             `bson:"ip" json:"ip"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	LastSeenAt time.Time          `bson:"lastSeenAt" json:"lastSeenAt"`
+	ExpiresAt  time.Time          `bson:"expiresAt" json:"expiresAt"`
+	RevokedAt  *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
+
+// Purposes of single-use user tokens.
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposeTwoFactorLogin    = "two_factor_login"
+	TokenPurposeEmailChange       = "email_change"
+	TokenPurposeAccountDeletion   = "account_deletion"
+)
+
+// UserToken is a single-use, expiring token sent to a user by email. Only
+// its hash is stored.
+type UserToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+	Purpose   string             `bson:"purpose" json:"purpose"`
+	TokenHash string             `bson:"tokenHash" json:"-"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+	UsedAt    *time.Time         `bson:"usedAt,omitempty" json:"usedAt,omitempty"`
+	// Data is what the token vouches for, such as the address an email
+	// change link was sent to.
+	Data string `bson:"data,omitempty" json:"-"`
+}
+
+// Invitation lets someone register while registration is invite-only. Only
+// the hash of the code is stored. An invitation with an Email can only be
+// used to register that address.
+type Invitation struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"_id,omitempty"`
+	CodeHash  string              `bson:"codeHash" json:"-"`
+	CreatedBy primitive.ObjectID  `bson:"createdBy" json:"createdBy"`
+	Email     string              `bson:"email,omitempty" json:"email,omitempty"`
+	CreatedAt time.Time           `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time           `bson:"expiresAt" json:"expiresAt"`
+	UsedAt    *time.Time          `bson:"usedAt,omitempty" json:"usedAt,omitempty"`
+	UsedBy    *primitive.ObjectID `bson:"usedBy,omitempty" json:"usedBy,omitempty"`
+}
+
+// OIDCLoginState is a pending single sign-on login, created when the user is
+// sent to the identity provider and consumed on the callback.
+type OIDCLoginState struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	Provider     string             `bson:"provider" json:"provider"`
+	StateHash    string             `bson:"stateHash" json:"-"`
+	Nonce        string             `bson:"nonce" json:"-"`
+	CodeVerifier string             `bson:"codeVerifier" json:"-"`
+	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt    time.Time          `bson:"expiresAt" json:"expiresAt"`
+	// Invitation is the invitation code to redeem if the login creates an
+	// account.
+	Invitation string `bson:"invitation,omitempty" json:"-"`
+}
+
+// Scopes a personal access token can be granted.
+const (
+	ScopeDrawingsRead  = "drawings:read"
+	ScopeDrawingsWrite = "drawings:write"
+)
+
+// PersonalAccessToken is a long-lived API key for scripts and CI. Only the
+// hash of the token is stored; Prefix is kept so users can tell tokens apart.
+type PersonalAccessToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserID     primitive.ObjectID `bson:"userId" json:"userId"`
+	Name       string             `bson:"name" json:"name"`
+	TokenHash  string             `bson:"tokenHash" json:"-"`
+	Prefix     string             `bson:"prefix" json:"prefix"`
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt  *time.Time         `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time         `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
 }
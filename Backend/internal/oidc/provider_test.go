@@ -0,0 +1,30 @@
+package oidc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProviders(t *testing.T) {
+	configs, err := ParseProviders("")
+	assert.NoError(t, err)
+	assert.Empty(t, configs)
+
+	configs, err = ParseProviders(`[{"name": "corp", "issuer": "https://idp.example.com", "clientId": "app"}]`)
+	assert.NoError(t, err)
+	assert.Len(t, configs, 1)
+
+	_, err = ParseProviders(`[{"name": "corp", "issuer": "https://idp.example.com"}]`)
+	assert.Error(t, err, "clientId is required")
+
+	_, err = ParseProviders(`[{"name": "corp", "issuer": "a", "clientId": "b"}, {"name": "corp", "issuer": "c", "clientId": "d"}]`)
+	assert.Error(t, err, "names must be unique")
+}
+
+func TestNewProviders(t *testing.T) {
+	providers := NewProviders([]ProviderConfig{{Name: "corp", Issuer: "https://idp.example.com", ClientID: "app"}}, "https://api.example.com/")
+	assert.Equal(t, "https://api.example.com/api/v1/auth/oidc/corp/callback", providers[0].RedirectURL)
+	assert.Equal(t, []string{"openid", "email", "profile"}, providers[0].Config.Scopes)
+	assert.Equal(t, "corp", providers[0].Config.DisplayName)
+}
@@ -0,0 +1,349 @@
+// Package openapi generates an OpenAPI 3 description of the API from the gin
+// route table and the Go types handlers bind and respond with.
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/drshn/excalidraw/Backend/internal/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the OpenAPI version of generated documents.
+const Version = "3.0.3"
+
+// DocsPage renders the document at openapi.json, relative to its own URL,
+// without loading anything from elsewhere.
+//
+//go:embed docs.html
+var DocsPage []byte
+
+// Auth is what credentials a route accepts.
+type Auth int
+
+const (
+	// Public routes need no credentials.
+	Public Auth = iota
+	// Session routes need an access token from a login.
+	Session
+	// Scoped routes also accept a personal access token with the route's
+	// read or write scope.
+	Scoped
+	// Admin routes need a login session of an administrator.
+	Admin
+)
+
+// Route documents one gin route.
+type Route struct {
+	Method string
+	// Path uses gin syntax, such as /api/v1/drawings/:id.
+	Path        string
+	Tag         string
+	Summary     string
+	Description string
+	Auth        Auth
+	// ReadScope and WriteScope name the personal access token scopes of a
+	// Scoped route.
+	ReadScope, WriteScope string
+	// Query lists the query parameters. QueryParams derives them from a
+	// struct bound with ShouldBindQuery.
+	Query []Parameter
+	// Request is a value of the type bound from the JSON body, or nil.
+	Request any
+	// OptionalRequest is set when the body may be left out.
+	OptionalRequest bool
+	// RequestContentType defaults to application/json.
+	RequestContentType string
+	// Status is the success status and defaults to 200.
+	Status int
+	// Response is a value of the type of the success response, or nil for
+	// none. OneOf documents a choice of types.
+	Response any
+	// ResponseContentType defaults to application/json.
+	ResponseContentType string
+	// Errors lists error statuses beyond those implied by the route: 400
+	// for routes with input, 401 and 403 for authenticated routes, 404 for
+	// routes with path parameters and 500 everywhere.
+	Errors []int
+}
+
+// OneOf documents a response that is one of several types.
+type OneOf []any
+
+// Binary documents a file, such as an upload or a download.
+type Binary struct{}
+
+// Info describes the API as a whole.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathItem maps lower-case HTTP methods to operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+const bearerAuth = "bearerAuth"
+
+// Build documents the routes in routes, in the order of docs. Routes without
+// documentation are left out; Check reports them.
+func Build(info Info, routes gin.RoutesInfo, docs []Route) *Document {
+	schemas := newSchemaGenerator()
+	problemSchema := schemas.schemaFor(problem.Problem{})
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: schemas.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				bearerAuth: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "An access token from login, or a personal access token on routes that accept one.",
+				},
+			},
+		},
+	}
+
+	registered := map[string]bool{}
+	for _, r := range routes {
+		registered[key(r.Method, r.Path)] = true
+	}
+	tags := map[string]bool{}
+	for _, route := range docs {
+		if !registered[key(route.Method, route.Path)] {
+			continue
+		}
+		path := openAPIPath(route.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = PathItem{}
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = operation(route, schemas, problemSchema)
+		if route.Tag != "" && !tags[route.Tag] {
+			tags[route.Tag] = true
+			doc.Tags = append(doc.Tags, Tag{Name: route.Tag})
+		}
+	}
+	return doc
+}
+
+// Check compares the registered routes with their documentation and returns
+// the routes nobody documented and the documentation of routes that no
+// longer exist, each as "METHOD /path".
+func Check(routes gin.RoutesInfo, docs []Route) (undocumented, stale []string) {
+	registered := map[string]bool{}
+	byRoute := index(docs)
+	for _, r := range routes {
+		k := key(r.Method, r.Path)
+		registered[k] = true
+		if _, ok := byRoute[k]; !ok {
+			undocumented = append(undocumented, k)
+		}
+	}
+	for _, d := range docs {
+		if k := key(d.Method, d.Path); !registered[k] {
+			stale = append(stale, k)
+		}
+	}
+	sort.Strings(undocumented)
+	sort.Strings(stale)
+	return undocumented, stale
+}
+
+func index(docs []Route) map[string]Route {
+	byRoute := make(map[string]Route, len(docs))
+	for _, d := range docs {
+		byRoute[key(d.Method, d.Path)] = d
+	}
+	return byRoute
+}
+
+func key(method, path string) string {
+	return method + " " + path
+}
+
+func operation(route Route, schemas *schemaGenerator, problemSchema *Schema) *Operation {
+	op := &Operation{
+		Summary:     route.Summary,
+		Description: route.Description,
+		OperationID: operationID(route),
+		Responses:   map[string]Response{},
+		Security:    []map[string][]string{{bearerAuth: {}}},
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+
+	switch route.Auth {
+	case Public:
+		op.Security = []map[string][]string{}
+	case Scoped:
+		scope := route.WriteScope
+		if route.Method == http.MethodGet {
+			scope = route.ReadScope
+		}
+		op.Description = strings.TrimSpace(op.Description + "\n\nAccepts a personal access token with the `" + scope + "` scope.")
+	case Admin:
+		op.Description = strings.TrimSpace(op.Description + "\n\nRequires the admin role.")
+	}
+
+	for _, segment := range strings.Split(route.Path, "/") {
+		if strings.HasPrefix(segment, ":") {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     segment[1:],
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+	}
+	op.Parameters = append(op.Parameters, route.Query...)
+
+	if route.Request != nil {
+		contentType := route.RequestContentType
+		if contentType == "" {
+			contentType = gin.MIMEJSON
+		}
+		op.RequestBody = &RequestBody{
+			Required: !route.OptionalRequest,
+			Content:  map[string]MediaType{contentType: {Schema: schemas.schemaFor(route.Request)}},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := Response{Description: http.StatusText(status)}
+	if route.Response != nil {
+		contentType := route.ResponseContentType
+		if contentType == "" {
+			contentType = gin.MIMEJSON
+		}
+		success.Content = map[string]MediaType{contentType: {Schema: schemas.schemaFor(route.Response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	errors := append([]int{http.StatusInternalServerError}, route.Errors...)
+	if len(op.Parameters) > 0 || op.RequestBody != nil {
+		errors = append(errors, http.StatusBadRequest)
+	}
+	if route.Auth != Public {
+		errors = append(errors, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	if strings.Contains(route.Path, "/:") {
+		errors = append(errors, http.StatusNotFound)
+	}
+	for _, status := range errors {
+		op.Responses[strconv.Itoa(status)] = Response{
+			Description: http.StatusText(status),
+			Headers: map[string]Header{
+				problem.RequestIDHeader: {Description: "Correlation id of the request, also in requestId.", Schema: &Schema{Type: "string"}},
+			},
+			Content: map[string]MediaType{problem.ContentType: {Schema: problemSchema}},
+		}
+	}
+	return op
+}
+
+// QueryParam documents a query parameter the handler reads itself.
+func QueryParam(name, schemaType, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: schemaType}}
+}
+
+// openAPIPath turns gin's :param and *param segments into {param}.
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// operationID derives a stable id such as getDrawingsById from the method
+// and path, leaving out the /api/v1 prefix.
+func operationID(route Route) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.Method))
+	path := strings.TrimPrefix(route.Path, "/api/v1")
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '.' }) {
+		if strings.HasPrefix(segment, ":") {
+			b.WriteString("By")
+			segment = segment[1:]
+		}
+		b.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+	return b.String()
+}
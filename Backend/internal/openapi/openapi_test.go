@@ -0,0 +1,127 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type testThing struct {
+	ID        primitive.ObjectID `json:"_id"`
+	Name      string             `json:"name" binding:"required,max=10"`
+	Kind      string             `json:"kind" binding:"omitempty,oneof=a b"`
+	Tags      []string           `json:"tags" binding:"max=3,dive,required,max=5"`
+	Size      *int               `json:"size,omitempty" binding:"omitempty,min=1"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Secret    string             `json:"-"`
+	Parent    *testThing         `json:"parent,omitempty"`
+}
+
+type testThingResponse struct {
+	*testThing
+	Count int `json:"count"`
+}
+
+type testQuery struct {
+	Query string `form:"q"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=50"`
+}
+
+func testRoutes() gin.RoutesInfo {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	handler := func(c *gin.Context) {}
+	r.GET("/things", handler)
+	r.POST("/things", handler)
+	r.GET("/things/:id", handler)
+	r.GET("/health", handler)
+	return r.Routes()
+}
+
+var testDocs = []Route{
+	{Method: http.MethodGet, Path: "/things", Tag: "Things", Summary: "List things", Auth: Scoped, ReadScope: "things:read", WriteScope: "things:write",
+		Query: QueryParams(testQuery{}), Response: []testThingResponse{}},
+	{Method: http.MethodPost, Path: "/things", Tag: "Things", Summary: "Create a thing", Auth: Session,
+		Request: testThing{}, Status: http.StatusCreated, Response: testThing{}, Errors: []int{http.StatusConflict}},
+	{Method: http.MethodGet, Path: "/things/:id", Tag: "Things", Auth: Admin, Response: OneOf{testThing{}, Binary{}}},
+	{Method: http.MethodDelete, Path: "/things/:id", Tag: "Things", Auth: Session},
+}
+
+func TestCheck(t *testing.T) {
+	undocumented, stale := Check(testRoutes(), testDocs)
+	assert.Equal(t, []string{"GET /health"}, undocumented)
+	assert.Equal(t, []string{"DELETE /things/:id"}, stale)
+}
+
+func TestBuild(t *testing.T) {
+	doc := Build(Info{Title: "Test", Version: "v1"}, testRoutes(), testDocs)
+
+	// The document must survive a round trip through JSON.
+	_, err := json.Marshal(doc)
+	assert.NoError(t, err)
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Equal(t, []Tag{{Name: "Things"}}, doc.Tags)
+	assert.NotContains(t, doc.Paths, "/health")
+	assert.NotContains(t, doc.Paths["/things/{id}"], "delete")
+
+	t.Run("Operations", func(t *testing.T) {
+		list := doc.Paths["/things"]["get"]
+		assert.Equal(t, "getThings", list.OperationID)
+		assert.Contains(t, list.Description, "`things:read`")
+		assert.Equal(t, []string{"200", "400", "401", "403", "500"}, keys(list.Responses))
+		assert.Equal(t, "q", list.Parameters[0].Name)
+		assert.Equal(t, 50.0, *list.Parameters[1].Schema.Maximum)
+
+		create := doc.Paths["/things"]["post"]
+		assert.True(t, create.RequestBody.Required)
+		assert.Equal(t, "#/components/schemas/testThing", create.RequestBody.Content["application/json"].Schema.Ref)
+		assert.Equal(t, []string{"201", "400", "401", "403", "409", "500"}, keys(create.Responses))
+		assert.Contains(t, create.Responses["409"].Content, "application/problem+json")
+
+		get := doc.Paths["/things/{id}"]["get"]
+		assert.Equal(t, "getThingsById", get.OperationID)
+		assert.Equal(t, "id", get.Parameters[0].Name)
+		assert.Equal(t, "path", get.Parameters[0].In)
+		assert.Contains(t, get.Description, "admin role")
+		assert.Contains(t, get.Responses, "404")
+		oneOf := get.Responses["200"].Content["application/json"].Schema.OneOf
+		assert.Len(t, oneOf, 2)
+		assert.Equal(t, "binary", oneOf[1].Format)
+	})
+
+	t.Run("Schemas", func(t *testing.T) {
+		thing := doc.Components.Schemas["testThing"]
+		assert.Equal(t, []string{"name"}, thing.Required)
+		assert.NotContains(t, thing.Properties, "Secret")
+		assert.Equal(t, "^[0-9a-f]{24}$", thing.Properties["_id"].Pattern)
+		assert.Equal(t, "date-time", thing.Properties["createdAt"].Format)
+		assert.Equal(t, 10, *thing.Properties["name"].MaxLength)
+		assert.Equal(t, []any{"a", "b"}, thing.Properties["kind"].Enum)
+		assert.Equal(t, 3, *thing.Properties["tags"].MaxItems)
+		assert.Equal(t, 5, *thing.Properties["tags"].Items.MaxLength)
+		assert.Equal(t, "integer", thing.Properties["size"].Type)
+		assert.Equal(t, 1.0, *thing.Properties["size"].Minimum)
+		assert.Equal(t, "#/components/schemas/testThing", thing.Properties["parent"].Ref, "recursive types refer to themselves")
+
+		response := doc.Components.Schemas["testThingResponse"]
+		assert.Contains(t, response.Properties, "name", "embedded structs are flattened")
+		assert.Contains(t, response.Properties, "count")
+
+		assert.Contains(t, doc.Components.Schemas, "Problem")
+	})
+}
+
+func keys(responses map[string]Response) []string {
+	var statuses []string
+	for _, status := range []string{"200", "201", "400", "401", "403", "404", "409", "500"} {
+		if _, ok := responses[status]; ok {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
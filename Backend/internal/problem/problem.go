@@ -0,0 +1,221 @@
+// Package problem writes error responses as RFC 7807 problem details. Every
+// response carries a stable code from the catalogue below, which clients
+// should match on instead of the human-readable title and detail.
+package problem
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of problem responses.
+const ContentType = "application/problem+json"
+
+// TypeBaseURI prefixes a code to form the problem's type URI, where its
+// catalogue entry is served.
+const TypeBaseURI = "/api/v1/problems/"
+
+// Code identifies a kind of problem. Codes are part of the API and never
+// change meaning once published.
+type Code string
+
+// General problems.
+const (
+	InvalidRequest       Code = "invalid_request"
+	ValidationFailed     Code = "validation_failed"
+	InvalidID            Code = "invalid_id"
+	Unauthorized         Code = "unauthorized"
+	Forbidden            Code = "forbidden"
+	NotFound             Code = "not_found"
+	Conflict             Code = "conflict"
+	PayloadTooLarge      Code = "payload_too_large"
+	UnsupportedMediaType Code = "unsupported_media_type"
+	RateLimited          Code = "rate_limited"
+	ServerBusy           Code = "server_busy"
+	InternalError        Code = "internal_error"
+)
+
+// Authentication and account problems.
+const (
+	AuthenticationRequired      Code = "authentication_required"
+	InvalidToken                Code = "invalid_token"
+	TokenRevoked                Code = "token_revoked"
+	InsufficientScope           Code = "insufficient_scope"
+	PermissionDenied            Code = "permission_denied"
+	InvalidCredentials          Code = "invalid_credentials"
+	IncorrectPassword           Code = "incorrect_password"
+	ReauthenticationRequired    Code = "reauthentication_required"
+	AccountDisabled             Code = "account_disabled"
+	EmailNotVerified            Code = "email_not_verified"
+	EmailAlreadyVerified        Code = "email_already_verified"
+	EmailTaken                  Code = "email_taken"
+	PasswordRejected            Code = "password_rejected"
+	InvalidRefreshToken         Code = "invalid_refresh_token"
+	RefreshTokenReused          Code = "refresh_token_reused"
+	InvalidEmailToken           Code = "invalid_email_token"
+	InvalidChallenge            Code = "invalid_challenge"
+	InvalidTwoFactorCode        Code = "invalid_two_factor_code"
+	TwoFactorSetupNotStarted    Code = "two_factor_setup_not_started"
+	TwoFactorAlreadyEnabled     Code = "two_factor_already_enabled"
+	TwoFactorNotEnabled         Code = "two_factor_not_enabled"
+	RegistrationDisabled        Code = "registration_disabled"
+	EmailDomainNotAllowed       Code = "email_domain_not_allowed"
+	InvitationRequired          Code = "invitation_required"
+	InvalidInvitation           Code = "invalid_invitation"
+	IdentityProviderUnavailable Code = "identity_provider_unavailable"
+)
+
+// Entry describes a code in the catalogue.
+type Entry struct {
+	Code  Code   `json:"code"`
+	Title string `json:"title"`
+	// Description explains when the problem occurs and what clients can do.
+	Description string `json:"description"`
+}
+
+// Catalogue lists every code the API returns.
+var Catalogue = []Entry{
+	{InvalidRequest, "Invalid request", "The request could not be understood, for example because its body is not valid JSON."},
+	{ValidationFailed, "Validation failed", "One or more fields are missing or invalid. See errors for each field."},
+	{InvalidID, "Invalid ID", "An ID in the path or body is not a valid identifier."},
+	{Unauthorized, "Unauthorized", "The request is not authenticated."},
+	{Forbidden, "Forbidden", "The request is authenticated but not allowed."},
+	{NotFound, "Not found", "The resource does not exist or is not visible to the current user."},
+	{Conflict, "Conflict", "The request conflicts with the current state of the resource."},
+	{PayloadTooLarge, "Payload too large", "The uploaded content is larger than allowed."},
+	{UnsupportedMediaType, "Unsupported media type", "The uploaded content is not of an accepted type."},
+	{RateLimited, "Too many requests", "The client is sending too many requests. Retry after the number of seconds in the Retry-After header."},
+	{ServerBusy, "Server busy", "The server is checking too many passwords at once. Retry after the number of seconds in the Retry-After header."},
+	{InternalError, "Internal server error", "The server failed unexpectedly. Quote requestId when reporting the problem."},
+
+	{AuthenticationRequired, "Authentication required", "The Authorization header is missing or is not a Bearer token."},
+	{InvalidToken, "Invalid token", "The access token or personal access token is invalid or expired."},
+	{TokenRevoked, "Token revoked", "The access token was revoked, for example by logging out."},
+	{InsufficientScope, "Insufficient scope", "The personal access token lacks the scope this endpoint needs, or cannot be used here at all."},
+	{PermissionDenied, "Permission denied", "The current user's role does not allow this action."},
+	{InvalidCredentials, "Invalid credentials", "The email address or password is wrong."},
+	{IncorrectPassword, "Incorrect password", "The password given to confirm a sensitive action is wrong."},
+	{ReauthenticationRequired, "Reauthentication required", "The account has no password, so a sensitive action needs a recent sign-in. Sign in again and retry within 10 minutes."},
+	{AccountDisabled, "Account disabled", "An administrator has disabled the account."},
+	{EmailNotVerified, "Email not verified", "The account must verify its email address first."},
+	{EmailAlreadyVerified, "Email already verified", "The account's email address is already verified."},
+	{EmailTaken, "Email taken", "Another account uses this email address."},
+	{PasswordRejected, "Password rejected", "The new password does not meet the password policy. See errors for each rule it breaks."},
+	{InvalidRefreshToken, "Invalid refresh token", "The refresh token is invalid or expired; log in again."},
+	{RefreshTokenReused, "Refresh token reused", "The refresh token was already used, so every session from its login was revoked."},
+	{InvalidEmailToken, "Invalid email token", "The token from a verification, password reset, email change or account deletion link is invalid, expired or already used."},
+	{InvalidChallenge, "Invalid challenge", "The two-factor login challenge is invalid or expired; log in again."},
+	{InvalidTwoFactorCode, "Invalid two-factor code", "The authenticator or recovery code is wrong or was already used."},
+	{TwoFactorSetupNotStarted, "Two-factor setup not started", "Two-factor authentication must be set up before it can be confirmed."},
+	{TwoFactorAlreadyEnabled, "Two-factor already enabled", "Two-factor authentication is already enabled."},
+	{TwoFactorNotEnabled, "Two-factor not enabled", "Two-factor authentication is not enabled."},
+	{RegistrationDisabled, "Registration disabled", "Accounts can only be created by an administrator."},
+	{EmailDomainNotAllowed, "Email domain not allowed", "Registration is limited to approved email domains."},
+	{InvitationRequired, "Invitation required", "Registration requires an invitation code."},
+	{InvalidInvitation, "Invalid invitation", "The invitation code is invalid, expired, already used or for another address."},
+	{IdentityProviderUnavailable, "Identity provider unavailable", "The single sign-on provider could not be reached."},
+}
+
+// Lookup returns the catalogue entry for code.
+func Lookup(code Code) (Entry, bool) {
+	for _, entry := range Catalogue {
+		if entry.Code == code {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// FieldError describes one invalid field in a request.
+type FieldError struct {
+	// Field is the JSON path of the field, such as "scopes[0]".
+	Field string `json:"field"`
+	// Rule names the check that failed, such as "required" or "max".
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem details object, extended with the code,
+// the request's correlation id and any field errors.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	RequestID string       `json:"requestId"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Write responds with a problem and aborts the request. detail is shown to
+// clients, so it must not contain internal error messages.
+func Write(c *gin.Context, status int, code Code, detail string, fieldErrors ...FieldError) {
+	entry, ok := Lookup(code)
+	if !ok {
+		entry.Title = http.StatusText(status)
+	}
+	p := Problem{
+		Type:      TypeBaseURI + string(code),
+		Title:     entry.Title,
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      code,
+		RequestID: RequestID(c),
+		Errors:    fieldErrors,
+	}
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(status, p)
+}
+
+// Internal logs err with the request's correlation id and responds with a
+// generic 500, so database and other internal errors never reach clients.
+func Internal(c *gin.Context, err error) {
+	log.Printf("Internal error [request %s] %s %s: %v", RequestID(c), c.Request.Method, c.Request.URL.Path, err)
+	Write(c, http.StatusInternalServerError, InternalError, "An unexpected error occurred")
+}
+
+const requestIDKey = "requestID"
+
+// RequestIDHeader carries the correlation id in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID returns the request's correlation id, taking it from the
+// X-Request-ID header when the client or a proxy sent a usable one and
+// generating it otherwise. The id is echoed in the response header.
+func RequestID(c *gin.Context) string {
+	if id := c.GetString(requestIDKey); id != "" {
+		return id
+	}
+
+	id := c.GetHeader(RequestIDHeader)
+	if !validRequestID(id) {
+		b := make([]byte, 12)
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+		id = hex.EncodeToString(b)
+	}
+	c.Set(requestIDKey, id)
+	c.Header(RequestIDHeader, id)
+	return id
+}
+
+// validRequestID accepts short ids of visible ASCII characters, so that a
+// client cannot inject anything into the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package problem
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type testRequest struct {
+	Email  string   `json:"email" binding:"required,email"`
+	Name   string   `json:"name" binding:"max=5"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=read write"`
+	Count  int      `json:"count" binding:"omitempty,min=1"`
+}
+
+func do(t *testing.T, handler gin.HandlerFunc, body string, header http.Header) (*httptest.ResponseRecorder, Problem) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/things", handler)
+
+	req, _ := http.NewRequest(http.MethodPost, "/things", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		req.Header.Set(key, values[0])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var p Problem
+	json.Unmarshal(w.Body.Bytes(), &p)
+	return w, p
+}
+
+func bind(c *gin.Context) {
+	var req testRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func TestWrite(t *testing.T) {
+	w, p := do(t, func(c *gin.Context) {
+		Write(c, http.StatusConflict, EmailTaken, "User with this email already exists")
+	}, "", nil)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "/api/v1/problems/email_taken", p.Type)
+	assert.Equal(t, "Email taken", p.Title)
+	assert.Equal(t, http.StatusConflict, p.Status)
+	assert.Equal(t, "User with this email already exists", p.Detail)
+	assert.Equal(t, "/things", p.Instance)
+	assert.Equal(t, EmailTaken, p.Code)
+	assert.NotEmpty(t, p.RequestID)
+	assert.Equal(t, p.RequestID, w.Header().Get(RequestIDHeader))
+}
+
+func TestInternal(t *testing.T) {
+	w, p := do(t, func(c *gin.Context) {
+		Internal(c, errors.New("connection(localhost:27017) socket was unexpectedly closed"))
+	}, "", http.Header{RequestIDHeader: {"req-123"}})
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, InternalError, p.Code)
+	assert.Equal(t, "req-123", p.RequestID, "a client's correlation id is kept")
+	assert.NotContains(t, w.Body.String(), "27017")
+
+	_, p = do(t, func(c *gin.Context) { Internal(c, errors.New("boom")) }, "", http.Header{RequestIDHeader: {"bad id\n"}})
+	assert.NotEqual(t, "bad id\n", p.RequestID)
+	assert.Len(t, p.RequestID, 24)
+}
+
+func TestBadRequest(t *testing.T) {
+	t.Run("Field Errors", func(t *testing.T) {
+		w, p := do(t, bind, `{"email": "not-an-email", "name": "Too long", "scopes": ["read", "admin"]}`, nil)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, ValidationFailed, p.Code)
+		assert.Equal(t, []FieldError{
+			{Field: "email", Rule: "email", Message: "must be a valid email address"},
+			{Field: "name", Rule: "max", Message: "must be at most 5 characters long"},
+			{Field: "scopes[1]", Rule: "oneof", Message: "must be one of read, write"},
+		}, p.Errors)
+		assert.Equal(t, "email must be a valid email address; name must be at most 5 characters long; scopes[1] must be one of read, write", p.Detail)
+
+		_, p = do(t, bind, `{"scopes": []}`, nil)
+		assert.Equal(t, []FieldError{
+			{Field: "email", Rule: "required", Message: "is required"},
+			{Field: "scopes", Rule: "min", Message: "must have at least 1 item"},
+		}, p.Errors)
+	})
+
+	t.Run("Wrong JSON Type", func(t *testing.T) {
+		_, p := do(t, bind, `{"email": "alice@example.com", "scopes": ["read"], "count": "three"}`, nil)
+		assert.Equal(t, ValidationFailed, p.Code)
+		assert.Equal(t, []FieldError{{Field: "count", Rule: "type", Message: "must be an integer"}}, p.Errors)
+	})
+
+	t.Run("Malformed Body", func(t *testing.T) {
+		_, p := do(t, bind, `{"email": `, nil)
+		assert.Equal(t, InvalidRequest, p.Code)
+		assert.Equal(t, "Request body is not valid JSON", p.Detail)
+
+		_, p = do(t, bind, ``, nil)
+		assert.Equal(t, InvalidRequest, p.Code)
+		assert.Equal(t, "Request body is empty", p.Detail)
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		for _, id := range []string{"123", strings.Repeat("z", 24)} {
+			_, p := do(t, func(c *gin.Context) {
+				_, err := primitive.ObjectIDFromHex(id)
+				BadRequest(c, err)
+			}, "", nil)
+			assert.Equal(t, InvalidID, p.Code)
+		}
+	})
+	t.Run("Other Errors Stay Private", func(t *testing.T) {
+		w, p := do(t, func(c *gin.Context) {
+			BadRequest(c, errors.New("open /var/lib/excalidraw/uploads: permission denied"))
+		}, "", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, InvalidRequest, p.Code)
+		assert.Equal(t, "Invalid request", p.Detail)
+		assert.NotContains(t, w.Body.String(), "/var/lib")
+	})
+}
+
+func TestCatalogue(t *testing.T) {
+	seen := map[Code]bool{}
+	for _, entry := range Catalogue {
+		assert.False(t, seen[entry.Code], "duplicate code %s", entry.Code)
+		seen[entry.Code] = true
+		assert.NotEmpty(t, entry.Title)
+		assert.NotEmpty(t, entry.Description)
+	}
+
+	entry, ok := Lookup(RateLimited)
+	assert.True(t, ok)
+	assert.Equal(t, "Too many requests", entry.Title)
+	_, ok = Lookup("no_such_code")
+	assert.False(t, ok)
+}
@@ -0,0 +1,139 @@
+package problem
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func init() {
+	// Report fields by the names clients send, not the Go field names.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(fieldName)
+	}
+}
+
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// BadRequest responds with 400 for an error caused by the request: field
+// errors from gin's binding become validation_failed with one entry per
+// field, and malformed bodies and IDs get their own codes. Any other error
+// is logged, and the client only learns the request was invalid.
+func BadRequest(c *gin.Context, err error) {
+	var validationErrs validator.ValidationErrors
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var numErr *strconv.NumError
+	var hexErr hex.InvalidByteError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		fieldErrors := make([]FieldError, len(validationErrs))
+		for i, fe := range validationErrs {
+			fieldErrors[i] = fieldError(fe)
+		}
+		Invalid(c, fieldErrors...)
+	case errors.As(err, &typeErr):
+		Invalid(c, FieldError{Field: typeErr.Field, Rule: "type", Message: "must be " + jsonType(typeErr.Type)})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		Write(c, http.StatusBadRequest, InvalidRequest, "Request body is not valid JSON")
+	case errors.Is(err, io.EOF):
+		Write(c, http.StatusBadRequest, InvalidRequest, "Request body is empty")
+	case errors.As(err, &numErr):
+		Write(c, http.StatusBadRequest, InvalidRequest, fmt.Sprintf("%q is not a valid number", numErr.Num))
+	case errors.Is(err, primitive.ErrInvalidHex), errors.As(err, &hexErr):
+		Write(c, http.StatusBadRequest, InvalidID, "Invalid ID")
+	default:
+		log.Printf("Bad request [request %s] %s %s: %v", RequestID(c), c.Request.Method, c.Request.URL.Path, err)
+		Write(c, http.StatusBadRequest, InvalidRequest, "Invalid request")
+	}
+}
+
+// Invalid responds with validation_failed for the given fields.
+func Invalid(c *gin.Context, fieldErrors ...FieldError) {
+	details := make([]string, len(fieldErrors))
+	for i, fe := range fieldErrors {
+		details[i] = fe.Field + " " + fe.Message
+	}
+	Write(c, http.StatusBadRequest, ValidationFailed, strings.Join(details, "; "), fieldErrors...)
+}
+
+func fieldError(fe validator.FieldError) FieldError {
+	// The namespace starts with the request type's name.
+	_, field, _ := strings.Cut(fe.Namespace(), ".")
+	return FieldError{Field: field, Rule: fe.Tag(), Message: ruleMessage(fe)}
+}
+
+func ruleMessage(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "eq":
+		return "must be " + param
+	case "startswith":
+		return "must start with " + param
+	case "min", "max":
+		bound := "at least"
+		if fe.Tag() == "max" {
+			bound = "at most"
+		}
+		switch fe.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("must be %s %s characters long", bound, param)
+		case reflect.Slice, reflect.Array, reflect.Map:
+			if param == "1" {
+				return fmt.Sprintf("must have %s 1 item", bound)
+			}
+			return fmt.Sprintf("must have %s %s items", bound, param)
+		default:
+			return fmt.Sprintf("must be %s %s", bound, param)
+		}
+	}
+	return "is invalid"
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Backoff locks a key out after repeated failures. The first FreeFailures
+// failures are free; each one after that locks the key for twice as long as
+// the previous lockout, starting at BaseDelay and capped at MaxDelay. A key's
+// failures are forgotten after a success, or after ResetAfter without one.
+// Like Limiter, it keeps state in memory.
+type Backoff struct {
+	FreeFailures int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	ResetAfter   time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*backoffEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type backoffEntry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func NewBackoff(freeFailures int, baseDelay, maxDelay, resetAfter time.Duration) *Backoff {
+	return &Backoff{
+		FreeFailures: freeFailures,
+		BaseDelay:    baseDelay,
+		MaxDelay:     maxDelay,
+		ResetAfter:   resetAfter,
+		entries:      make(map[string]*backoffEntry),
+		now:          time.Now,
+	}
+}
+
+// Locked returns how long key remains locked out, or zero if it is not.
+func (b *Backoff) Locked(key string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry := b.entry(key, b.now())
+	if entry == nil {
+		return 0
+	}
+	if remaining := entry.lockedUntil.Sub(b.now()); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Fail records a failure for key and returns the lockout it caused, if any.
+func (b *Backoff) Fail(key string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.sweep(now)
+	entry := b.entry(key, now)
+	if entry == nil {
+		entry = &backoffEntry{}
+		b.entries[key] = entry
+	}
+	entry.failures++
+	entry.lastFailure = now
+
+	excess := entry.failures - b.FreeFailures
+	if excess <= 0 {
+		return 0
+	}
+	delay := b.BaseDelay
+	for i := 1; i < excess && delay < b.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > b.MaxDelay {
+		delay = b.MaxDelay
+	}
+	entry.lockedUntil = now.Add(delay)
+	return delay
+}
+
+// Succeed forgets key's failures.
+func (b *Backoff) Succeed(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.entries, key)
+}
+
+// entry returns key's state, dropping it if it has expired.
+func (b *Backoff) entry(key string, now time.Time) *backoffEntry {
+	entry, ok := b.entries[key]
+	if !ok {
+		return nil
+	}
+	if b.expired(entry, now) {
+		delete(b.entries, key)
+		return nil
+	}
+	return entry
+}
+
+func (b *Backoff) expired(entry *backoffEntry, now time.Time) bool {
+	return now.Sub(entry.lastFailure) > b.ResetAfter && !now.Before(entry.lockedUntil)
+}
+
+// sweep drops expired entries at most once per ResetAfter, so keys that are
+// never seen again do not accumulate.
+func (b *Backoff) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < b.ResetAfter {
+		return
+	}
+	b.lastSweep = now
+	for key, entry := range b.entries {
+		if b.expired(entry, now) {
+			delete(b.entries, key)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := NewBackoff(3, 30*time.Second, 2*time.Minute, time.Hour)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		assert.Zero(t, b.Fail("a"), "the first failures are free")
+	}
+	assert.Zero(t, b.Locked("a"))
+
+	assert.Equal(t, 30*time.Second, b.Fail("a"))
+	assert.Equal(t, 30*time.Second, b.Locked("a"))
+	assert.Zero(t, b.Locked("b"), "lockouts are per key")
+
+	now = now.Add(30 * time.Second)
+	assert.Zero(t, b.Locked("a"))
+	assert.Equal(t, time.Minute, b.Fail("a"), "each lockout doubles")
+	assert.Equal(t, 2*time.Minute, b.Fail("a"))
+	assert.Equal(t, 2*time.Minute, b.Fail("a"), "capped at MaxDelay")
+
+	b.Succeed("a")
+	assert.Zero(t, b.Locked("a"))
+	assert.Zero(t, b.Fail("a"), "a success resets the count")
+
+	now = now.Add(2 * time.Hour)
+	b.Fail("a")
+	b.Fail("a")
+	assert.Zero(t, b.Fail("a"), "old failures are forgotten after ResetAfter")
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows at most Limit events per key within a sliding Window. It
+// keeps state in memory, so limits apply per server instance.
+type Limiter struct {
+	Limit  int
+	Window time.Duration
+
+	mu        sync.Mutex
+	events    map[string][]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewLimiter(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		Limit:  limit,
+		Window: window,
+		events: make(map[string][]time.Time),
+		now:    time.Now,
+	}
+}
+
+// Allow records an event for key and reports whether it is within the limit.
+// When it is not, the returned duration is how long until the next event
+// would be allowed.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	recent := l.prune(key, now)
+	if len(recent) >= l.Limit {
+		return false, recent[0].Add(l.Window).Sub(now)
+	}
+	l.events[key] = append(recent, now)
+	return true, 0
+}
+
+// prune drops events outside the window and returns the remaining ones.
+func (l *Limiter) prune(key string, now time.Time) []time.Time {
+	events := l.events[key]
+	cutoff := now.Add(-l.Window)
+	i := 0
+	for i < len(events) && !events[i].After(cutoff) {
+		i++
+	}
+	events = events[i:]
+	if len(events) == 0 {
+		delete(l.events, key)
+	} else {
+		l.events[key] = events
+	}
+	return events
+}
+
+// sweep drops keys without events in the window at most once per Window, so
+// keys that are never seen again do not accumulate.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.Window {
+		return
+	}
+	l.lastSweep = now
+	cutoff := now.Add(-l.Window)
+	for key, events := range l.events {
+		if !events[len(events)-1].After(cutoff) {
+			delete(l.events, key)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.Allow("a@example.com")
+	assert.True(t, ok)
+	now = now.Add(10 * time.Second)
+	ok, _ = l.Allow("a@example.com")
+	assert.True(t, ok)
+
+	ok, retryAfter := l.Allow("a@example.com")
+	assert.False(t, ok)
+	assert.Equal(t, 50*time.Second, retryAfter)
+
+	ok, _ = l.Allow("b@example.com")
+	assert.True(t, ok, "limits are per key")
+
+	now = now.Add(51 * time.Second)
+	ok, _ = l.Allow("a@example.com")
+	assert.True(t, ok, "the oldest event has left the window")
+}
+
+func TestLimiter_SweepsIdleKeys(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for _, key := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		l.Allow(key)
+	}
+	assert.Len(t, l.events, 3)
+
+	now = now.Add(2 * time.Minute)
+	l.Allow("d@example.com")
+	assert.Len(t, l.events, 1, "keys not seen within the window are dropped")
+}
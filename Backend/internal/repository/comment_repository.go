@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CommentRepository interface {
+	Create(ctx context.Context, comment *models.Comment) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Comment, error)
+	// FindByDrawingID returns every comment on a drawing, oldest first.
+	FindByDrawingID(ctx context.Context, drawingID primitive.ObjectID) ([]*models.Comment, error)
+	UpdateBody(ctx context.Context, id primitive.ObjectID, body string) error
+	SetResolved(ctx context.Context, id primitive.ObjectID, resolved bool, by primitive.ObjectID) error
+	// Delete removes a comment together with its replies.
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	DeleteByDrawingID(ctx context.Context, drawingID primitive.ObjectID) error
+	DeleteByDrawingIDs(ctx context.Context, drawingIDs []primitive.ObjectID) error
+	// CountUnresolvedByDrawingIDs returns the number of unresolved threads per
+	// drawing. Drawings without unresolved threads are absent from the map.
+	CountUnresolvedByDrawingIDs(ctx context.Context, drawingIDs []primitive.ObjectID) (map[primitive.ObjectID]int, error)
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoCommentRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoCommentRepository(db *mongo.Database) CommentRepository {
+	return &mongoCommentRepository{
+		collection: db.Collection("comments"),
+	}
+}
+
+func (r *mongoCommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	_, err := r.collection.InsertOne(ctx, comment)
+	return err
+}
+
+func (r *mongoCommentRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
+	var comment models.Comment
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&comment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (r *mongoCommentRepository) FindByDrawingID(ctx context.Context, drawingID primitive.ObjectID) ([]*models.Comment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"drawingId": drawingID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var comments []*models.Comment
+	if err = cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (r *mongoCommentRepository) UpdateBody(ctx context.Context, id primitive.ObjectID, body string) error {
+	update := bson.M{"$set": bson.M{"body": body, "updatedAt": time.Now().UTC()}}
+	return r.updateOne(ctx, id, update)
+}
+
+func (r *mongoCommentRepository) SetResolved(ctx context.Context, id primitive.ObjectID, resolved bool, by primitive.ObjectID) error {
+	update := bson.M{
+		"$set":   bson.M{"resolved": false},
+		"$unset": bson.M{"resolvedBy": "", "resolvedAt": ""},
+	}
+	if resolved {
+		update = bson.M{"$set": bson.M{"resolved": true, "resolvedBy": by, "resolvedAt": time.Now().UTC()}}
+	}
+	return r.updateOne(ctx, id, update)
+}
+
+func (r *mongoCommentRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"$or": bson.A{bson.M{"_id": id}, bson.M{"parentId": id}}})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return &NotFoundError{Resource: "comment"}
+	}
+	return nil
+}
+
+func (r *mongoCommentRepository) DeleteByDrawingID(ctx context.Context, drawingID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"drawingId": drawingID})
+	return err
+}
+
+func (r *mongoCommentRepository) DeleteByDrawingIDs(ctx context.Context, drawingIDs []primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"drawingId": bson.M{"$in": drawingIDs}})
+	return err
+}
+
+func (r *mongoCommentRepository) CountUnresolvedByDrawingIDs(ctx context.Context, drawingIDs []primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"drawingId": bson.M{"$in": drawingIDs},
+			"parentId":  bson.M{"$exists": false},
+			"resolved":  false,
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": "$drawingId", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		DrawingID primitive.ObjectID `bson:"_id"`
+		Count     int                `bson:"count"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[primitive.ObjectID]int, len(results))
+	for _, result := range results {
+		counts[result.DrawingID] = result.Count
+	}
+	return counts, nil
+}
+
+func (r *mongoCommentRepository) updateOne(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{Resource: "comment"}
+	}
+	return nil
+}
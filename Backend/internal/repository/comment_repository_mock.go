@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockCommentRepository is an in-memory implementation of CommentRepository for testing.
+type MockCommentRepository struct {
+	Comments map[primitive.ObjectID]*models.Comment
+}
+
+func NewMockCommentRepository() *MockCommentRepository {
+	return &MockCommentRepository{
+		Comments: make(map[primitive.ObjectID]*models.Comment),
+	}
+}
+
+func (m *MockCommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	m.Comments[comment.ID] = comment
+	return nil
+}
+
+func (m *MockCommentRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
+	return m.Comments[id], nil
+}
+
+func (m *MockCommentRepository) FindByDrawingID(ctx context.Context, drawingID primitive.ObjectID) ([]*models.Comment, error) {
+	var comments []*models.Comment
+	for _, c := range m.Comments {
+		if c.DrawingID == drawingID {
+			comments = append(comments, c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if !comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+		}
+		return comments[i].ID.Hex() < comments[j].ID.Hex()
+	})
+	return comments, nil
+}
+
+func (m *MockCommentRepository) UpdateBody(ctx context.Context, id primitive.ObjectID, body string) error {
+	c, exists := m.Comments[id]
+	if !exists {
+		return &NotFoundError{Resource: "comment"}
+	}
+	c.Body = body
+	c.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func (m *MockCommentRepository) SetResolved(ctx context.Context, id primitive.ObjectID, resolved bool, by primitive.ObjectID) error {
+	c, exists := m.Comments[id]
+	if !exists {
+		return &NotFoundError{Resource: "comment"}
+	}
+	c.Resolved = resolved
+	c.ResolvedBy, c.ResolvedAt = nil, nil
+	if resolved {
+		now := time.Now().UTC()
+		c.ResolvedBy, c.ResolvedAt = &by, &now
+	}
+	return nil
+}
+
+func (m *MockCommentRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if _, exists := m.Comments[id]; !exists {
+		return &NotFoundError{Resource: "comment"}
+	}
+	for cid, c := range m.Comments {
+		if cid == id || (c.ParentID != nil && *c.ParentID == id) {
+			delete(m.Comments, cid)
+		}
+	}
+	return nil
+}
+
+func (m *MockCommentRepository) DeleteByDrawingID(ctx context.Context, drawingID primitive.ObjectID) error {
+	for id, c := range m.Comments {
+		if c.DrawingID == drawingID {
+			delete(m.Comments, id)
+		}
+	}
+	return nil
+}
+
+func (m *MockCommentRepository) DeleteByDrawingIDs(ctx context.Context, drawingIDs []primitive.ObjectID) error {
+	for _, drawingID := range drawingIDs {
+		m.DeleteByDrawingID(ctx, drawingID)
+	}
+	return nil
+}
+
+func (m *MockCommentRepository) CountUnresolvedByDrawingIDs(ctx context.Context, drawingIDs []primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+	wanted := make(map[primitive.ObjectID]bool, len(drawingIDs))
+	for _, id := range drawingIDs {
+		wanted[id] = true
+	}
+	counts := make(map[primitive.ObjectID]int)
+	for _, c := range m.Comments {
+		if wanted[c.DrawingID] && c.ParentID == nil && !c.Resolved {
+			counts[c.DrawingID]++
+		}
+	}
+	return counts, nil
+}
@@ -2,15 +2,51 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/drshn/excalidraw/Backend/internal/models"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// DrawingPatch lists the metadata to change on a drawing. Nil fields are left
+// alone; an empty folder or tag list removes it.
+type DrawingPatch struct {
+	Title     *string
+	SceneData *string
+	Folder    *string
+	Tags      *[]string
+}
+
 type DrawingRepository interface {
 	Create(ctx context.Context, drawing *models.Drawing) error
 	FindAllByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Drawing, error)
 	FindByIDAndUserID(ctx context.Context, id, userID primitive.ObjectID) (*models.Drawing, error)
+	// Update replaces the title, scene, folder and tags of a drawing.
 	Update(ctx context.Context, drawing *models.Drawing) error
+	// Patch changes only the fields set in patch and returns the drawing
+	// without its scene data.
+	Patch(ctx context.Context, id, userID primitive.ObjectID, patch DrawingPatch) (*models.Drawing, error)
+	// SetTemplate marks a drawing as a template, or unmarks it when info is nil.
+	SetTemplate(ctx context.Context, id, userID primitive.ObjectID, info *models.TemplateInfo) error
+	FindTemplatesByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Drawing, error)
+	// Delete moves a drawing to the trash. Use Purge to remove it permanently.
 	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	FindDeletedByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Drawing, error)
+	Restore(ctx context.Context, id, userID primitive.ObjectID) error
+	Purge(ctx context.Context, id, userID primitive.ObjectID) error
+	// FindIDsDeletedBefore returns the IDs of the drawings trashed before
+	// cutoff.
+	FindIDsDeletedBefore(ctx context.Context, cutoff time.Time) ([]primitive.ObjectID, error)
+	// PurgeDeleted permanently removes the drawings of ids that are still in
+	// the trash and returns how many were removed.
+	PurgeDeleted(ctx context.Context, ids []primitive.ObjectID) (int64, error)
+	// FindAllWithScenesByUserID returns every drawing of the user, including
+	// those in the trash, with their scene data.
+	FindAllWithScenesByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Drawing, error)
+	// PurgeAllByUserID permanently removes every drawing of the user.
+	PurgeAllByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	// TransferOwnership gives a drawing, wherever it is, to another user.
+	TransferOwnership(ctx context.Context, id, toUserID primitive.ObjectID) error
+	// StorageUsageByUserID reports the user's drawings and those in the trash.
+	StorageUsageByUserID(ctx context.Context, userID primitive.ObjectID) (active, trashed models.StorageUsage, err error)
 }
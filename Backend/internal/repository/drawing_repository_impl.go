@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/drshn/excalidraw/Backend/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -20,30 +21,22 @@ func NewMongoDrawingRepository(db *mongo.Database) DrawingRepository {
 	}
 }
 
+// notDeleted matches documents without a deletedAt field, i.e. drawings that
+// are not in the trash.
+var notDeleted = bson.M{"$exists": false}
+
 func (r *mongoDrawingRepository) Create(ctx context.Context, drawing *models.Drawing) error {
 	_, err := r.collection.InsertOne(ctx, drawing)
 	return err
 }
 
 func (r *mongoDrawingRepository) FindAllByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Drawing, error) {
-	// Projection to exclude the large sceneData field
-	opts := options.Find().SetProjection(bson.M{"sceneData": 0})
-	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID}, opts)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	var drawings []*models.Drawing
-	if err = cursor.All(ctx, &drawings); err != nil {
-		return nil, err
-	}
-	return drawings, nil
+	return r.findSummaries(ctx, bson.M{"userId": userID, "deletedAt": notDeleted})
 }
 
 func (r *mongoDrawingRepository) FindByIDAndUserID(ctx context.Context, id, userID primitive.ObjectID) (*models.Drawing, error) {
 	var drawing models.Drawing
-	err := r.collection.FindOne(ctx, bson.M{"_id": id, "userId": userID}).Decode(&drawing)
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "userId": userID, "deletedAt": notDeleted}).Decode(&drawing)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -54,27 +47,249 @@ func (r *mongoDrawingRepository) FindByIDAndUserID(ctx context.Context, id, user
 }
 
 func (r *mongoDrawingRepository) Update(ctx context.Context, drawing *models.Drawing) error {
-	filter := bson.M{"_id": drawing.ID, "userId": drawing.UserID}
-	update := bson.M{"$set": bson.M{"title": drawing.Title, "sceneData": drawing.SceneData}}
+	filter := bson.M{"_id": drawing.ID, "userId": drawing.UserID, "deletedAt": notDeleted}
+	folder, tags := drawing.Folder, drawing.Tags
+	update := patchUpdate(DrawingPatch{Title: &drawing.Title, SceneData: &drawing.SceneData, Folder: &folder, Tags: &tags})
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
 	}
 	if result.MatchedCount == 0 {
-		return mongo.ErrNoDocuments // Or a custom not found error
+		return &NotFoundError{Resource: "drawing"}
 	}
 	return nil
 }
 
+func (r *mongoDrawingRepository) Patch(ctx context.Context, id, userID primitive.ObjectID, patch DrawingPatch) (*models.Drawing, error) {
+	filter := bson.M{"_id": id, "userId": userID, "deletedAt": notDeleted}
+	projection := bson.M{"sceneData": 0}
+
+	var drawing models.Drawing
+	var err error
+	if update := patchUpdate(patch); len(update) == 0 {
+		err = r.collection.FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&drawing)
+	} else {
+		opts := options.FindOneAndUpdate().SetProjection(projection).SetReturnDocument(options.After)
+		err = r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&drawing)
+	}
+	if err == mongo.ErrNoDocuments {
+		return nil, &NotFoundError{Resource: "drawing"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &drawing, nil
+}
+
+// patchUpdate builds the update document for patch, unsetting an emptied
+// folder or tag list rather than storing an empty value.
+func patchUpdate(patch DrawingPatch) bson.M {
+	set, unset := bson.M{}, bson.M{}
+	if patch.Title != nil {
+		set["title"] = *patch.Title
+	}
+	if patch.SceneData != nil {
+		set["sceneData"] = *patch.SceneData
+	}
+	if patch.Folder != nil {
+		if *patch.Folder == "" {
+			unset["folder"] = ""
+		} else {
+			set["folder"] = *patch.Folder
+		}
+	}
+	if patch.Tags != nil {
+		if len(*patch.Tags) == 0 {
+			unset["tags"] = ""
+		} else {
+			set["tags"] = *patch.Tags
+		}
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+func (r *mongoDrawingRepository) SetTemplate(ctx context.Context, id, userID primitive.ObjectID, info *models.TemplateInfo) error {
+	filter := bson.M{"_id": id, "userId": userID, "deletedAt": notDeleted}
+	update := bson.M{"$set": bson.M{"template": info}}
+	if info == nil {
+		update = bson.M{"$unset": bson.M{"template": ""}}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{Resource: "drawing"}
+	}
+	return nil
+}
+
+func (r *mongoDrawingRepository) FindTemplatesByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Drawing, error) {
+	return r.findSummaries(ctx, bson.M{"userId": userID, "template": bson.M{"$exists": true}, "deletedAt": notDeleted})
+}
+
 func (r *mongoDrawingRepository) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
+	filter := bson.M{"_id": id, "userId": userID, "deletedAt": notDeleted}
+	update := bson.M{"$set": bson.M{"deletedAt": time.Now().UTC()}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{Resource: "drawing"}
+	}
+	return nil
+}
+
+func (r *mongoDrawingRepository) FindDeletedByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Drawing, error) {
+	return r.findSummaries(ctx, bson.M{"userId": userID, "deletedAt": bson.M{"$exists": true}})
+}
+
+func (r *mongoDrawingRepository) Restore(ctx context.Context, id, userID primitive.ObjectID) error {
+	filter := bson.M{"_id": id, "userId": userID, "deletedAt": bson.M{"$exists": true}}
+	update := bson.M{"$unset": bson.M{"deletedAt": ""}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{Resource: "drawing"}
+	}
+	return nil
+}
+
+func (r *mongoDrawingRepository) Purge(ctx context.Context, id, userID primitive.ObjectID) error {
 	filter := bson.M{"_id": id, "userId": userID}
 	result, err := r.collection.DeleteOne(ctx, filter)
 	if err != nil {
 		return err
 	}
 	if result.DeletedCount == 0 {
-		return mongo.ErrNoDocuments // Or a custom not found error
+		return &NotFoundError{Resource: "drawing"}
 	}
 	return nil
 }
+
+func (r *mongoDrawingRepository) FindIDsDeletedBefore(ctx context.Context, cutoff time.Time) ([]primitive.ObjectID, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := r.collection.Find(ctx, bson.M{"deletedAt": bson.M{"$lt": cutoff}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var drawings []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &drawings); err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(drawings))
+	for i, d := range drawings {
+		ids[i] = d.ID
+	}
+	return ids, nil
+}
+
+func (r *mongoDrawingRepository) PurgeDeleted(ctx context.Context, ids []primitive.ObjectID) (int64, error) {
+	filter := bson.M{"_id": bson.M{"$in": ids}, "deletedAt": bson.M{"$ne": nil}}
+	result, err := r.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *mongoDrawingRepository) FindAllWithScenesByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Drawing, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var drawings []*models.Drawing
+	if err = cursor.All(ctx, &drawings); err != nil {
+		return nil, err
+	}
+	return drawings, nil
+}
+
+func (r *mongoDrawingRepository) PurgeAllByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *mongoDrawingRepository) TransferOwnership(ctx context.Context, id, toUserID primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"userId": toUserID}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{Resource: "drawing"}
+	}
+	return nil
+}
+
+func (r *mongoDrawingRepository) StorageUsageByUserID(ctx context.Context, userID primitive.ObjectID) (active, trashed models.StorageUsage, err error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userId": userID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$gt": bson.A{"$deletedAt", nil}},
+			"count": bson.M{"$sum": 1},
+			"bytes": bson.M{"$sum": bson.M{"$bsonSize": "$$ROOT"}},
+		}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return active, trashed, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Trashed             bool `bson:"_id"`
+		models.StorageUsage `bson:",inline"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil {
+		return active, trashed, err
+	}
+	for _, group := range groups {
+		if group.Trashed {
+			trashed = group.StorageUsage
+		} else {
+			active = group.StorageUsage
+		}
+	}
+	return active, trashed, nil
+}
+
+// findSummaries returns the drawings matching filter without their scene data.
+func (r *mongoDrawingRepository) findSummaries(ctx context.Context, filter bson.M) ([]*models.Drawing, error) {
+	// Projection to exclude the large sceneData field
+	opts := options.Find().SetProjection(bson.M{"sceneData": 0})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var drawings []*models.Drawing
+	if err = cursor.All(ctx, &drawings); err != nil {
+		return nil, err
+	}
+	return drawings, nil
+}
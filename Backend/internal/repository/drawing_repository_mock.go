@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockDrawingRepository is an in-memory implementation of DrawingRepository for testing.
+type MockDrawingRepository struct {
+	Drawings map[primitive.ObjectID]*models.Drawing
+}
+
+func NewMockDrawingRepository() *MockDrawingRepository {
+	return &MockDrawingRepository{
+		Drawings: make(map[primitive.ObjectID]*models.Drawing),
+	}
+}
+
+func (m *MockDrawingRepository) Create(ctx context.Context, drawing *models.Drawing) error {
+	m.Drawings[drawing.ID] = drawing
+	return nil
+}
+
+func (m *MockDrawingRepository) FindAllByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Drawing, error) {
+	var drawings []*models.Drawing
+	for _, d := range m.Drawings {
+		if d.UserID == userID && d.DeletedAt == nil {
+			drawings = append(drawings, d)
+		}
+	}
+	return drawings, nil
+}
+
+func (m *MockDrawingRepository) FindByIDAndUserID(ctx context.Context, id, userID primitive.ObjectID) (*models.Drawing, error) {
+	d, exists := m.Drawings[id]
+	if !exists || d.UserID != userID || d.DeletedAt != nil {
+		return nil, nil
+	}
+	return d, nil
+}
+
+func (m *MockDrawingRepository) Update(ctx context.Context, drawing *models.Drawing) error {
+	d, exists := m.Drawings[drawing.ID]
+	if !exists || d.UserID != drawing.UserID || d.DeletedAt != nil {
+		return &NotFoundError{Resource: "drawing"}
+	}
+	d.Title = drawing.Title
+	d.SceneData = drawing.SceneData
+	d.Folder = drawing.Folder
+	d.Tags = drawing.Tags
+	return nil
+}
+
+func (m *MockDrawingRepository) Patch(ctx context.Context, id, userID primitive.ObjectID, patch DrawingPatch) (*models.Drawing, error) {
+	d, exists := m.Drawings[id]
+	if !exists || d.UserID != userID || d.DeletedAt != nil {
+		return nil, &NotFoundError{Resource: "drawing"}
+	}
+	if patch.Title != nil {
+		d.Title = *patch.Title
+	}
+	if patch.SceneData != nil {
+		d.SceneData = *patch.SceneData
+	}
+	if patch.Folder != nil {
+		d.Folder = *patch.Folder
+	}
+	if patch.Tags != nil {
+		d.Tags = *patch.Tags
+		if len(d.Tags) == 0 {
+			d.Tags = nil
+		}
+	}
+	summary := *d
+	summary.SceneData = ""
+	return &summary, nil
+}
+
+func (m *MockDrawingRepository) SetTemplate(ctx context.Context, id, userID primitive.ObjectID, info *models.TemplateInfo) error {
+	d, exists := m.Drawings[id]
+	if !exists || d.UserID != userID || d.DeletedAt != nil {
+		return &NotFoundError{Resource: "drawing"}
+	}
+	d.Template = info
+	return nil
+}
+
+func (m *MockDrawingRepository) FindTemplatesByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Drawing, error) {
+	var drawings []*models.Drawing
+	for _, d := range m.Drawings {
+		if d.UserID == userID && d.Template != nil && d.DeletedAt == nil {
+			drawings = append(drawings, d)
+		}
+	}
+	return drawings, nil
+}
+
+func (m *MockDrawingRepository) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
+	d, exists := m.Drawings[id]
+	if !exists || d.UserID != userID || d.DeletedAt != nil {
+		return &NotFoundError{Resource: "drawing"}
+	}
+	now := time.Now().UTC()
+	d.DeletedAt = &now
+	return nil
+}
+
+func (m *MockDrawingRepository) FindDeletedByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Drawing, error) {
+	var drawings []*models.Drawing
+	for _, d := range m.Drawings {
+		if d.UserID == userID && d.DeletedAt != nil {
+			drawings = append(drawings, d)
+		}
+	}
+	return drawings, nil
+}
+
+func (m *MockDrawingRepository) Restore(ctx context.Context, id, userID primitive.ObjectID) error {
+	d, exists := m.Drawings[id]
+	if !exists || d.UserID != userID || d.DeletedAt == nil {
+		return &NotFoundError{Resource: "drawing"}
+	}
+	d.DeletedAt = nil
+	return nil
+}
+
+func (m *MockDrawingRepository) Purge(ctx context.Context, id, userID primitive.ObjectID) error {
+	d, exists := m.Drawings[id]
+	if !exists || d.UserID != userID {
+		return &NotFoundError{Resource: "drawing"}
+	}
+	delete(m.Drawings, id)
+	return nil
+}
+
+func (m *MockDrawingRepository) FindIDsDeletedBefore(ctx context.Context, cutoff time.Time) ([]primitive.ObjectID, error) {
+	var ids []primitive.ObjectID
+	for id, d := range m.Drawings {
+		if d.DeletedAt != nil && d.DeletedAt.Before(cutoff) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *MockDrawingRepository) PurgeDeleted(ctx context.Context, ids []primitive.ObjectID) (int64, error) {
+	var n int64
+	for _, id := range ids {
+		if d, exists := m.Drawings[id]; exists && d.DeletedAt != nil {
+			delete(m.Drawings, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MockDrawingRepository) FindAllWithScenesByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Drawing, error) {
+	var drawings []*models.Drawing
+	for _, d := range m.Drawings {
+		if d.UserID == userID {
+			drawings = append(drawings, d)
+		}
+	}
+	return drawings, nil
+}
+
+func (m *MockDrawingRepository) PurgeAllByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	var n int64
+	for id, d := range m.Drawings {
+		if d.UserID == userID {
+			delete(m.Drawings, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MockDrawingRepository) TransferOwnership(ctx context.Context, id, toUserID primitive.ObjectID) error {
+	d, exists := m.Drawings[id]
+	if !exists {
+		return &NotFoundError{Resource: "drawing"}
+	}
+	d.UserID = toUserID
+	return nil
+}
+
+// StorageUsageByUserID counts the length of the scene data as the size.
+func (m *MockDrawingRepository) StorageUsageByUserID(ctx context.Context, userID primitive.ObjectID) (active, trashed models.StorageUsage, err error) {
+	for _, d := range m.Drawings {
+		if d.UserID != userID {
+			continue
+		}
+		usage := &active
+		if d.DeletedAt != nil {
+			usage = &trashed
+		}
+		usage.Count++
+		usage.Bytes += int64(len(d.SceneData))
+	}
+	return active, trashed, nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NotFoundError is returned when a document to update or delete does not
+// exist. Repositories scoped to a user also return it for documents that
+// belong to someone else, so their existence is not revealed. Find methods
+// return a nil document instead.
+type NotFoundError struct {
+	// Resource names the kind of document, such as "drawing".
+	Resource string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Resource + " not found"
+}
+
+// ConflictError is returned when a write would break a uniqueness rule.
+type ConflictError struct {
+	Resource string
+	// Field is the unique field that clashed, when known.
+	Field string
+}
+
+func (e *ConflictError) Error() string {
+	if e.Field == "" {
+		return e.Resource + " already exists"
+	}
+	return e.Resource + " with this " + e.Field + " already exists"
+}
+
+// ForbiddenError is returned when a document is visible to the caller but
+// the change is not allowed.
+type ForbiddenError struct {
+	Resource string
+	Reason   string
+}
+
+func (e *ForbiddenError) Error() string {
+	if e.Reason == "" {
+		return "not allowed to change this " + e.Resource
+	}
+	return e.Reason
+}
+
+// conflictOnDuplicateKey turns a unique index violation into a ConflictError.
+func conflictOnDuplicateKey(err error, resource, field string) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return &ConflictError{Resource: resource, Field: field}
+	}
+	return err
+}
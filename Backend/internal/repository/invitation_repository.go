@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type InvitationRepository interface {
+	Create(ctx context.Context, invitation *models.Invitation) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Invitation, error)
+	FindByHash(ctx context.Context, codeHash string) (*models.Invitation, error)
+	// FindAllByCreator lists the invitations a user has issued, newest first.
+	FindAllByCreator(ctx context.Context, userID primitive.ObjectID) ([]*models.Invitation, error)
+	// Consume marks an invitation as used by a new user. It returns false
+	// if the invitation was already used.
+	Consume(ctx context.Context, id, usedBy primitive.ObjectID) (bool, error)
+	// Release makes a consumed invitation usable again, for when creating
+	// the account fails.
+	Release(ctx context.Context, id primitive.ObjectID) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// DeleteAllByCreator removes every invitation a user has issued, used
+	// or not.
+	DeleteAllByCreator(ctx context.Context, userID primitive.ObjectID) error
+}
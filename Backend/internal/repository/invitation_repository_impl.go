@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoInvitationRepository struct {
+	collection *mongo.Collection
+}
+
+var invitationIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "codeHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+	{Keys: bson.D{{Key: "createdBy", Value: 1}, {Key: "createdAt", Value: -1}}},
+	{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+}
+
+func NewMongoInvitationRepository(db *mongo.Database) InvitationRepository {
+	collection := db.Collection("invitations")
+	_, err := collection.Indexes().CreateMany(context.Background(), invitationIndexes)
+	if err != nil {
+		log.Printf("Failed to create invitation indexes: %v", err)
+	}
+	return &mongoInvitationRepository{collection: collection}
+}
+
+func (r *mongoInvitationRepository) Create(ctx context.Context, invitation *models.Invitation) error {
+	_, err := r.collection.InsertOne(ctx, invitation)
+	return err
+}
+
+func (r *mongoInvitationRepository) findOne(ctx context.Context, filter bson.M) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.collection.FindOne(ctx, filter).Decode(&invitation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *mongoInvitationRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Invitation, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoInvitationRepository) FindByHash(ctx context.Context, codeHash string) (*models.Invitation, error) {
+	return r.findOne(ctx, bson.M{"codeHash": codeHash})
+}
+
+func (r *mongoInvitationRepository) FindAllByCreator(ctx context.Context, userID primitive.ObjectID) ([]*models.Invitation, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"createdBy": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	invitations := []*models.Invitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (r *mongoInvitationRepository) Consume(ctx context.Context, id, usedBy primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": id, "usedAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"usedAt": time.Now().UTC(), "usedBy": usedBy}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (r *mongoInvitationRepository) Release(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$unset": bson.M{"usedAt": "", "usedBy": ""}})
+	return err
+}
+
+func (r *mongoInvitationRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return &NotFoundError{Resource: "invitation"}
+	}
+	return nil
+}
+
+func (r *mongoInvitationRepository) DeleteAllByCreator(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"createdBy": userID})
+	return err
+}
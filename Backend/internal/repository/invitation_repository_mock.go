@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockInvitationRepository is an in-memory implementation of InvitationRepository for testing.
+type MockInvitationRepository struct {
+	Invitations map[primitive.ObjectID]*models.Invitation
+}
+
+func NewMockInvitationRepository() *MockInvitationRepository {
+	return &MockInvitationRepository{
+		Invitations: make(map[primitive.ObjectID]*models.Invitation),
+	}
+}
+
+func (m *MockInvitationRepository) Create(ctx context.Context, invitation *models.Invitation) error {
+	m.Invitations[invitation.ID] = invitation
+	return nil
+}
+
+func (m *MockInvitationRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Invitation, error) {
+	return m.Invitations[id], nil
+}
+
+func (m *MockInvitationRepository) FindByHash(ctx context.Context, codeHash string) (*models.Invitation, error) {
+	for _, inv := range m.Invitations {
+		if inv.CodeHash == codeHash {
+			return inv, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockInvitationRepository) FindAllByCreator(ctx context.Context, userID primitive.ObjectID) ([]*models.Invitation, error) {
+	invitations := []*models.Invitation{}
+	for _, inv := range m.Invitations {
+		if inv.CreatedBy == userID {
+			invitations = append(invitations, inv)
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool {
+		return invitations[i].CreatedAt.After(invitations[j].CreatedAt)
+	})
+	return invitations, nil
+}
+
+func (m *MockInvitationRepository) Consume(ctx context.Context, id, usedBy primitive.ObjectID) (bool, error) {
+	inv, exists := m.Invitations[id]
+	if !exists || inv.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	inv.UsedAt, inv.UsedBy = &now, &usedBy
+	return true, nil
+}
+
+func (m *MockInvitationRepository) Release(ctx context.Context, id primitive.ObjectID) error {
+	if inv, exists := m.Invitations[id]; exists {
+		inv.UsedAt, inv.UsedBy = nil, nil
+	}
+	return nil
+}
+
+func (m *MockInvitationRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if _, exists := m.Invitations[id]; !exists {
+		return &NotFoundError{Resource: "invitation"}
+	}
+	delete(m.Invitations, id)
+	return nil
+}
+
+func (m *MockInvitationRepository) DeleteAllByCreator(ctx context.Context, userID primitive.ObjectID) error {
+	for id, inv := range m.Invitations {
+		if inv.CreatedBy == userID {
+			delete(m.Invitations, id)
+		}
+	}
+	return nil
+}
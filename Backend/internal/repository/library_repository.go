@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LibraryRepository interface {
+	Create(ctx context.Context, library *models.Library) error
+	// FindAllByUserID returns the user's libraries without their items.
+	FindAllByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Library, error)
+	FindByIDAndUserID(ctx context.Context, id, userID primitive.ObjectID) (*models.Library, error)
+	// Update replaces the name of a library.
+	Update(ctx context.Context, library *models.Library) error
+	// AddItem appends item unless the library already holds an item with
+	// the same hash or ID, and reports whether it did. Items are added and
+	// deleted one at a time so concurrent changes keep each other's items.
+	AddItem(ctx context.Context, id, userID primitive.ObjectID, item models.LibraryItem) (bool, error)
+	// DeleteItem removes the item with itemID.
+	DeleteItem(ctx context.Context, id, userID primitive.ObjectID, itemID string) error
+	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	DeleteAllByUserID(ctx context.Context, userID primitive.ObjectID) error
+	StorageUsageByUserID(ctx context.Context, userID primitive.ObjectID) (models.StorageUsage, error)
+}
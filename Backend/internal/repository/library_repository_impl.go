@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoLibraryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoLibraryRepository(db *mongo.Database) LibraryRepository {
+	return &mongoLibraryRepository{
+		collection: db.Collection("libraries"),
+	}
+}
+
+func (r *mongoLibraryRepository) Create(ctx context.Context, library *models.Library) error {
+	_, err := r.collection.InsertOne(ctx, library)
+	return err
+}
+
+func (r *mongoLibraryRepository) FindAllByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Library, error) {
+	opts := options.Find().SetProjection(bson.M{"items": 0})
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var libraries []*models.Library
+	if err = cursor.All(ctx, &libraries); err != nil {
+		return nil, err
+	}
+	return libraries, nil
+}
+
+func (r *mongoLibraryRepository) FindByIDAndUserID(ctx context.Context, id, userID primitive.ObjectID) (*models.Library, error) {
+	var library models.Library
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "userId": userID}).Decode(&library)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &library, nil
+}
+
+func (r *mongoLibraryRepository) Update(ctx context.Context, library *models.Library) error {
+	library.UpdatedAt = time.Now().UTC()
+	filter := bson.M{"_id": library.ID, "userId": library.UserID}
+	update := bson.M{"$set": bson.M{"name": library.Name, "updatedAt": library.UpdatedAt}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{Resource: "library"}
+	}
+	return nil
+}
+
+func (r *mongoLibraryRepository) AddItem(ctx context.Context, id, userID primitive.ObjectID, item models.LibraryItem) (bool, error) {
+	filter := bson.M{
+		"_id":        id,
+		"userId":     userID,
+		"items.hash": bson.M{"$ne": item.Hash},
+		"items.id":   bson.M{"$ne": item.ID},
+	}
+	update := bson.M{
+		"$push": bson.M{"items": item},
+		"$set":  bson.M{"updatedAt": time.Now().UTC()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	if result.MatchedCount > 0 {
+		return true, nil
+	}
+
+	// Either the item clashes with one already there or the library is gone.
+	n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id, "userId": userID})
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, &NotFoundError{Resource: "library"}
+	}
+	return false, nil
+}
+
+func (r *mongoLibraryRepository) DeleteItem(ctx context.Context, id, userID primitive.ObjectID, itemID string) error {
+	filter := bson.M{"_id": id, "userId": userID, "items.id": itemID}
+	update := bson.M{
+		"$pull": bson.M{"items": bson.M{"id": itemID}},
+		"$set":  bson.M{"updatedAt": time.Now().UTC()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{Resource: "library item"}
+	}
+	return nil
+}
+
+func (r *mongoLibraryRepository) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "userId": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return &NotFoundError{Resource: "library"}
+	}
+	return nil
+}
+
+func (r *mongoLibraryRepository) DeleteAllByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
+
+func (r *mongoLibraryRepository) StorageUsageByUserID(ctx context.Context, userID primitive.ObjectID) (models.StorageUsage, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userId": userID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   nil,
+			"count": bson.M{"$sum": 1},
+			"bytes": bson.M{"$sum": bson.M{"$bsonSize": "$$ROOT"}},
+		}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return models.StorageUsage{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var usage []models.StorageUsage
+	if err = cursor.All(ctx, &usage); err != nil || len(usage) == 0 {
+		return models.StorageUsage{}, err
+	}
+	return usage[0], nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockLibraryRepository is an in-memory implementation of LibraryRepository for testing.
+type MockLibraryRepository struct {
+	Libraries map[primitive.ObjectID]*models.Library
+}
+
+func NewMockLibraryRepository() *MockLibraryRepository {
+	return &MockLibraryRepository{
+		Libraries: make(map[primitive.ObjectID]*models.Library),
+	}
+}
+
+func (m *MockLibraryRepository) Create(ctx context.Context, library *models.Library) error {
+	m.Libraries[library.ID] = library
+	return nil
+}
+
+func (m *MockLibraryRepository) FindAllByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Library, error) {
+	var libraries []*models.Library
+	for _, l := range m.Libraries {
+		if l.UserID == userID {
+			summary := *l
+			summary.Items = nil
+			libraries = append(libraries, &summary)
+		}
+	}
+	return libraries, nil
+}
+
+func (m *MockLibraryRepository) FindByIDAndUserID(ctx context.Context, id, userID primitive.ObjectID) (*models.Library, error) {
+	l, exists := m.Libraries[id]
+	if !exists || l.UserID != userID {
+		return nil, nil
+	}
+	copied := *l
+	return &copied, nil
+}
+
+func (m *MockLibraryRepository) Update(ctx context.Context, library *models.Library) error {
+	l, exists := m.Libraries[library.ID]
+	if !exists || l.UserID != library.UserID {
+		return &NotFoundError{Resource: "library"}
+	}
+	l.Name = library.Name
+	return nil
+}
+
+func (m *MockLibraryRepository) AddItem(ctx context.Context, id, userID primitive.ObjectID, item models.LibraryItem) (bool, error) {
+	l, exists := m.Libraries[id]
+	if !exists || l.UserID != userID {
+		return false, &NotFoundError{Resource: "library"}
+	}
+	for _, existing := range l.Items {
+		if existing.Hash == item.Hash || existing.ID == item.ID {
+			return false, nil
+		}
+	}
+	l.Items = append(l.Items, item)
+	return true, nil
+}
+
+func (m *MockLibraryRepository) DeleteItem(ctx context.Context, id, userID primitive.ObjectID, itemID string) error {
+	l, exists := m.Libraries[id]
+	if !exists || l.UserID != userID {
+		return &NotFoundError{Resource: "library item"}
+	}
+	kept := l.Items[:0:0]
+	for _, item := range l.Items {
+		if item.ID != itemID {
+			kept = append(kept, item)
+		}
+	}
+	if len(kept) == len(l.Items) {
+		return &NotFoundError{Resource: "library item"}
+	}
+	l.Items = kept
+	return nil
+}
+
+func (m *MockLibraryRepository) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
+	l, exists := m.Libraries[id]
+	if !exists || l.UserID != userID {
+		return &NotFoundError{Resource: "library"}
+	}
+	delete(m.Libraries, id)
+	return nil
+}
+
+func (m *MockLibraryRepository) DeleteAllByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	for id, l := range m.Libraries {
+		if l.UserID == userID {
+			delete(m.Libraries, id)
+		}
+	}
+	return nil
+}
+
+// StorageUsageByUserID counts the size of each library as JSON.
+func (m *MockLibraryRepository) StorageUsageByUserID(ctx context.Context, userID primitive.ObjectID) (models.StorageUsage, error) {
+	var usage models.StorageUsage
+	for _, l := range m.Libraries {
+		if l.UserID == userID {
+			usage.Count++
+			data, _ := json.Marshal(l)
+			usage.Bytes += int64(len(data))
+		}
+	}
+	return usage, nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Indexes on collections whose repositories do not create them, because
+// they can fail on existing data (such as duplicate emails). Migrate creates
+// them, and the server runs it before serving.
+var (
+	userIndexes = []mongo.IndexModel{
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}}},
+	}
+	drawingIndexes = []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "deletedAt", Value: 1}}},
+		{Keys: bson.D{{Key: "deletedAt", Value: 1}}, Options: options.Index().SetSparse(true)},
+	}
+	commentIndexes = []mongo.IndexModel{
+		{Keys: bson.D{{Key: "drawingId", Value: 1}, {Key: "createdAt", Value: 1}}},
+	}
+	libraryIndexes = []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+	}
+)
+
+// migrations lists the indexes of every collection.
+var migrations = []struct {
+	collection string
+	indexes    []mongo.IndexModel
+}{
+	{"users", userIndexes},
+	{"drawings", drawingIndexes},
+	{"comments", commentIndexes},
+	{"libraries", libraryIndexes},
+	{"refresh_tokens", refreshTokenIndexes},
+	{"revoked_tokens", revocationIndexes},
+	{"sessions", sessionIndexes},
+	{"user_tokens", userTokenIndexes},
+	{"oidc_states", oidcStateIndexes},
+	{"personal_access_tokens", personalAccessTokenIndexes},
+	{"invitations", invitationIndexes},
+}
+
+// backfills set fields on documents written before the fields existed. Each
+// only matches documents without the field, so it is safe to rerun.
+var backfills = []struct {
+	collection, field string
+	filter, update    bson.M
+}{
+	// Accounts from before email verification were never sent a link, so
+	// REQUIRE_EMAIL_VERIFICATION would lock them out of creating drawings.
+	{"users", "emailVerified", bson.M{"emailVerified": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"emailVerified": true}}},
+}
+
+// Migrate creates the indexes of every collection and backfills fields on
+// old documents, reporting each step to progress. Unlike the repository
+// constructors, which only log failures, it stops at the first error. It is
+// safe to run repeatedly.
+func Migrate(ctx context.Context, db *mongo.Database, progress func(step string)) error {
+	report := func(step string) {
+		if progress != nil {
+			progress(step)
+		}
+	}
+
+	for _, m := range migrations {
+		report("Creating " + m.collection + " indexes")
+		if _, err := db.Collection(m.collection).Indexes().CreateMany(ctx, m.indexes); err != nil {
+			return fmt.Errorf("creating %s indexes: %w", m.collection, err)
+		}
+	}
+	for _, b := range backfills {
+		report("Backfilling " + b.collection + "." + b.field)
+		if _, err := db.Collection(b.collection).UpdateMany(ctx, b.filter, b.update); err != nil {
+			return fmt.Errorf("backfilling %s.%s: %w", b.collection, b.field, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+)
+
+type OIDCStateRepository interface {
+	Create(ctx context.Context, state *models.OIDCLoginState) error
+	// Consume deletes and returns the pending login with the given state
+	// hash, or returns nil if there is none.
+	Consume(ctx context.Context, stateHash string) (*models.OIDCLoginState, error)
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoOIDCStateRepository struct {
+	collection *mongo.Collection
+}
+
+var oidcStateIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "stateHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+	{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+}
+
+func NewMongoOIDCStateRepository(db *mongo.Database) OIDCStateRepository {
+	collection := db.Collection("oidc_states")
+	_, err := collection.Indexes().CreateMany(context.Background(), oidcStateIndexes)
+	if err != nil {
+		log.Printf("Failed to create OIDC state indexes: %v", err)
+	}
+	return &mongoOIDCStateRepository{collection: collection}
+}
+
+func (r *mongoOIDCStateRepository) Create(ctx context.Context, state *models.OIDCLoginState) error {
+	_, err := r.collection.InsertOne(ctx, state)
+	return err
+}
+
+func (r *mongoOIDCStateRepository) Consume(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	var state models.OIDCLoginState
+	err := r.collection.FindOneAndDelete(ctx, bson.M{"stateHash": stateHash}).Decode(&state)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &state, nil
+}
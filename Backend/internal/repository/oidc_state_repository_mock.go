@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+)
+
+// MockOIDCStateRepository is an in-memory implementation of OIDCStateRepository for testing.
+type MockOIDCStateRepository struct {
+	mu     sync.Mutex
+	States map[string]*models.OIDCLoginState
+}
+
+func NewMockOIDCStateRepository() *MockOIDCStateRepository {
+	return &MockOIDCStateRepository{
+		States: make(map[string]*models.OIDCLoginState),
+	}
+}
+
+func (m *MockOIDCStateRepository) Create(ctx context.Context, state *models.OIDCLoginState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.States[state.StateHash] = state
+	return nil
+}
+
+func (m *MockOIDCStateRepository) Consume(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, exists := m.States[stateHash]
+	if !exists {
+		return nil, nil
+	}
+	delete(m.States, stateHash)
+	return state, nil
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PersonalAccessTokenRepository interface {
+	Create(ctx context.Context, token *models.PersonalAccessToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error)
+	FindAllByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.PersonalAccessToken, error)
+	Delete(ctx context.Context, id, userID primitive.ObjectID) error
+	DeleteAllByUserID(ctx context.Context, userID primitive.ObjectID) error
+	TouchLastUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error
+}
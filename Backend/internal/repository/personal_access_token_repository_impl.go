@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoPersonalAccessTokenRepository struct {
+	collection *mongo.Collection
+}
+
+var personalAccessTokenIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+	{Keys: bson.D{{Key: "userId", Value: 1}}},
+	{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+}
+
+func NewMongoPersonalAccessTokenRepository(db *mongo.Database) PersonalAccessTokenRepository {
+	collection := db.Collection("personal_access_tokens")
+	_, err := collection.Indexes().CreateMany(context.Background(), personalAccessTokenIndexes)
+	if err != nil {
+		log.Printf("Failed to create personal access token indexes: %v", err)
+	}
+	return &mongoPersonalAccessTokenRepository{collection: collection}
+}
+
+func (r *mongoPersonalAccessTokenRepository) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+func (r *mongoPersonalAccessTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	err := r.collection.FindOne(ctx, bson.M{"tokenHash": tokenHash}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *mongoPersonalAccessTokenRepository) FindAllByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.PersonalAccessToken, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tokens := []*models.PersonalAccessToken{}
+	if err = cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *mongoPersonalAccessTokenRepository) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "userId": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return &NotFoundError{Resource: "token"}
+	}
+	return nil
+}
+
+func (r *mongoPersonalAccessTokenRepository) DeleteAllByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
+
+func (r *mongoPersonalAccessTokenRepository) TouchLastUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$max": bson.M{"lastUsedAt": at}})
+	return err
+}
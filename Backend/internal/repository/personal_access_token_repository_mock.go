@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockPersonalAccessTokenRepository is an in-memory implementation of PersonalAccessTokenRepository for testing.
+type MockPersonalAccessTokenRepository struct {
+	mu     sync.Mutex
+	Tokens map[primitive.ObjectID]*models.PersonalAccessToken
+}
+
+func NewMockPersonalAccessTokenRepository() *MockPersonalAccessTokenRepository {
+	return &MockPersonalAccessTokenRepository{
+		Tokens: make(map[primitive.ObjectID]*models.PersonalAccessToken),
+	}
+}
+
+func (m *MockPersonalAccessTokenRepository) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Tokens[token.ID] = token
+	return nil
+}
+
+func (m *MockPersonalAccessTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.Tokens {
+		if t.TokenHash == tokenHash {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockPersonalAccessTokenRepository) FindAllByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.PersonalAccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tokens := []*models.PersonalAccessToken{}
+	for _, t := range m.Tokens {
+		if t.UserID == userID {
+			tokens = append(tokens, t)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.After(tokens[j].CreatedAt) })
+	return tokens, nil
+}
+
+func (m *MockPersonalAccessTokenRepository) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, exists := m.Tokens[id]
+	if !exists || t.UserID != userID {
+		return &NotFoundError{Resource: "token"}
+	}
+	delete(m.Tokens, id)
+	return nil
+}
+
+func (m *MockPersonalAccessTokenRepository) DeleteAllByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, t := range m.Tokens {
+		if t.UserID == userID {
+			delete(m.Tokens, id)
+		}
+	}
+	return nil
+}
+
+func (m *MockPersonalAccessTokenRepository) TouchLastUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, exists := m.Tokens[id]; exists && (t.LastUsedAt == nil || at.After(*t.LastUsedAt)) {
+		t.LastUsedAt = &at
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// MarkUsed records that a token has been exchanged. It returns false if
+	// the token was already used or revoked.
+	MarkUsed(ctx context.Context, id primitive.ObjectID) (bool, error)
+	RevokeFamily(ctx context.Context, familyID primitive.ObjectID) error
+	// RevokeAllByUserID revokes every active token of the user and returns the
+	// affected families.
+	RevokeAllByUserID(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error)
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRefreshTokenRepository struct {
+	collection *mongo.Collection
+}
+
+var refreshTokenIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+	{Keys: bson.D{{Key: "familyId", Value: 1}}},
+	{Keys: bson.D{{Key: "userId", Value: 1}}},
+	// Expired tokens are removed by MongoDB's TTL monitor.
+	{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+}
+
+func NewMongoRefreshTokenRepository(db *mongo.Database) RefreshTokenRepository {
+	collection := db.Collection("refresh_tokens")
+	_, err := collection.Indexes().CreateMany(context.Background(), refreshTokenIndexes)
+	if err != nil {
+		log.Printf("Failed to create refresh token indexes: %v", err)
+	}
+	return &mongoRefreshTokenRepository{collection: collection}
+}
+
+func (r *mongoRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+func (r *mongoRefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.collection.FindOne(ctx, bson.M{"tokenHash": tokenHash}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *mongoRefreshTokenRepository) MarkUsed(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": id, "usedAt": bson.M{"$exists": false}, "revokedAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"usedAt": time.Now().UTC()}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (r *mongoRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID primitive.ObjectID) error {
+	filter := bson.M{"familyId": familyID, "revokedAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now().UTC()}}
+	_, err := r.collection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+func (r *mongoRefreshTokenRepository) RevokeAllByUserID(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	filter := bson.M{"userId": userID, "revokedAt": bson.M{"$exists": false}}
+	families, err := r.collection.Distinct(ctx, "familyId", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now().UTC()}}
+	if _, err := r.collection.UpdateMany(ctx, filter, update); err != nil {
+		return nil, err
+	}
+
+	familyIDs := make([]primitive.ObjectID, 0, len(families))
+	for _, f := range families {
+		if id, ok := f.(primitive.ObjectID); ok {
+			familyIDs = append(familyIDs, id)
+		}
+	}
+	return familyIDs, nil
+}
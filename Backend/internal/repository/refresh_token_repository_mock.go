@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockRefreshTokenRepository is an in-memory implementation of RefreshTokenRepository for testing.
+type MockRefreshTokenRepository struct {
+	Tokens map[primitive.ObjectID]*models.RefreshToken
+}
+
+func NewMockRefreshTokenRepository() *MockRefreshTokenRepository {
+	return &MockRefreshTokenRepository{
+		Tokens: make(map[primitive.ObjectID]*models.RefreshToken),
+	}
+}
+
+func (m *MockRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	m.Tokens[token.ID] = token
+	return nil
+}
+
+func (m *MockRefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	for _, t := range m.Tokens {
+		if t.TokenHash == tokenHash {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockRefreshTokenRepository) MarkUsed(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	t, exists := m.Tokens[id]
+	if !exists || t.UsedAt != nil || t.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	t.UsedAt = &now
+	return true, nil
+}
+
+func (m *MockRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID primitive.ObjectID) error {
+	now := time.Now().UTC()
+	for _, t := range m.Tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *MockRefreshTokenRepository) RevokeAllByUserID(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	now := time.Now().UTC()
+	seen := make(map[primitive.ObjectID]bool)
+	var families []primitive.ObjectID
+	for _, t := range m.Tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+			if !seen[t.FamilyID] {
+				seen[t.FamilyID] = true
+				families = append(families, t.FamilyID)
+			}
+		}
+	}
+	return families, nil
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// RevocationRepository is a deny-list of revoked token identifiers. Entries
+// only need to outlive the tokens they revoke.
+type RevocationRepository interface {
+	Revoke(ctx context.Context, id string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, id string) (bool, error)
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRevocationRepository struct {
+	collection *mongo.Collection
+}
+
+var revocationIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+}
+
+func NewMongoRevocationRepository(db *mongo.Database) RevocationRepository {
+	collection := db.Collection("revoked_tokens")
+	_, err := collection.Indexes().CreateMany(context.Background(), revocationIndexes)
+	if err != nil {
+		log.Printf("Failed to create revoked token indexes: %v", err)
+	}
+	return &mongoRevocationRepository{collection: collection}
+}
+
+func (r *mongoRevocationRepository) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$max": bson.M{"expiresAt": expiresAt}}
+	_, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *mongoRevocationRepository) IsRevoked(ctx context.Context, id string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// MockRevocationRepository is an in-memory implementation of RevocationRepository for testing.
+type MockRevocationRepository struct {
+	Revoked map[string]time.Time
+}
+
+func NewMockRevocationRepository() *MockRevocationRepository {
+	return &MockRevocationRepository{
+		Revoked: make(map[string]time.Time),
+	}
+}
+
+func (m *MockRevocationRepository) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	if existing, ok := m.Revoked[id]; !ok || expiresAt.After(existing) {
+		m.Revoked[id] = expiresAt
+	}
+	return nil
+}
+
+func (m *MockRevocationRepository) IsRevoked(ctx context.Context, id string) (bool, error) {
+	_, revoked := m.Revoked[id]
+	return revoked, nil
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error)
+	// FindActiveByUserID returns the user's sessions that are neither revoked
+	// nor expired, most recently seen first.
+	FindActiveByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Session, error)
+	Extend(ctx context.Context, id primitive.ObjectID, expiresAt time.Time) error
+	// TouchMany records last-seen times for several sessions in one write.
+	TouchMany(ctx context.Context, lastSeen map[primitive.ObjectID]time.Time) error
+	Revoke(ctx context.Context, id primitive.ObjectID) error
+	RevokeAllByUserID(ctx context.Context, userID primitive.ObjectID) error
+}
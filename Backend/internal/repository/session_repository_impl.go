@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoSessionRepository struct {
+	collection *mongo.Collection
+}
+
+var sessionIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "userId", Value: 1}}},
+	{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+}
+
+func NewMongoSessionRepository(db *mongo.Database) SessionRepository {
+	collection := db.Collection("sessions")
+	_, err := collection.Indexes().CreateMany(context.Background(), sessionIndexes)
+	if err != nil {
+		log.Printf("Failed to create session indexes: %v", err)
+	}
+	return &mongoSessionRepository{collection: collection}
+}
+
+func (r *mongoSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	_, err := r.collection.InsertOne(ctx, session)
+	return err
+}
+
+func (r *mongoSessionRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error) {
+	var session models.Session
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *mongoSessionRepository) FindActiveByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Session, error) {
+	filter := bson.M{
+		"userId":    userID,
+		"revokedAt": bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": time.Now().UTC()},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "lastSeenAt", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []*models.Session
+	if err = cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *mongoSessionRepository) Extend(ctx context.Context, id primitive.ObjectID, expiresAt time.Time) error {
+	update := bson.M{"$set": bson.M{"expiresAt": expiresAt}}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *mongoSessionRepository) TouchMany(ctx context.Context, lastSeen map[primitive.ObjectID]time.Time) error {
+	if len(lastSeen) == 0 {
+		return nil
+	}
+	writes := make([]mongo.WriteModel, 0, len(lastSeen))
+	for id, seen := range lastSeen {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{"$max": bson.M{"lastSeenAt": seen}}))
+	}
+	_, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (r *mongoSessionRepository) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	filter := bson.M{"_id": id, "revokedAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now().UTC()}}
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (r *mongoSessionRepository) RevokeAllByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	filter := bson.M{"userId": userID, "revokedAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now().UTC()}}
+	_, err := r.collection.UpdateMany(ctx, filter, update)
+	return err
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockSessionRepository is an in-memory implementation of SessionRepository for testing.
+type MockSessionRepository struct {
+	mu       sync.Mutex
+	Sessions map[primitive.ObjectID]*models.Session
+}
+
+func NewMockSessionRepository() *MockSessionRepository {
+	return &MockSessionRepository{
+		Sessions: make(map[primitive.ObjectID]*models.Session),
+	}
+}
+
+func (m *MockSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Sessions[session.ID] = session
+	return nil
+}
+
+func (m *MockSessionRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Sessions[id], nil
+}
+
+func (m *MockSessionRepository) FindActiveByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sessions []*models.Session
+	for _, s := range m.Sessions {
+		if s.UserID == userID && s.RevokedAt == nil && s.ExpiresAt.After(time.Now()) {
+			sessions = append(sessions, s)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt) })
+	return sessions, nil
+}
+
+func (m *MockSessionRepository) Extend(ctx context.Context, id primitive.ObjectID, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, exists := m.Sessions[id]; exists {
+		s.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+func (m *MockSessionRepository) TouchMany(ctx context.Context, lastSeen map[primitive.ObjectID]time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, seen := range lastSeen {
+		if s, exists := m.Sessions[id]; exists && seen.After(s.LastSeenAt) {
+			s.LastSeenAt = seen
+		}
+	}
+	return nil
+}
+
+func (m *MockSessionRepository) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, exists := m.Sessions[id]; exists && s.RevokedAt == nil {
+		now := time.Now().UTC()
+		s.RevokedAt = &now
+	}
+	return nil
+}
+
+func (m *MockSessionRepository) RevokeAllByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UTC()
+	for _, s := range m.Sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			s.RevokedAt = &now
+		}
+	}
+	return nil
+}
@@ -2,13 +2,44 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/drshn/excalidraw/Backend/internal/models"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type UserRepository interface {
+	// Create returns a *ConflictError if the email is already in use.
 	Create(ctx context.Context, user *models.User) error
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
 	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error
+	SetEmailVerified(ctx context.Context, id primitive.ObjectID) error
+	FindByIdentity(ctx context.Context, provider, subject string) (*models.User, error)
+	AddIdentity(ctx context.Context, id primitive.ObjectID, identity models.UserIdentity) error
+	// UpdateTwoFactor replaces the user's 2FA settings; nil removes them.
+	UpdateTwoFactor(ctx context.Context, id primitive.ObjectID, twoFactor *models.TwoFactor) error
+	// UseTOTPStep records a TOTP time step as used. It returns false if the
+	// step, or a later one, was already used.
+	UseTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error)
+	// UseRecoveryCode removes a recovery code hash. It returns false if the
+	// user has no such code.
+	UseRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error)
+	UpdateProfile(ctx context.Context, id primitive.ObjectID, displayName string, preferences models.UserPreferences) error
+	// SetAvatar replaces the user's avatar; nil removes it.
+	SetAvatar(ctx context.Context, id primitive.ObjectID, avatar *models.Avatar) error
+	SetPendingEmail(ctx context.Context, id primitive.ObjectID, email string) error
+	// UpdateEmail changes the user's email to a confirmed address and clears
+	// any pending change. It returns a *ConflictError if another user has
+	// the address.
+	UpdateEmail(ctx context.Context, id primitive.ObjectID, email string) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// Search returns users whose email or display name contains query, ordered
+	// by email, together with the total number of matches. An empty query
+	// matches everyone. Avatars are not loaded.
+	Search(ctx context.Context, query string, offset, limit int64) ([]*models.User, int64, error)
+	// SetDisabled disables the user as of disabledAt, or enables them when it
+	// is nil.
+	SetDisabled(ctx context.Context, id primitive.ObjectID, disabledAt *time.Time) error
+	SetRole(ctx context.Context, id primitive.ObjectID, role string) error
 }
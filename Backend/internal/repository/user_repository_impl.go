@@ -2,11 +2,14 @@ package repository
 
 import (
 	"context"
+	"regexp"
+	"time"
 
 	"github.com/drshn/excalidraw/Backend/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type mongoUserRepository struct {
@@ -21,7 +24,7 @@ func NewMongoUserRepository(db *mongo.Database) UserRepository {
 
 func (r *mongoUserRepository) Create(ctx context.Context, user *models.User) error {
 	_, err := r.collection.InsertOne(ctx, user)
-	return err
+	return conflictOnDuplicateKey(err, "user", "email")
 }
 
 func (r *mongoUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
@@ -47,3 +50,176 @@ func (r *mongoUserRepository) FindByID(ctx context.Context, id primitive.ObjectI
 	}
 	return &user, nil
 }
+
+func (r *mongoUserRepository) UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error {
+	update := bson.M{"$set": bson.M{"password": passwordHash}}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{Resource: "user"}
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) SetEmailVerified(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{"$set": bson.M{"emailVerified": true}}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{Resource: "user"}
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) FindByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	var user models.User
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+	err := r.collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *mongoUserRepository) AddIdentity(ctx context.Context, id primitive.ObjectID, identity models.UserIdentity) error {
+	update := bson.M{"$addToSet": bson.M{"identities": identity}}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{Resource: "user"}
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) UpdateTwoFactor(ctx context.Context, id primitive.ObjectID, twoFactor *models.TwoFactor) error {
+	update := bson.M{"$set": bson.M{"twoFactor": twoFactor}}
+	if twoFactor == nil {
+		update = bson.M{"$unset": bson.M{"twoFactor": ""}}
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{Resource: "user"}
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) UseTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	filter := bson.M{"_id": id, "twoFactor.lastUsedStep": bson.M{"$lt": step}}
+	update := bson.M{"$set": bson.M{"twoFactor.lastUsedStep": step}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (r *mongoUserRepository) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error) {
+	filter := bson.M{"_id": id, "twoFactor.recoveryCodes": codeHash}
+	update := bson.M{"$pull": bson.M{"twoFactor.recoveryCodes": codeHash}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (r *mongoUserRepository) UpdateProfile(ctx context.Context, id primitive.ObjectID, displayName string, preferences models.UserPreferences) error {
+	update := bson.M{"$set": bson.M{"displayName": displayName, "preferences": preferences}}
+	return r.updateOne(ctx, id, update)
+}
+
+func (r *mongoUserRepository) SetAvatar(ctx context.Context, id primitive.ObjectID, avatar *models.Avatar) error {
+	update := bson.M{"$set": bson.M{"avatar": avatar}}
+	if avatar == nil {
+		update = bson.M{"$unset": bson.M{"avatar": ""}}
+	}
+	return r.updateOne(ctx, id, update)
+}
+
+func (r *mongoUserRepository) SetPendingEmail(ctx context.Context, id primitive.ObjectID, email string) error {
+	return r.updateOne(ctx, id, bson.M{"$set": bson.M{"pendingEmail": email}})
+}
+
+func (r *mongoUserRepository) UpdateEmail(ctx context.Context, id primitive.ObjectID, email string) error {
+	update := bson.M{
+		"$set":   bson.M{"email": email, "emailVerified": true},
+		"$unset": bson.M{"pendingEmail": ""},
+	}
+	return conflictOnDuplicateKey(r.updateOne(ctx, id, update), "user", "email")
+}
+
+func (r *mongoUserRepository) updateOne(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{Resource: "user"}
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return &NotFoundError{Resource: "user"}
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) Search(ctx context.Context, query string, offset, limit int64) ([]*models.User, int64, error) {
+	filter := bson.M{}
+	if query != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+		filter = bson.M{"$or": bson.A{bson.M{"email": pattern}, bson.M{"displayName": pattern}}}
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "email", Value: 1}}).
+		SetSkip(offset).
+		SetLimit(limit).
+		SetProjection(bson.M{"avatar.data": 0})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []*models.User{}
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (r *mongoUserRepository) SetDisabled(ctx context.Context, id primitive.ObjectID, disabledAt *time.Time) error {
+	update := bson.M{"$set": bson.M{"disabledAt": disabledAt}}
+	if disabledAt == nil {
+		update = bson.M{"$unset": bson.M{"disabledAt": ""}}
+	}
+	return r.updateOne(ctx, id, update)
+}
+
+func (r *mongoUserRepository) SetRole(ctx context.Context, id primitive.ObjectID, role string) error {
+	return r.updateOne(ctx, id, bson.M{"$set": bson.M{"role": role}})
+}
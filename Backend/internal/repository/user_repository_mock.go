@@ -3,7 +3,9 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/drshn/excalidraw/Backend/internal/models"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,6 +17,11 @@ type MockUserRepository struct {
 	CreateFunc      func(ctx context.Context, user *models.User) error
 	FindByEmailFunc func(ctx context.Context, email string) (*models.User, error)
 	FindByIDFunc    func(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+
+	UpdatePasswordFunc   func(ctx context.Context, id primitive.ObjectID, passwordHash string) error
+	SetEmailVerifiedFunc func(ctx context.Context, id primitive.ObjectID) error
+	FindByIdentityFunc   func(ctx context.Context, provider, subject string) (*models.User, error)
+	AddIdentityFunc      func(ctx context.Context, id primitive.ObjectID, identity models.UserIdentity) error
 }
 
 func NewMockUserRepository() *MockUserRepository {
@@ -28,7 +35,7 @@ func (m *MockUserRepository) Create(ctx context.Context, user *models.User) erro
 		return m.CreateFunc(ctx, user)
 	}
 	if _, exists := m.Users[user.Email]; exists {
-		return errors.New("user already exists")
+		return &ConflictError{Resource: "user", Field: "email"}
 	}
 	m.Users[user.Email] = user
 	return nil
@@ -56,3 +63,195 @@ func (m *MockUserRepository) FindByID(ctx context.Context, id primitive.ObjectID
 	}
 	return nil, nil
 }
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error {
+	if m.UpdatePasswordFunc != nil {
+		return m.UpdatePasswordFunc(ctx, id, passwordHash)
+	}
+	for _, user := range m.Users {
+		if user.ID == id {
+			user.Password = passwordHash
+			return nil
+		}
+	}
+	return &NotFoundError{Resource: "user"}
+}
+
+func (m *MockUserRepository) SetEmailVerified(ctx context.Context, id primitive.ObjectID) error {
+	if m.SetEmailVerifiedFunc != nil {
+		return m.SetEmailVerifiedFunc(ctx, id)
+	}
+	for _, user := range m.Users {
+		if user.ID == id {
+			user.EmailVerified = true
+			return nil
+		}
+	}
+	return &NotFoundError{Resource: "user"}
+}
+
+func (m *MockUserRepository) FindByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	if m.FindByIdentityFunc != nil {
+		return m.FindByIdentityFunc(ctx, provider, subject)
+	}
+	for _, user := range m.Users {
+		for _, identity := range user.Identities {
+			if identity.Provider == provider && identity.Subject == subject {
+				return user, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepository) AddIdentity(ctx context.Context, id primitive.ObjectID, identity models.UserIdentity) error {
+	if m.AddIdentityFunc != nil {
+		return m.AddIdentityFunc(ctx, id, identity)
+	}
+	for _, user := range m.Users {
+		if user.ID == id {
+			for _, existing := range user.Identities {
+				if existing == identity {
+					return nil
+				}
+			}
+			user.Identities = append(user.Identities, identity)
+			return nil
+		}
+	}
+	return &NotFoundError{Resource: "user"}
+}
+
+func (m *MockUserRepository) UpdateTwoFactor(ctx context.Context, id primitive.ObjectID, twoFactor *models.TwoFactor) error {
+	for _, user := range m.Users {
+		if user.ID == id {
+			user.TwoFactor = twoFactor
+			return nil
+		}
+	}
+	return &NotFoundError{Resource: "user"}
+}
+
+func (m *MockUserRepository) UseTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	for _, user := range m.Users {
+		if user.ID == id && user.TwoFactor != nil && user.TwoFactor.LastUsedStep < step {
+			user.TwoFactor.LastUsedStep = step
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MockUserRepository) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error) {
+	for _, user := range m.Users {
+		if user.ID != id || user.TwoFactor == nil {
+			continue
+		}
+		for i, hash := range user.TwoFactor.RecoveryCodes {
+			if hash == codeHash {
+				codes := user.TwoFactor.RecoveryCodes
+				user.TwoFactor.RecoveryCodes = append(codes[:i:i], codes[i+1:]...)
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (m *MockUserRepository) UpdateProfile(ctx context.Context, id primitive.ObjectID, displayName string, preferences models.UserPreferences) error {
+	user := m.findByID(id)
+	if user == nil {
+		return &NotFoundError{Resource: "user"}
+	}
+	user.DisplayName = displayName
+	user.Preferences = preferences
+	return nil
+}
+
+func (m *MockUserRepository) SetAvatar(ctx context.Context, id primitive.ObjectID, avatar *models.Avatar) error {
+	user := m.findByID(id)
+	if user == nil {
+		return &NotFoundError{Resource: "user"}
+	}
+	user.Avatar = avatar
+	return nil
+}
+
+func (m *MockUserRepository) SetPendingEmail(ctx context.Context, id primitive.ObjectID, email string) error {
+	user := m.findByID(id)
+	if user == nil {
+		return &NotFoundError{Resource: "user"}
+	}
+	user.PendingEmail = email
+	return nil
+}
+
+func (m *MockUserRepository) UpdateEmail(ctx context.Context, id primitive.ObjectID, email string) error {
+	user := m.findByID(id)
+	if user == nil {
+		return &NotFoundError{Resource: "user"}
+	}
+	if other, exists := m.Users[email]; exists && other != user {
+		return &ConflictError{Resource: "user", Field: "email"}
+	}
+	delete(m.Users, user.Email)
+	user.Email = email
+	user.EmailVerified = true
+	user.PendingEmail = ""
+	m.Users[email] = user
+	return nil
+}
+
+func (m *MockUserRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	user := m.findByID(id)
+	if user == nil {
+		return &NotFoundError{Resource: "user"}
+	}
+	delete(m.Users, user.Email)
+	return nil
+}
+
+func (m *MockUserRepository) Search(ctx context.Context, query string, offset, limit int64) ([]*models.User, int64, error) {
+	query = strings.ToLower(query)
+	matches := []*models.User{}
+	for _, user := range m.Users {
+		if strings.Contains(strings.ToLower(user.Email), query) || strings.Contains(strings.ToLower(user.DisplayName), query) {
+			matches = append(matches, user)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Email < matches[j].Email })
+
+	total := int64(len(matches))
+	if offset > total {
+		offset = total
+	}
+	end := min(offset+limit, total)
+	return matches[offset:end], total, nil
+}
+
+func (m *MockUserRepository) SetDisabled(ctx context.Context, id primitive.ObjectID, disabledAt *time.Time) error {
+	user := m.findByID(id)
+	if user == nil {
+		return &NotFoundError{Resource: "user"}
+	}
+	user.DisabledAt = disabledAt
+	return nil
+}
+
+func (m *MockUserRepository) SetRole(ctx context.Context, id primitive.ObjectID, role string) error {
+	user := m.findByID(id)
+	if user == nil {
+		return &NotFoundError{Resource: "user"}
+	}
+	user.Role = role
+	return nil
+}
+
+func (m *MockUserRepository) findByID(id primitive.ObjectID) *models.User {
+	for _, user := range m.Users {
+		if user.ID == id {
+			return user
+		}
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *models.UserToken) error
+	FindByHash(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error)
+	// Consume marks a token as used. It returns false if the token was
+	// already used.
+	Consume(ctx context.Context, id primitive.ObjectID) (bool, error)
+	// DeleteByUserID removes the user's outstanding tokens for a purpose.
+	DeleteByUserID(ctx context.Context, userID primitive.ObjectID, purpose string) error
+	// DeleteAllByUserID removes the user's tokens for every purpose.
+	DeleteAllByUserID(ctx context.Context, userID primitive.ObjectID) error
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoUserTokenRepository struct {
+	collection *mongo.Collection
+}
+
+var userTokenIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+	{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "purpose", Value: 1}}},
+	{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+}
+
+func NewMongoUserTokenRepository(db *mongo.Database) UserTokenRepository {
+	collection := db.Collection("user_tokens")
+	_, err := collection.Indexes().CreateMany(context.Background(), userTokenIndexes)
+	if err != nil {
+		log.Printf("Failed to create user token indexes: %v", err)
+	}
+	return &mongoUserTokenRepository{collection: collection}
+}
+
+func (r *mongoUserTokenRepository) Create(ctx context.Context, token *models.UserToken) error {
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+func (r *mongoUserTokenRepository) FindByHash(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	var token models.UserToken
+	err := r.collection.FindOne(ctx, bson.M{"purpose": purpose, "tokenHash": tokenHash}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *mongoUserTokenRepository) Consume(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": id, "usedAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"usedAt": time.Now().UTC()}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (r *mongoUserTokenRepository) DeleteByUserID(ctx context.Context, userID primitive.ObjectID, purpose string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID, "purpose": purpose})
+	return err
+}
+
+func (r *mongoUserTokenRepository) DeleteAllByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
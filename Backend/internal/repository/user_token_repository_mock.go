@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockUserTokenRepository is an in-memory implementation of UserTokenRepository for testing.
+type MockUserTokenRepository struct {
+	Tokens map[primitive.ObjectID]*models.UserToken
+}
+
+func NewMockUserTokenRepository() *MockUserTokenRepository {
+	return &MockUserTokenRepository{
+		Tokens: make(map[primitive.ObjectID]*models.UserToken),
+	}
+}
+
+func (m *MockUserTokenRepository) Create(ctx context.Context, token *models.UserToken) error {
+	m.Tokens[token.ID] = token
+	return nil
+}
+
+func (m *MockUserTokenRepository) FindByHash(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	for _, t := range m.Tokens {
+		if t.Purpose == purpose && t.TokenHash == tokenHash {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockUserTokenRepository) Consume(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	t, exists := m.Tokens[id]
+	if !exists || t.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	t.UsedAt = &now
+	return true, nil
+}
+
+func (m *MockUserTokenRepository) DeleteByUserID(ctx context.Context, userID primitive.ObjectID, purpose string) error {
+	for id, t := range m.Tokens {
+		if t.UserID == userID && t.Purpose == purpose {
+			delete(m.Tokens, id)
+		}
+	}
+	return nil
+}
+
+func (m *MockUserTokenRepository) DeleteAllByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	for id, t := range m.Tokens {
+		if t.UserID == userID {
+			delete(m.Tokens, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+// Package server wires the repositories, services and handlers into the API
+// router, so the server and its tests serve the same routes.
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/auth"
+	"github.com/drshn/excalidraw/Backend/internal/config"
+	"github.com/drshn/excalidraw/Backend/internal/handlers"
+	"github.com/drshn/excalidraw/Backend/internal/jobs"
+	"github.com/drshn/excalidraw/Backend/internal/mail"
+	"github.com/drshn/excalidraw/Backend/internal/middleware"
+	"github.com/drshn/excalidraw/Backend/internal/models"
+	"github.com/drshn/excalidraw/Backend/internal/oidc"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Repositories holds every repository the server uses.
+type Repositories struct {
+	Users                repository.UserRepository
+	Drawings             repository.DrawingRepository
+	Libraries            repository.LibraryRepository
+	Comments             repository.CommentRepository
+	RefreshTokens        repository.RefreshTokenRepository
+	Revocations          repository.RevocationRepository
+	Sessions             repository.SessionRepository
+	UserTokens           repository.UserTokenRepository
+	OIDCStates           repository.OIDCStateRepository
+	PersonalAccessTokens repository.PersonalAccessTokenRepository
+	Invitations          repository.InvitationRepository
+}
+
+func NewMongoRepositories(db *mongo.Database) Repositories {
+	return Repositories{
+		Users:                repository.NewMongoUserRepository(db),
+		Drawings:             repository.NewMongoDrawingRepository(db),
+		Libraries:            repository.NewMongoLibraryRepository(db),
+		Comments:             repository.NewMongoCommentRepository(db),
+		RefreshTokens:        repository.NewMongoRefreshTokenRepository(db),
+		Revocations:          repository.NewMongoRevocationRepository(db),
+		Sessions:             repository.NewMongoSessionRepository(db),
+		UserTokens:           repository.NewMongoUserTokenRepository(db),
+		OIDCStates:           repository.NewMongoOIDCStateRepository(db),
+		PersonalAccessTokens: repository.NewMongoPersonalAccessTokenRepository(db),
+		Invitations:          repository.NewMongoInvitationRepository(db),
+	}
+}
+
+// Server is the API router and the background jobs that go with it.
+type Server struct {
+	Router         *gin.Engine
+	SessionTracker *auth.SessionTracker
+	TrashPurger    *jobs.TrashPurger
+}
+
+// New builds the server from cfg. It fails when the configuration is invalid.
+func New(cfg *config.Config, repos Repositories, mailer mail.Mailer) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	keys, err := auth.LoadKeySet(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not load JWT keys: %w", err)
+	}
+	tokenService := auth.NewTokenService(repos.RefreshTokens, repos.Revocations, repos.Sessions, keys, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+
+	passwords, err := auth.LoadPasswordHasher(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid password hashing configuration: %w", err)
+	}
+	passwordPolicy, err := auth.LoadPasswordPolicy(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid password policy: %w", err)
+	}
+	sessionTracker := auth.NewSessionTracker(repos.Sessions, cfg.SessionFlushInterval)
+	personalAccessTokenService := auth.NewPersonalAccessTokenService(repos.PersonalAccessTokens, repos.Users)
+
+	oidcConfigs, err := oidc.ParseProviders(cfg.OIDCProviders)
+	if err != nil {
+		return nil, fmt.Errorf("could not configure single sign-on: %w", err)
+	}
+
+	emailVerificationHandler := handlers.NewEmailVerificationHandler(repos.Users, repos.UserTokens, mailer, cfg.AppBaseURL, cfg.EmailVerificationTTL)
+	twoFactorHandler := handlers.NewTwoFactorHandler(repos.Users, repos.UserTokens, tokenService, passwords, cfg.TOTPIssuer)
+	invitationHandler := handlers.NewInvitationHandler(repos.Invitations, repos.Users, cfg.InvitationsByMembers, cfg.AppBaseURL, cfg.InvitationTTL)
+	registration, err := handlers.NewRegistration(cfg.RegistrationMode, cfg.RegistrationAllowedDomains, cfg.RequireEmailVerification, invitationHandler)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registration configuration: %w", err)
+	}
+	oidcHandler := handlers.NewOIDCHandler(repos.Users, repos.OIDCStates, tokenService, twoFactorHandler, registration, oidc.NewProviders(oidcConfigs, cfg.APIBaseURL), cfg.AppBaseURL)
+	authHandler := handlers.NewAuthHandler(repos.Users, tokenService, passwords, passwordPolicy, emailVerificationHandler, twoFactorHandler, registration)
+	drawingHandler := handlers.NewDrawingHandler(repos.Drawings, repos.Comments)
+	templateHandler := handlers.NewTemplateHandler(repos.Drawings)
+	libraryHandler := handlers.NewLibraryHandler(repos.Libraries)
+	commentHandler := handlers.NewCommentHandler(repos.Comments, repos.Drawings)
+	personalAccessTokenHandler := handlers.NewPersonalAccessTokenHandler(personalAccessTokenService)
+	jwksHandler := handlers.NewJWKSHandler(keys)
+	passwordResetHandler := handlers.NewPasswordResetHandler(repos.Users, repos.UserTokens, tokenService, passwords, passwordPolicy, mailer, cfg.AppBaseURL, cfg.PasswordResetTTL)
+	userHandler := handlers.NewUserHandler(repos.Users, repos.UserTokens, tokenService, passwords, passwordPolicy, mailer, cfg.AppBaseURL, cfg.EmailVerificationTTL)
+	accountHandler := handlers.NewAccountHandler(repos.Users, repos.Drawings, repos.Comments, repos.Libraries, repos.Invitations, repos.PersonalAccessTokens, repos.UserTokens, tokenService, passwords, mailer, cfg.AppBaseURL)
+	adminHandler := handlers.NewAdminHandler(repos.Users, repos.Drawings, repos.Libraries, tokenService, passwordResetHandler)
+	problemHandler := handlers.NewProblemHandler()
+
+	r := gin.New()
+	r.Use(middleware.RequestID(), gin.Logger(), middleware.Recovery())
+
+	// Configure CORS to allow all origins
+	r.Use(cors.New(cors.Config{
+		AllowOrigins:     []string{"*"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"*"},
+		ExposeHeaders:    []string{"Content-Length", "X-Request-ID"},
+		AllowCredentials: false,
+		MaxAge:           12 * time.Hour,
+	}))
+
+	authMiddleware := middleware.AuthMiddleware(tokenService, sessionTracker, personalAccessTokenService)
+	requireVerifiedEmail := middleware.RequireVerifiedEmail(repos.Users, cfg.RequireEmailVerification)
+
+	openAPIHandler := handlers.NewOpenAPIHandler(r.Routes)
+
+	r.NoRoute(problemHandler.NoRoute)
+	r.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
+	api := r.Group("/api/v1")
+	{
+		api.GET("/problems", problemHandler.GetProblems)
+		api.GET("/problems/:code", problemHandler.GetProblem)
+		api.GET("/openapi.json", openAPIHandler.GetSpec)
+		api.GET("/docs", openAPIHandler.GetDocs)
+
+		auth := api.Group("/auth")
+		{
+			auth.GET("/registration", authHandler.GetRegistration)
+			auth.POST("/register", authHandler.Register)
+			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", authMiddleware, authHandler.Logout)
+			auth.POST("/logout-all", authMiddleware, authHandler.LogoutAll)
+			auth.GET("/sessions", authMiddleware, authHandler.GetSessions)
+			auth.DELETE("/sessions/:id", authMiddleware, authHandler.DeleteSession)
+			auth.POST("/password/forgot", passwordResetHandler.ForgotPassword)
+			auth.POST("/password/reset", passwordResetHandler.ResetPassword)
+			auth.POST("/verify-email", emailVerificationHandler.VerifyEmail)
+			auth.POST("/verify-email/resend", authMiddleware, emailVerificationHandler.ResendVerification)
+			auth.POST("/2fa/setup", authMiddleware, twoFactorHandler.Setup)
+			auth.POST("/2fa/confirm", authMiddleware, twoFactorHandler.Confirm)
+			auth.POST("/2fa/disable", authMiddleware, twoFactorHandler.Disable)
+			auth.POST("/2fa/recovery-codes", authMiddleware, twoFactorHandler.RegenerateRecoveryCodes)
+			auth.POST("/2fa/verify", twoFactorHandler.VerifyLogin)
+			auth.GET("/oidc/providers", oidcHandler.GetProviders)
+			auth.GET("/oidc/:provider/login", oidcHandler.Login)
+			auth.GET("/oidc/:provider/callback", oidcHandler.Callback)
+		}
+
+		// Personal access tokens are accepted only on groups that allow
+		// their scopes; everywhere else a login session is required.
+		drawingScopes := middleware.AllowScopes(models.ScopeDrawingsRead, models.ScopeDrawingsWrite)
+
+		drawings := api.Group("/drawings")
+		drawings.Use(drawingScopes, authMiddleware)
+		{
+			drawings.POST("", requireVerifiedEmail, drawingHandler.CreateDrawing)
+			drawings.GET("", drawingHandler.GetDrawings)
+			drawings.GET("/trash", drawingHandler.GetTrash)
+			drawings.GET("/:id", drawingHandler.GetDrawingByID)
+			drawings.PUT("/:id", drawingHandler.UpdateDrawing)
+			drawings.PATCH("/:id", drawingHandler.PatchDrawing)
+			drawings.DELETE("/:id", drawingHandler.DeleteDrawing)
+			drawings.POST("/:id/restore", drawingHandler.RestoreDrawing)
+			drawings.POST("/:id/duplicate", requireVerifiedEmail, drawingHandler.DuplicateDrawing)
+			drawings.PUT("/:id/template", templateHandler.SetDrawingTemplate)
+			drawings.DELETE("/:id/template", templateHandler.UnsetDrawingTemplate)
+			drawings.GET("/:id/comments", commentHandler.GetComments)
+			drawings.POST("/:id/comments", commentHandler.CreateComment)
+			drawings.PUT("/:id/comments/:commentId", commentHandler.UpdateComment)
+			drawings.DELETE("/:id/comments/:commentId", commentHandler.DeleteComment)
+			drawings.POST("/:id/comments/:commentId/resolve", commentHandler.ResolveComment)
+			drawings.POST("/:id/comments/:commentId/reopen", commentHandler.ReopenComment)
+		}
+
+		templates := api.Group("/templates")
+		templates.Use(drawingScopes, authMiddleware)
+		{
+			templates.GET("", templateHandler.GetTemplates)
+		}
+
+		libraries := api.Group("/libraries")
+		libraries.Use(authMiddleware)
+		{
+			libraries.POST("", libraryHandler.CreateLibrary)
+			libraries.GET("", libraryHandler.GetLibraries)
+			libraries.GET("/:id", libraryHandler.GetLibraryByID)
+			libraries.PUT("/:id", libraryHandler.UpdateLibrary)
+			libraries.DELETE("/:id", libraryHandler.DeleteLibrary)
+			libraries.POST("/:id/items", libraryHandler.AddLibraryItem)
+			libraries.DELETE("/:id/items/:itemId", libraryHandler.DeleteLibraryItem)
+			libraries.POST("/:id/import", libraryHandler.ImportLibrary)
+			libraries.GET("/:id/export", libraryHandler.ExportLibrary)
+		}
+
+		tokens := api.Group("/tokens")
+		tokens.Use(authMiddleware)
+		{
+			tokens.POST("", personalAccessTokenHandler.CreateToken)
+			tokens.GET("", personalAccessTokenHandler.GetTokens)
+			tokens.DELETE("/:id", personalAccessTokenHandler.DeleteToken)
+		}
+
+		invitations := api.Group("/invitations")
+		invitations.Use(authMiddleware)
+		{
+			invitations.POST("", invitationHandler.CreateInvitation)
+			invitations.GET("", invitationHandler.GetInvitations)
+			invitations.DELETE("/:id", invitationHandler.DeleteInvitation)
+		}
+
+		users := api.Group("/users")
+		users.Use(authMiddleware)
+		{
+			users.GET("/me", userHandler.GetMe)
+			users.PATCH("/me", userHandler.UpdateMe)
+			users.DELETE("/me", accountHandler.DeleteAccount)
+			users.GET("/me/export", accountHandler.ExportAccount)
+			users.GET("/me/avatar", userHandler.GetAvatar)
+			users.POST("/me/avatar", userHandler.UploadAvatar)
+			users.DELETE("/me/avatar", userHandler.DeleteAvatar)
+			users.POST("/me/password", userHandler.ChangePassword)
+			users.POST("/me/email", userHandler.RequestEmailChange)
+			users.POST("/me/email/confirm", userHandler.ConfirmEmailChange)
+		}
+
+		admin := api.Group("/admin")
+		admin.Use(authMiddleware, middleware.RequireRole(repos.Users, models.RoleAdmin))
+		{
+			admin.GET("/users", adminHandler.ListUsers)
+			admin.GET("/users/:id", adminHandler.GetUser)
+			admin.GET("/users/:id/storage", adminHandler.GetUserStorage)
+			admin.POST("/users/:id/disable", adminHandler.DisableUser)
+			admin.POST("/users/:id/enable", adminHandler.EnableUser)
+			admin.POST("/users/:id/password-reset", adminHandler.ForcePasswordReset)
+			admin.POST("/drawings/:id/transfer", adminHandler.TransferDrawing)
+		}
+	}
+
+	return &Server{
+		Router:         r,
+		SessionTracker: sessionTracker,
+		TrashPurger:    jobs.NewTrashPurger(repos.Drawings, repos.Comments, cfg.TrashRetention, cfg.TrashPurgeInterval),
+	}, nil
+}
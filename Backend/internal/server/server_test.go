@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drshn/excalidraw/Backend/internal/config"
+	"github.com/drshn/excalidraw/Backend/internal/handlers"
+	"github.com/drshn/excalidraw/Backend/internal/mail"
+	"github.com/drshn/excalidraw/Backend/internal/openapi"
+	"github.com/drshn/excalidraw/Backend/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) *Server {
+	gin.SetMode(gin.TestMode)
+
+	repos := Repositories{
+		Users:                repository.NewMockUserRepository(),
+		Drawings:             repository.NewMockDrawingRepository(),
+		Libraries:            repository.NewMockLibraryRepository(),
+		Comments:             repository.NewMockCommentRepository(),
+		RefreshTokens:        repository.NewMockRefreshTokenRepository(),
+		Revocations:          repository.NewMockRevocationRepository(),
+		Sessions:             repository.NewMockSessionRepository(),
+		UserTokens:           repository.NewMockUserTokenRepository(),
+		OIDCStates:           repository.NewMockOIDCStateRepository(),
+		PersonalAccessTokens: repository.NewMockPersonalAccessTokenRepository(),
+		Invitations:          repository.NewMockInvitationRepository(),
+	}
+	s, err := New(config.LoadConfig(), repos, mail.NewLogMailer("", "test@example.com"))
+	if err != nil {
+		t.Fatalf("could not build the server: %v", err)
+	}
+	return s
+}
+
+// TestOpenAPIDocumentsEveryRoute fails when a route is registered without an
+// entry in handlers.APIRoutes, or an entry outlives its route.
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	s := newTestServer(t)
+
+	undocumented, stale := openapi.Check(s.Router.Routes(), handlers.APIRoutes)
+	assert.Empty(t, undocumented, "document these routes in handlers.APIRoutes")
+	assert.Empty(t, stale, "these routes in handlers.APIRoutes are not registered")
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil)
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var doc openapi.Document
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	operations := 0
+	for _, item := range doc.Paths {
+		operations += len(item)
+	}
+	assert.Equal(t, len(s.Router.Routes()), operations)
+	assert.NotNil(t, doc.Paths["/api/v1/drawings/{id}"]["patch"])
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	cfg := config.LoadConfig()
+	cfg.RegistrationMode = "sometimes"
+
+	_, err := New(cfg, Repositories{}, mail.NewLogMailer("", "test@example.com"))
+	assert.ErrorContains(t, err, "invalid registration configuration")
+
+	cfg = config.LoadConfig()
+	cfg.RegistrationMode = "domains"
+	cfg.RegistrationAllowedDomains = "example.com"
+	cfg.RequireEmailVerification = false
+	_, err = New(cfg, Repositories{}, mail.NewLogMailer("", "test@example.com"))
+	assert.ErrorContains(t, err, "REQUIRE_EMAIL_VERIFICATION")
+}
+
+func TestNew_NonPositiveDuration(t *testing.T) {
+	for _, set := range []func(*config.Config){
+		func(cfg *config.Config) { cfg.TrashPurgeInterval = 0 },
+		func(cfg *config.Config) { cfg.TrashRetention = -time.Hour },
+		func(cfg *config.Config) { cfg.SessionFlushInterval = 0 },
+	} {
+		cfg := config.LoadConfig()
+		set(cfg)
+
+		_, err := New(cfg, Repositories{}, mail.NewLogMailer("", "test@example.com"))
+		assert.ErrorContains(t, err, "must be a positive duration")
+	}
+}
@@ -0,0 +1,66 @@
+package templates
+
+import (
+	"embed"
+	"encoding/base64"
+	"log"
+)
+
+//go:embed builtin
+var builtinFS embed.FS
+
+// Template is a drawing that new drawings can be created from. Built-in
+// templates are embedded in the binary; user templates are drawings marked
+// as templates.
+type Template struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Category  string `json:"category"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+	SceneData string `json:"sceneData,omitempty"`
+	BuiltIn   bool   `json:"builtIn"`
+}
+
+var builtins = []Template{
+	{ID: "flowchart", Name: "Flowchart", Category: "diagram"},
+	{ID: "sequence", Name: "Sequence diagram", Category: "diagram"},
+	{ID: "kanban", Name: "Kanban board", Category: "planning"},
+}
+
+func init() {
+	for i := range builtins {
+		t := &builtins[i]
+		scene, err := builtinFS.ReadFile("builtin/" + t.ID + ".excalidraw")
+		if err != nil {
+			log.Fatalf("Missing built-in template %q: %v", t.ID, err)
+		}
+		thumbnail, err := builtinFS.ReadFile("builtin/" + t.ID + ".svg")
+		if err != nil {
+			log.Fatalf("Missing thumbnail for built-in template %q: %v", t.ID, err)
+		}
+		t.SceneData = string(scene)
+		t.Thumbnail = "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString(thumbnail)
+		t.BuiltIn = true
+	}
+}
+
+// BuiltIn returns the built-in templates without their scene data.
+func BuiltIn() []Template {
+	list := make([]Template, len(builtins))
+	for i, t := range builtins {
+		t.SceneData = ""
+		list[i] = t
+	}
+	return list
+}
+
+// FindBuiltIn returns the built-in template with the given ID, including its
+// scene data.
+func FindBuiltIn(id string) (*Template, bool) {
+	for _, t := range builtins {
+		if t.ID == id {
+			return &t, true
+		}
+	}
+	return nil, false
+}
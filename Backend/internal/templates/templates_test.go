@@ -0,0 +1,30 @@
+package templates
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltInTemplates(t *testing.T) {
+	for _, tmpl := range BuiltIn() {
+		assert.Empty(t, tmpl.SceneData, "BuiltIn should not include scene data")
+		assert.True(t, strings.HasPrefix(tmpl.Thumbnail, "data:image/svg+xml;base64,"))
+
+		full, found := FindBuiltIn(tmpl.ID)
+		assert.True(t, found)
+
+		var scene struct {
+			Type     string            `json:"type"`
+			Elements []json.RawMessage `json:"elements"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(full.SceneData), &scene), tmpl.ID)
+		assert.Equal(t, "excalidraw", scene.Type)
+		assert.NotEmpty(t, scene.Elements)
+	}
+
+	_, found := FindBuiltIn("missing")
+	assert.False(t, found)
+}
@@ -38,7 +38,7 @@ export function LoginForm() {
   const loginMutation = useMutation({
     ...mutations.auth.login(),
     onSuccess: (data) => {
-      login(data.token);
+      login(data.token, data.refreshToken);
       navigate("/");
     },
     onError: (error: any) => {
@@ -15,6 +15,7 @@ import {
   queryKeys,
 } from "../../lib/queryFactory";
 import { useAuthStore } from "../../stores/authStore";
+import { authApi } from "../../lib/api";
 import { CreateDrawingButton } from "./CreateDrawingModal";
 import { ModeToggle } from "../theme-toggle";
 import { Trash2, Edit, LogOut } from "lucide-react";
@@ -47,7 +48,9 @@ export function DrawingList() {
     }
   };
 
-  const handleLogout = () => {
+  const handleLogout = async () => {
+    // Revoke the session server-side; sign out locally regardless.
+    await authApi.logout().catch(() => {});
     logout();
     navigate("/login");
   };
@@ -16,13 +16,43 @@ api.interceptors.request.use((config) => {
   return config;
 });
 
+// Refresh the access token once when a request is rejected with 401, then
+// retry it. Concurrent failures share a single refresh request.
+let refreshRequest: Promise<string> | null = null;
+
+const refreshAccessToken = async (): Promise<string> => {
+  const refreshToken = localStorage.getItem("auth-refresh-token");
+  if (!refreshToken) {
+    throw new Error("No refresh token");
+  }
+  const response = await axios.post<LoginResponse>(
+    `${API_BASE_URL}/auth/refresh`,
+    { refreshToken }
+  );
+  localStorage.setItem("auth-token", response.data.token);
+  localStorage.setItem("auth-refresh-token", response.data.refreshToken);
+  return response.data.token;
+};
+
 // Handle auth errors
 api.interceptors.response.use(
   (response) => response,
-  (error) => {
-    if (error.response?.status === 401) {
-      localStorage.removeItem("auth-token");
-      window.location.href = "/login";
+  async (error) => {
+    const original = error.config;
+    if (error.response?.status === 401 && original && !original._retry) {
+      original._retry = true;
+      try {
+        refreshRequest = refreshRequest ?? refreshAccessToken();
+        const token = await refreshRequest;
+        original.headers.Authorization = `Bearer ${token}`;
+        return api(original);
+      } catch {
+        localStorage.removeItem("auth-token");
+        localStorage.removeItem("auth-refresh-token");
+        window.location.href = "/login";
+      } finally {
+        refreshRequest = null;
+      }
     }
     return Promise.reject(error);
   }
@@ -36,6 +66,8 @@ export interface User {
 
 export interface LoginResponse {
   token: string;
+  refreshToken: string;
+  expiresAt: string;
 }
 
 export interface RegisterResponse {
@@ -71,6 +103,10 @@ export const authApi = {
     const response = await api.post("/auth/register", credentials);
     return response.data;
   },
+
+  logout: async (): Promise<void> => {
+    await api.post("/auth/logout");
+  },
 };
 
 export const drawingApi = {
@@ -89,8 +125,11 @@ export const drawingApi = {
     return response.data;
   },
 
+  // Sent as a merge patch, so saving the scene keeps the folder and tags.
   update: async (id: string, drawing: UpdateDrawingRequest): Promise<void> => {
-    await api.put(`/drawings/${id}`, drawing);
+    await api.patch(`/drawings/${id}`, drawing, {
+      headers: { "Content-Type": "application/merge-patch+json" },
+    });
   },
 
   delete: async (id: string): Promise<void> => {
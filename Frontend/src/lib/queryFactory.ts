@@ -67,6 +67,7 @@ export const mutations = {
       mutationFn: authApi.login,
       onSuccess: (data: any) => {
         localStorage.setItem("auth-token", data.token);
+        localStorage.setItem("auth-refresh-token", data.refreshToken);
       },
     }),
 
@@ -5,7 +5,7 @@ import { queryClient } from "../lib/queryFactory";
 interface AuthState {
   isAuthenticated: boolean;
   token: string | null;
-  login: (token: string) => void;
+  login: (token: string, refreshToken: string) => void;
   logout: () => void;
   checkAuth: () => void;
 }
@@ -16,13 +16,15 @@ export const useAuthStore = create<AuthState>()(
       isAuthenticated: false,
       token: null,
 
-      login: (token: string) => {
+      login: (token: string, refreshToken: string) => {
         localStorage.setItem("auth-token", token);
+        localStorage.setItem("auth-refresh-token", refreshToken);
         set({ isAuthenticated: true, token });
       },
 
       logout: () => {
         localStorage.removeItem("auth-token");
+        localStorage.removeItem("auth-refresh-token");
         queryClient.clear(); // Clear all cached queries on logout
         set({ isAuthenticated: false, token: null });
       },